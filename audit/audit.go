@@ -0,0 +1,30 @@
+// Package audit передает через context автора и комментарий изменения,
+// их записывает история версий флагов
+package audit
+
+import "context"
+
+// ActorUnknown - автор изменения не указан
+const ActorUnknown = "unknown"
+
+// Change - кто и зачем меняет данные
+type Change struct {
+	Actor   string
+	Comment string
+}
+
+type changeKey struct{}
+
+// WithChange сохраняет автора и комментарий изменения в ctx
+func WithChange(ctx context.Context, change Change) context.Context {
+	return context.WithValue(ctx, changeKey{}, change)
+}
+
+// FromContext возвращает автора и комментарий изменения, без автора - ActorUnknown
+func FromContext(ctx context.Context) Change {
+	change, _ := ctx.Value(changeKey{}).(Change)
+	if change.Actor == "" {
+		change.Actor = ActorUnknown
+	}
+	return change
+}
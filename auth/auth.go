@@ -0,0 +1,204 @@
+// Package auth проверяет учетные данные из заголовка Authorization: Bearer и права (scopes),
+// которые операция huma объявляет в поле Security
+package auth
+
+import (
+	"context"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/project"
+	"feature-flag-2/tenant"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gofiber/fiber/v3"
+)
+
+var (
+	ErrAuthUnauthorized = errors.New("unauthorized")
+
+	ErrAuthForbidden = errors.New("forbidden")
+)
+
+// SecuritySchemeBearer - схема безопасности OpenAPI, по которой операции объявляют права
+const SecuritySchemeBearer = "bearer"
+
+// права доступа
+const (
+	ScopeFlagsRead     = "flags:read"
+	ScopeFlagsWrite    = "flags:write"
+	ScopeFlagsEvaluate = "flags:evaluate"
+	ScopeFlagsApprove  = "flags:approve"
+	// ScopeAdmin - управление ключами и пользователями и все остальные права
+	ScopeAdmin = "admin"
+)
+
+// MetadataEnvironmentScoped - ключ Metadata операции huma над флагами окружения: без параметров пути
+// project и environment операция работает с окружением default проекта default
+const MetadataEnvironmentScoped = "environmentScoped"
+
+// MetadataAuthorizedByService - ключ Metadata операции huma, окружения которой приходят в теле запроса:
+// middleware проверяет только учетные данные, права на окружения проверяет сервис через CheckResource
+const MetadataAuthorizedByService = "authorizedByService"
+
+// Scopes - все известные права
+var Scopes = []string{ScopeFlagsRead, ScopeFlagsWrite, ScopeFlagsEvaluate, ScopeFlagsApprove, ScopeAdmin}
+
+// AuthenticateFunc проверяет токен из Authorization: Bearer: неизвестный токен - ErrAuthUnauthorized,
+// известный, но без доступа - *ForbiddenError
+type AuthenticateFunc func(ctx context.Context, token string) (Principal, error)
+
+// ByTokenType - JWT (три части через точку) проверяет jwt, остальные токены - apiKey,
+// jwt = nil - JWT не настроены, все токены считаются API ключами
+func ByTokenType(apiKey, jwt AuthenticateFunc) AuthenticateFunc {
+	return func(ctx context.Context, token string) (Principal, error) {
+		if jwt != nil && strings.Count(token, ".") == 2 {
+			return jwt(ctx, token)
+		}
+		return apiKey(ctx, token)
+	}
+}
+
+// Require - значение Security операции huma: для вызова нужно право scope
+func Require(scope string) []map[string][]string {
+	return []map[string][]string{{SecuritySchemeBearer: {scope}}}
+}
+
+// SecuritySchemes - схемы безопасности для components.securitySchemes документа OpenAPI
+func SecuritySchemes() map[string]*huma.SecurityScheme {
+	return map[string]*huma.SecurityScheme{
+		SecuritySchemeBearer: {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "API key or JWT: Authorization: Bearer <token>",
+		},
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal сохраняет субъект запроса в ctx
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext возвращает субъект запроса, ok = false - запрос без проверки прав
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// HumaMiddleware проверяет права операций с Security на проект и окружение из параметров пути
+// project и environment (у операций с MetadataEnvironmentScoped по старому пути - на окружение default),
+// операции без Security открыты. Субъект и ресурс остаются в ctx
+// для проверок в сервисах, автором изменения становится субъект, комментарий из X-Change-Comment сохраняется.
+// Арендатор субъекта тоже остается в ctx: запрос работает только с его данными
+func HumaMiddleware(api huma.API, authenticate AuthenticateFunc) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		security := ctx.Operation().Security
+		if len(security) == 0 {
+			next(ctx)
+			return
+		}
+		principal, err := authenticate(ctx.Context(), bearerToken(ctx.Header("Authorization")))
+		if err != nil {
+			writeErr(api, ctx, authenticationError(err))
+			return
+		}
+		resource := Resource{Project: ctx.Param("project"), Environment: ctx.Param("environment")}
+		if _, ok := ctx.Operation().Metadata[MetadataEnvironmentScoped]; ok && resource.Environment == "" {
+			resource = Resource{Project: project.DefaultProject, Environment: project.DefaultEnvironment}
+		}
+		if _, ok := ctx.Operation().Metadata[MetadataAuthorizedByService]; !ok {
+			if err := authorize(principal, resource, security); err != nil {
+				writeErr(api, ctx, err)
+				return
+			}
+		}
+		change := audit.FromContext(ctx.Context())
+		change.Actor = principal.Subject
+		authCtx := WithResource(WithPrincipal(audit.WithChange(ctx.Context(), change), principal), resource)
+		authCtx = tenant.WithTenant(authCtx, principal.Tenant)
+		next(huma.WithContext(ctx, authCtx))
+	}
+}
+
+// FiberMiddleware - проверка права scope для маршрутов fiber вне huma (/stream)
+// и перед fiber cache, который отвечает раньше huma. Все такие маршруты читают флаги окружения
+// из параметров пути project и environment, без них - окружения default
+func FiberMiddleware(authenticate AuthenticateFunc, scope string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		principal, err := authenticate(c, bearerToken(c.Get("Authorization")))
+		if err != nil {
+			statusErr := authenticationError(err)
+			if statusErr.GetStatus() == http.StatusUnauthorized {
+				c.Set("WWW-Authenticate", "Bearer")
+			}
+			return writeFiberErr(c, statusErr)
+		}
+		resource := Resource{Project: c.Params("project"), Environment: c.Params("environment")}
+		if resource.Environment == "" {
+			resource = Resource{Project: project.DefaultProject, Environment: project.DefaultEnvironment}
+		}
+		if err := authorize(principal, resource, Require(scope)); err != nil {
+			return writeFiberErr(c, err)
+		}
+		tenant.SetLocals(c, principal.Tenant)
+		return c.Next()
+	}
+}
+
+// authorize - security из OpenAPI: подходит любое из требований, внутри требования нужны все права,
+// в отказе - первое недостающее право
+func authorize(principal Principal, resource Resource, security []map[string][]string) huma.StatusError {
+	missingScope := ""
+	for _, requirement := range security {
+		scopes := requirement[SecuritySchemeBearer]
+		i := slices.IndexFunc(scopes, func(scope string) bool {
+			return !principal.Can(scope, resource)
+		})
+		if i == -1 {
+			return nil
+		}
+		if missingScope == "" {
+			missingScope = scopes[i]
+		}
+	}
+	return NewForbiddenError(ReasonMissingScope, missingScope, resource)
+}
+
+// authenticationError - *ForbiddenError из AuthenticateFunc как есть, ErrAuthUnauthorized - 401,
+// остальные ошибки (БД недоступна) - 500, учетные данные не проверены
+func authenticationError(err error) huma.StatusError {
+	var forbiddenErr *ForbiddenError
+	if errors.As(err, &forbiddenErr) {
+		return forbiddenErr
+	}
+	if errors.Is(err, ErrAuthUnauthorized) {
+		return huma.NewError(http.StatusUnauthorized, "invalid or missing credentials", err)
+	}
+	return huma.NewError(http.StatusInternalServerError, "credentials were not checked", err)
+}
+
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func writeErr(api huma.API, ctx huma.Context, err huma.StatusError) {
+	if err.GetStatus() == http.StatusUnauthorized {
+		ctx.SetHeader("WWW-Authenticate", "Bearer")
+	}
+	ctx.SetHeader("Content-Type", "application/problem+json")
+	ctx.SetStatus(err.GetStatus())
+	_ = api.Marshal(ctx.BodyWriter(), "application/problem+json", err)
+}
+
+func writeFiberErr(c fiber.Ctx, err huma.StatusError) error {
+	return c.Status(err.GetStatus()).JSON(err, "application/problem+json")
+}
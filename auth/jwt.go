@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwtLeeway - допустимое расхождение часов сервера и выдавшего токен
+const jwtLeeway = time.Minute
+
+// jwtAlgorithms - поддерживаемые алгоритмы подписи, none и HMAC не принимаются
+var jwtAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// Claims - проверенные claims JWT, которые нужны сервису
+type Claims struct {
+	Subject string
+	Email   string
+	Name    string
+}
+
+// JWTVerifier проверяет подпись JWT по ключам из локального файла JWKS, сеть не нужна.
+// Токен с неизвестным kid перечитывает файл, если он изменился - так подхватывается ротация ключей
+type JWTVerifier struct {
+	jwksPath string
+	issuer   string
+	audience string
+
+	mu      sync.RWMutex
+	keys    map[string]jwk
+	modTime time.Time
+}
+
+// NewJWTVerifier читает JWKS из jwksPath, пустые issuer и audience не проверяются
+func NewJWTVerifier(jwksPath, issuer, audience string) (*JWTVerifier, error) {
+	v := &JWTVerifier{jwksPath: jwksPath, issuer: issuer, audience: audience}
+	if err := v.reload(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Verify проверяет подпись, exp, nbf, iss и aud токена, любая ошибка - ErrAuthUnauthorized
+func (v *JWTVerifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed jwt", ErrAuthUnauthorized)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("%w: jwt header - {%v}", ErrAuthUnauthorized, err)
+	}
+	hash, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return Claims{}, fmt.Errorf("%w: unsupported jwt alg {%s}", ErrAuthUnauthorized, header.Alg)
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	if key.alg != "" && key.alg != header.Alg {
+		return Claims{}, fmt.Errorf("%w: key {%s} is not for alg {%s}", ErrAuthUnauthorized, header.Kid, header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: jwt signature - {%v}", ErrAuthUnauthorized, err)
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if !key.verify(header.Alg, hash, h.Sum(nil), signature) {
+		return Claims{}, fmt.Errorf("%w: invalid jwt signature", ErrAuthUnauthorized)
+	}
+	var payload struct {
+		Subject   string          `json:"sub"`
+		Issuer    string          `json:"iss"`
+		Audience  json.RawMessage `json:"aud"`
+		ExpiresAt *float64        `json:"exp"`
+		NotBefore *float64        `json:"nbf"`
+		Email     string          `json:"email"`
+		Name      string          `json:"name"`
+	}
+	if err := decodeSegment(parts[1], &payload); err != nil {
+		return Claims{}, fmt.Errorf("%w: jwt claims - {%v}", ErrAuthUnauthorized, err)
+	}
+	now := time.Now()
+	if payload.ExpiresAt == nil || now.After(unixTime(*payload.ExpiresAt).Add(jwtLeeway)) {
+		return Claims{}, fmt.Errorf("%w: jwt is expired", ErrAuthUnauthorized)
+	}
+	if payload.NotBefore != nil && now.Add(jwtLeeway).Before(unixTime(*payload.NotBefore)) {
+		return Claims{}, fmt.Errorf("%w: jwt is not valid yet", ErrAuthUnauthorized)
+	}
+	if v.issuer != "" && payload.Issuer != v.issuer {
+		return Claims{}, fmt.Errorf("%w: jwt issuer {%s}", ErrAuthUnauthorized, payload.Issuer)
+	}
+	if v.audience != "" && !hasAudience(payload.Audience, v.audience) {
+		return Claims{}, fmt.Errorf("%w: jwt audience is not {%s}", ErrAuthUnauthorized, v.audience)
+	}
+	if payload.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: jwt without sub", ErrAuthUnauthorized)
+	}
+	return Claims{Subject: payload.Subject, Email: payload.Email, Name: payload.Name}, nil
+}
+
+// key - ключ по kid, без kid подходит единственный ключ файла
+func (v *JWTVerifier) key(kid string) (jwk, error) {
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	if err := v.reloadIfChanged(); err != nil {
+		return jwk{}, fmt.Errorf("%w: jwks - {%v}", ErrAuthUnauthorized, err)
+	}
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	return jwk{}, fmt.Errorf("%w: unknown jwt kid {%s}", ErrAuthUnauthorized, kid)
+}
+
+func (v *JWTVerifier) lookup(kid string) (jwk, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *JWTVerifier) reloadIfChanged() error {
+	info, err := os.Stat(v.jwksPath)
+	if err != nil {
+		return err
+	}
+	v.mu.RLock()
+	changed := !info.ModTime().Equal(v.modTime)
+	v.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+	return v.reload()
+}
+
+func (v *JWTVerifier) reload() error {
+	info, err := os.Stat(v.jwksPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(v.jwksPath)
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("jwks {%s} - {%w}", v.jwksPath, err)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys = keys
+	v.modTime = info.ModTime()
+	return nil
+}
+
+// jwk - открытый ключ из JWKS
+type jwk struct {
+	alg string
+	rsa *rsa.PublicKey
+	ec  *ecdsa.PublicKey
+}
+
+func (k jwk) verify(alg string, hash crypto.Hash, digest, signature []byte) bool {
+	switch {
+	case strings.HasPrefix(alg, "RS") && k.rsa != nil:
+		return rsa.VerifyPKCS1v15(k.rsa, hash, digest, signature) == nil
+	case strings.HasPrefix(alg, "ES") && k.ec != nil:
+		// подпись JWS - r и s фиксированной длины подряд, а не ASN.1
+		size := (k.ec.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(k.ec, digest, r, s)
+	}
+	return false
+}
+
+// parseJWKS - ключи подписи RSA и EC по kid, ключи шифрования (use: enc) пропускаются
+func parseJWKS(data []byte) (map[string]jwk, error) {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]jwk, len(jwks.Keys))
+	for _, raw := range jwks.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		key := jwk{alg: raw.Alg}
+		switch raw.Kty {
+		case "RSA":
+			n, err := decodeBigInt(raw.N)
+			if err != nil {
+				return nil, fmt.Errorf("key {%s} n - {%w}", raw.Kid, err)
+			}
+			e, err := decodeBigInt(raw.E)
+			if err != nil || !e.IsInt64() {
+				return nil, fmt.Errorf("key {%s} e is invalid", raw.Kid)
+			}
+			key.rsa = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			curves := map[string]elliptic.Curve{
+				"P-256": elliptic.P256(),
+				"P-384": elliptic.P384(),
+				"P-521": elliptic.P521(),
+			}
+			curve, ok := curves[raw.Crv]
+			if !ok {
+				return nil, fmt.Errorf("key {%s} crv {%s} is not supported", raw.Kid, raw.Crv)
+			}
+			x, err := decodeBigInt(raw.X)
+			if err != nil {
+				return nil, fmt.Errorf("key {%s} x - {%w}", raw.Kid, err)
+			}
+			y, err := decodeBigInt(raw.Y)
+			if err != nil {
+				return nil, fmt.Errorf("key {%s} y - {%w}", raw.Kid, err)
+			}
+			key.ec = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		default:
+			continue
+		}
+		keys[raw.Kid] = key
+	}
+	return keys, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// hasAudience - aud может быть строкой или массивом строк
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return slices.Contains(list, audience)
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// роли пользователей
+const (
+	RoleViewer   = "viewer"
+	RoleEditor   = "editor"
+	RoleApprover = "approver"
+	RoleAdmin    = "admin"
+)
+
+// Roles - права каждой роли, admin имеет все права
+var Roles = map[string][]string{
+	RoleViewer:   {ScopeFlagsRead, ScopeFlagsEvaluate},
+	RoleEditor:   {ScopeFlagsRead, ScopeFlagsEvaluate, ScopeFlagsWrite},
+	RoleApprover: {ScopeFlagsRead, ScopeFlagsEvaluate, ScopeFlagsApprove},
+	RoleAdmin:    {ScopeAdmin},
+}
+
+// причины отказа в ответе 403
+const (
+	// ReasonMissingScope - ни одна роль или право субъекта не дает нужное право на ресурс
+	ReasonMissingScope = "missing_scope"
+	// ReasonUserNotFound - токен верный, но пользователь не заведен
+	ReasonUserNotFound = "user_not_found"
+	// ReasonUserDisabled - пользователь отключен
+	ReasonUserDisabled = "user_disabled"
+	// ReasonTenantNotAllowed - арендаторами управляет только admin арендатора default
+	ReasonTenantNotAllowed = "tenant_not_allowed"
+)
+
+// Resource - проект и окружение, к которым обращается запрос, пустые - глобально
+type Resource struct {
+	Project     string
+	Environment string
+}
+
+// Binding - права на ресурс: пустой проект - во всех проектах, пустое окружение - во всех окружениях
+type Binding struct {
+	Scopes      []string
+	Project     string
+	Environment string
+}
+
+func (b Binding) covers(resource Resource) bool {
+	return (b.Project == "" || b.Project == resource.Project) &&
+		(b.Environment == "" || b.Environment == resource.Environment)
+}
+
+// Principal - субъект запроса: Subject пишется автором изменений в историю флагов,
+// Tenant - арендатор ключа или пользователя, запрос видит только его данные
+type Principal struct {
+	Subject  string
+	Tenant   string
+	Bindings []Binding
+}
+
+// Can - у субъекта есть право scope (или admin) на resource
+func (p Principal) Can(scope string, resource Resource) bool {
+	for _, binding := range p.Bindings {
+		if !binding.covers(resource) {
+			continue
+		}
+		if slices.Contains(binding.Scopes, scope) || slices.Contains(binding.Scopes, ScopeAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForbiddenError - ответ 403, Reason - машиночитаемая причина отказа
+type ForbiddenError struct {
+	huma.ErrorModel
+	Reason      string `json:"reason"`
+	Scope       string `json:"scope,omitempty"`
+	Project     string `json:"project,omitempty"`
+	Environment string `json:"environment,omitempty"`
+}
+
+func (e *ForbiddenError) Unwrap() error {
+	return ErrAuthForbidden
+}
+
+// NewForbiddenError - отказ с причиной reason, scope и resource - чего не хватило
+func NewForbiddenError(reason, scope string, resource Resource) *ForbiddenError {
+	detail := fmt.Sprintf("forbidden: %s", reason)
+	if scope != "" {
+		detail = fmt.Sprintf("forbidden: scope {%s} is required", scope)
+	}
+	return &ForbiddenError{
+		ErrorModel: huma.ErrorModel{
+			Title:  http.StatusText(http.StatusForbidden),
+			Status: http.StatusForbidden,
+			Detail: detail,
+		},
+		Reason:      reason,
+		Scope:       scope,
+		Project:     resource.Project,
+		Environment: resource.Environment,
+	}
+}
+
+type resourceKey struct{}
+
+// WithResource сохраняет в ctx проект и окружение запроса
+func WithResource(ctx context.Context, resource Resource) context.Context {
+	return context.WithValue(ctx, resourceKey{}, resource)
+}
+
+// ResourceFromContext - проект и окружение запроса, без них - глобальный ресурс
+func ResourceFromContext(ctx context.Context) Resource {
+	resource, _ := ctx.Value(resourceKey{}).(Resource)
+	return resource
+}
+
+// Check проверяет право scope субъекта запроса на ресурс запроса.
+// Без субъекта (проверка выключена, фоновые воркеры) - разрешено
+func Check(ctx context.Context, scope string) error {
+	return CheckResource(ctx, scope, ResourceFromContext(ctx))
+}
+
+// CheckResource проверяет право scope субъекта запроса на resource - для окружений из тела запроса
+func CheckResource(ctx context.Context, scope string, resource Resource) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if !principal.Can(scope, resource) {
+		return NewForbiddenError(ReasonMissingScope, scope, resource)
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+// Package client - SDK для Go сервисов: загружает все флаги и сегменты при старте,
+// держит их в памяти, обновляет в фоне (опросом и, если включено, потоком /stream)
+// и вычисляет флаги локально тем же evaluator, что и сервер.
+// Если сервер недоступен, клиент продолжает отдавать последние полученные значения.
+//
+// Типизированные геттеры читают поле "value" вариации: {"value": true}, {"value": "blue"}.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"feature-flag-2/entity"
+	"feature-flag-2/evaluator"
+	"feature-flag-2/models"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClientUnexpectedStatus = errors.New("unexpected status")
+
+	ErrClientNotInitialized = errors.New("flags were not loaded")
+
+	ErrClientInvalidConfig = errors.New("invalid client config")
+)
+
+// ErrorCodeFlagNotFound - флага нет в локальном кэше клиента
+const ErrorCodeFlagNotFound = "FLAG_NOT_FOUND"
+
+// valueKey - поле вариации, которое читают типизированные геттеры
+const valueKey = "value"
+
+const (
+	defaultPollInterval = 30 * time.Second
+	defaultTimeout      = 10 * time.Second
+	// maxReconnectDelay - предел паузы между переподключениями к потоку
+	maxReconnectDelay = 30 * time.Second
+	// flagsPageLimit - самая большая страница GET /flags
+	flagsPageLimit = 1000
+)
+
+// Config - настройки клиента, BaseURL обязателен, остальное имеет значения по умолчанию
+type Config struct {
+	// BaseURL - адрес сервиса флагов, например http://localhost:8000
+	BaseURL string
+	// APIKey - ключ с правом flags:read, передается в Authorization: Bearer
+	APIKey string
+	// HTTPClient - клиент для запросов к сервису, по умолчанию с таймаутом 10s
+	HTTPClient *http.Client
+	// PollInterval - период обновления флагов и сегментов, по умолчанию 30s
+	PollInterval time.Duration
+	// Stream - дополнительно слушать /stream, изменения флагов приходят сразу после commit
+	Stream bool
+	// Project и Environment - окружение, флаги которого загружает клиент: пути
+	// /projects/{project}/environments/{environment}/flags и .../stream. Пустые - окружение default.
+	// Сегменты общие для всех окружений и всегда читаются из /segments
+	Project     string
+	Environment string
+}
+
+type Client struct {
+	baseURL string
+	// environmentPath - префикс путей флагов и потока окружения, пустой - окружение default
+	environmentPath string
+	apiKey          string
+	httpClient      *http.Client
+	pollInterval    time.Duration
+
+	mu          sync.RWMutex
+	flags       map[string]models.Flag
+	segments    []models.Segment
+	store       *evaluator.MapStore
+	lastRefresh time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New создает клиент, загружает флаги и запускает фоновое обновление.
+// Ошибка первой загрузки возвращается вместе с рабочим клиентом: он отдает значения по умолчанию
+// и продолжает попытки загрузки, сервис может стартовать без сервера флагов.
+// Project без Environment (и наоборот) - ошибка настройки, клиент не создается
+func New(ctx context.Context, cfg Config) (*Client, error) {
+	if (cfg.Project == "") != (cfg.Environment == "") {
+		return nil, fmt.Errorf("%w: Project and Environment are set only together", ErrClientInvalidConfig)
+	}
+	c := &Client{
+		baseURL:      strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:       cfg.APIKey,
+		httpClient:   cfg.HTTPClient,
+		pollInterval: cfg.PollInterval,
+		flags:        make(map[string]models.Flag),
+	}
+	if cfg.Project != "" {
+		c.environmentPath = "/projects/" + url.PathEscape(cfg.Project) + "/environments/" + url.PathEscape(cfg.Environment)
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	if c.pollInterval <= 0 {
+		c.pollInterval = defaultPollInterval
+	}
+	err := c.Refresh(ctx)
+	backgroundCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.wg.Add(1)
+	go c.poll(backgroundCtx)
+	if cfg.Stream {
+		c.wg.Add(1)
+		go c.listen(backgroundCtx)
+	}
+	if err != nil {
+		return c, fmt.Errorf("%w: %v", ErrClientNotInitialized, err)
+	}
+	return c, nil
+}
+
+// Close останавливает фоновое обновление
+func (c *Client) Close() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// Refresh загружает флаги и сегменты, при ошибке последние значения остаются в памяти
+func (c *Client) Refresh(ctx context.Context) error {
+	flags, err := c.listFlags(ctx)
+	if err != nil {
+		return err
+	}
+	var segments entity.ListOfSegmentResponse
+	if err := c.get(ctx, "/segments", &segments.Body); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.segments = segments.Body.Segments
+	c.replaceFlags(flags)
+	return nil
+}
+
+// listFlags загружает все флаги окружения по страницам next_cursor, вместе с удаленными - как снимок /stream.
+// Ошибка любой страницы - ошибка всей загрузки, неполный список заменил бы флаги в памяти
+func (c *Client) listFlags(ctx context.Context) ([]models.Flag, error) {
+	var flags []models.Flag
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(flagsPageLimit))
+	query.Set("deleted", "all")
+	for {
+		var page entity.ListOfFlagResponse
+		if err := c.get(ctx, c.environmentPath+"/flags?"+query.Encode(), &page.Body); err != nil {
+			return nil, err
+		}
+		flags = append(flags, page.Body.Flags...)
+		if page.Body.NextCursor == "" {
+			return flags, nil
+		}
+		query.Set("cursor", page.Body.NextCursor)
+	}
+}
+
+// replaceFlags заменяет все флаги, более новая версия из потока не перетирается
+// устаревшим ответом опроса (GET /flags кэшируется), вызывать под c.mu
+func (c *Client) replaceFlags(flags []models.Flag) {
+	newFlags := make(map[string]models.Flag, len(flags))
+	for _, flag := range flags {
+		if current, ok := c.flags[flag.FlagName]; ok && current.Version > flag.Version {
+			flag = current
+		}
+		newFlags[flag.FlagName] = flag
+	}
+	c.flags = newFlags
+	c.rebuildStore()
+}
+
+// applyFlag - изменение одного флага из потока, старые версии игнорируются
+func (c *Client) applyFlag(flag models.Flag) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := c.flags[flag.FlagName]; ok && current.Version > flag.Version {
+		return
+	}
+	c.flags[flag.FlagName] = flag
+	c.rebuildStore()
+}
+
+// rebuildStore - MapStore неизменяемый, пересобираем его после изменений, вызывать под c.mu
+func (c *Client) rebuildStore() {
+	flags := make([]models.Flag, 0, len(c.flags))
+	for _, flag := range c.flags {
+		flags = append(flags, flag)
+	}
+	c.store = evaluator.NewMapStore(flags, c.segments)
+	c.lastRefresh = time.Now()
+}
+
+// LastRefresh - время последней успешной загрузки, нулевое - флаги еще не загружены
+func (c *Client) LastRefresh() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastRefresh
+}
+
+// Evaluate вычисляет флаг локально, неизвестный флаг - Reason ERROR с кодом FLAG_NOT_FOUND
+func (c *Client) Evaluate(flagName string, evalCtx evaluator.Context) evaluator.Result {
+	c.mu.RLock()
+	store := c.store
+	c.mu.RUnlock()
+	if store == nil {
+		return notFound(flagName)
+	}
+	flag, ok := store.Flag(flagName)
+	if !ok {
+		return notFound(flagName)
+	}
+	return evaluator.Evaluate(flag, evalCtx, store, time.Now())
+}
+
+// BoolVariation - значение "value" вариации флага, defaultValue - если флага нет, ошибка или другой тип
+func (c *Client) BoolVariation(flagName string, evalCtx evaluator.Context, defaultValue bool) bool {
+	value, ok := c.variationValue(flagName, evalCtx)
+	if !ok {
+		return defaultValue
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return defaultValue
+	}
+	return b
+}
+
+// StringVariation - значение "value" вариации флага, defaultValue - если флага нет, ошибка или другой тип
+func (c *Client) StringVariation(flagName string, evalCtx evaluator.Context, defaultValue string) string {
+	value, ok := c.variationValue(flagName, evalCtx)
+	if !ok {
+		return defaultValue
+	}
+	s, ok := value.(string)
+	if !ok {
+		return defaultValue
+	}
+	return s
+}
+
+// JSONVariation - вариация флага целиком, defaultValue - если флага нет или ошибка вычисления
+func (c *Client) JSONVariation(flagName string, evalCtx evaluator.Context, defaultValue models.JSONmap) models.JSONmap {
+	result := c.Evaluate(flagName, evalCtx)
+	if result.Reason == evaluator.ReasonError || result.Value == nil {
+		return defaultValue
+	}
+	return result.Value
+}
+
+func (c *Client) variationValue(flagName string, evalCtx evaluator.Context) (any, bool) {
+	result := c.Evaluate(flagName, evalCtx)
+	if result.Reason == evaluator.ReasonError {
+		return nil, false
+	}
+	value, ok := result.Value[valueKey]
+	return value, ok
+}
+
+func (c *Client) poll(ctx context.Context) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		refreshCtx, cancel := context.WithTimeout(ctx, c.pollInterval)
+		if err := c.Refresh(refreshCtx); err != nil {
+			log.Printf("client: Refresh error, serving last known flags - {%v}", err)
+		}
+		cancel()
+	}
+}
+
+func (c *Client) get(ctx context.Context, path string, body any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	c.authorize(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s - %d", ErrClientUnexpectedStatus, path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(body)
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+func notFound(flagName string) evaluator.Result {
+	return evaluator.Result{
+		FlagName:  flagName,
+		Reason:    evaluator.ReasonError,
+		ErrorCode: ErrorCodeFlagNotFound,
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"feature-flag-2/evaluator"
+	"feature-flag-2/models"
+	"testing"
+	"time"
+)
+
+// Клиент против настоящего API проверяют тесты SDK в пакете main, здесь - только логика без сервера
+
+// boolFlag - включенный флаг, data отдает {"value": true}, default_data - {"value": false}
+func boolFlag(name string, version int64) models.Flag {
+	return models.Flag{
+		FlagName:    name,
+		IsEnabled:   true,
+		ActiveFrom:  time.Now().Add(-time.Hour),
+		Data:        models.JSONmap{valueKey: true},
+		DefaultData: models.JSONmap{valueKey: false},
+		Version:     version,
+	}
+}
+
+func TestReplaceFlagsKeepsNewerVersion(t *testing.T) {
+	c := &Client{flags: make(map[string]models.Flag)}
+	c.mu.Lock()
+	c.replaceFlags([]models.Flag{boolFlag("flag_a", 1), boolFlag("flag_b", 1)})
+	c.mu.Unlock()
+	disabled := boolFlag("flag_a", 5)
+	disabled.IsEnabled = false
+	c.applyFlag(disabled)
+	c.applyFlag(boolFlag("flag_a", 3))
+	evalCtx := evaluator.Context{Key: "user"}
+	if result := c.Evaluate("flag_a", evalCtx); result.Reason != evaluator.ReasonDisabled || result.Version != 5 {
+		t.Fatalf("flag after older stream event - %+v, want DISABLED version 5", result)
+	}
+	// GET /flags кэшируется и может отставать от потока
+	c.mu.Lock()
+	c.replaceFlags([]models.Flag{boolFlag("flag_a", 4), boolFlag("flag_b", 2)})
+	c.mu.Unlock()
+	if result := c.Evaluate("flag_a", evalCtx); result.Reason != evaluator.ReasonDisabled || result.Version != 5 {
+		t.Fatalf("flag after stale poll - %+v, want DISABLED version 5", result)
+	}
+	if result := c.Evaluate("flag_b", evalCtx); result.Version != 2 {
+		t.Fatalf("newer polled flag - %+v, want version 2", result)
+	}
+}
+
+func TestNewRequiresProjectWithEnvironment(t *testing.T) {
+	for _, cfg := range []Config{
+		{BaseURL: "http://localhost:8000", Project: "checkout"},
+		{BaseURL: "http://localhost:8000", Environment: "production"},
+	} {
+		if _, err := New(context.Background(), cfg); !errors.Is(err, ErrClientInvalidConfig) {
+			t.Fatalf("New with project {%s} and environment {%s} - %v, want %v",
+				cfg.Project, cfg.Environment, err, ErrClientInvalidConfig)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// типы событий /stream
+const (
+	eventPut    = "put"
+	eventPatch  = "patch"
+	eventDelete = "delete"
+)
+
+// listen держит соединение с /stream и переподключается с Last-Event-ID,
+// пауза между попытками растет до maxReconnectDelay
+func (c *Client) listen(ctx context.Context) {
+	defer c.wg.Done()
+	// у потока нет общего таймаута, поэтому отдельный http.Client с тем же транспортом
+	streamClient := &http.Client{Transport: c.httpClient.Transport}
+	lastEventID := ""
+	delay := time.Second
+	for {
+		connected, err := c.readStream(ctx, streamClient, &lastEventID)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = time.Second
+		}
+		log.Printf("client: stream closed, reconnect in %s - {%v}", delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// readStream читает события до разрыва соединения, connected - сервер принял подключение
+func (c *Client) readStream(ctx context.Context, streamClient *http.Client, lastEventID *string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+c.environmentPath+"/stream", nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.authorize(req)
+	if *lastEventID != "" {
+		req.Header.Set("Last-Event-ID", *lastEventID)
+	}
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("%w: GET /stream - %d", ErrClientUnexpectedStatus, resp.StatusCode)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var id, eventType string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if err := c.applyEvent(eventType, []byte(data.String())); err != nil {
+					return true, err
+				}
+				if id != "" {
+					*lastEventID = id
+				}
+			}
+			id, eventType = "", ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// heartbeat
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(line, "data: "))
+		}
+	}
+	return true, scanner.Err()
+}
+
+func (c *Client) applyEvent(eventType string, data []byte) error {
+	switch eventType {
+	case eventPut:
+		var flags entity.ListOfFlagResponse
+		if err := json.Unmarshal(data, &flags.Body); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.replaceFlags(flags.Body.Flags)
+		c.mu.Unlock()
+	case eventPatch:
+		var patch struct {
+			Flag models.Flag `json:"flag"`
+		}
+		if err := json.Unmarshal(data, &patch); err != nil {
+			return err
+		}
+		c.applyFlag(patch.Flag)
+	case eventDelete:
+		var deleted struct {
+			FlagName string `json:"flag_name"`
+			Version  int64  `json:"version"`
+		}
+		if err := json.Unmarshal(data, &deleted); err != nil {
+			return err
+		}
+		c.mu.RLock()
+		flag := c.flags[deleted.FlagName]
+		c.mu.RUnlock()
+		flag.FlagName = deleted.FlagName
+		flag.IsDeleted = true
+		flag.Version = deleted.Version
+		c.applyFlag(flag)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -9,12 +10,26 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// минимальная длина AUTH_BOOTSTRAP_KEY и число разных символов в нем: ключ admin не должен подбираться
+const (
+	MinBootstrapKeyLength        = 32
+	MinBootstrapKeyDistinctChars = 12
+)
+
+var (
+	ErrConfigNoAuthMethod     = errors.New("AUTH_ENABLED is true, but neither AUTH_BOOTSTRAP_KEY nor AUTH_JWKS_PATH is set")
+	ErrConfigWeakBootstrapKey = errors.New("AUTH_BOOTSTRAP_KEY is too weak")
+)
+
 // Config - contains url for database, server port with server network, secret key for jwt
 type Config struct {
 	DB         DataBaseConfig  `envPrefix:"DB_"`
 	Cache      CacheConfig     `envPrefix:"CACHE_"`
 	Migrations MigrationConfig `envPrefix:"MIGRATION_"`
 	Server     ServerConfig    `envPrefix:"SRV_"`
+	Worker     WorkerConfig    `envPrefix:"WORKER_"`
+	Stream     StreamConfig    `envPrefix:"STREAM_"`
+	Auth       AuthConfig      `envPrefix:"AUTH_"`
 }
 
 // NewConfig - load data from ENV (file or ENV variables)
@@ -40,6 +55,9 @@ func (cfg *Config) parse(pathToEnv string) error {
 	if err := env.Parse(cfg); err != nil {
 		return err
 	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
 	cfg.DB.URL = cfg.DB.url()
 
 	log.Print("config: parse end")
@@ -47,6 +65,11 @@ func (cfg *Config) parse(pathToEnv string) error {
 	return nil
 }
 
+// Validate проверяет настройки, с которыми сервер нельзя запускать
+func (cfg *Config) Validate() error {
+	return cfg.Auth.Validate()
+}
+
 type DataBaseConfig struct {
 	Host     string `env:"HOST"`
 	Port     uint16 `env:"PORT"`
@@ -92,3 +115,64 @@ type ServerConfig struct {
 	Host     string        `env:"HOST"`
 	ShutDown time.Duration `env:"SHUTDOWN"`
 }
+
+type WorkerConfig struct {
+	LifecycleInterval time.Duration `env:"LIFECYCLE_INTERVAL" envDefault:"1m"`
+	ScheduleInterval  time.Duration `env:"SCHEDULE_INTERVAL" envDefault:"30s"`
+	ScheduleBatchSize int           `env:"SCHEDULE_BATCH_SIZE" envDefault:"100"`
+	ListenMaxBackoff  time.Duration `env:"LISTEN_MAX_BACKOFF" envDefault:"30s"`
+}
+
+type StreamConfig struct {
+	Heartbeat time.Duration `env:"HEARTBEAT" envDefault:"15s"`
+	LogSize   int           `env:"LOG_SIZE" envDefault:"1000"`
+}
+
+type AuthConfig struct {
+	// Enabled - проверять API ключи и JWT, без проверки все операции открыты
+	Enabled bool `env:"ENABLED" envDefault:"true"`
+	// BootstrapKey - ключ с правом admin, создается при старте, чтобы выпустить остальные ключи.
+	// Пустой - ключ не создается, непустой короче MinBootstrapKeyLength не принимается
+	BootstrapKey string `env:"BOOTSTRAP_KEY"`
+	// JWKSPath - локальный файл JWKS для проверки JWT пользователей, пустой - принимаются только API ключи
+	JWKSPath string `env:"JWKS_PATH"`
+	// JWTIssuer и JWTAudience - ожидаемые iss и aud, пустые не проверяются
+	JWTIssuer   string `env:"JWT_ISSUER"`
+	JWTAudience string `env:"JWT_AUDIENCE"`
+}
+
+// Validate - при включенной проверке нужен хотя бы один способ войти: ключ admin или JWKS,
+// иначе все запросы получают 401. Ключ admin арендатора default управляет всеми арендаторами,
+// поэтому короткий ключ или ключ из повторяющихся символов не принимается
+func (cfgAuth *AuthConfig) Validate() error {
+	if !cfgAuth.Enabled {
+		return nil
+	}
+	if cfgAuth.BootstrapKey == "" && cfgAuth.JWKSPath == "" {
+		return ErrConfigNoAuthMethod
+	}
+	if cfgAuth.BootstrapKey == "" {
+		return nil
+	}
+	if len(cfgAuth.BootstrapKey) < MinBootstrapKeyLength {
+		return fmt.Errorf(
+			"%w: %d characters, need at least %d",
+			ErrConfigWeakBootstrapKey,
+			len(cfgAuth.BootstrapKey),
+			MinBootstrapKeyLength,
+		)
+	}
+	distinct := make(map[rune]struct{})
+	for _, char := range cfgAuth.BootstrapKey {
+		distinct[char] = struct{}{}
+	}
+	if len(distinct) < MinBootstrapKeyDistinctChars {
+		return fmt.Errorf(
+			"%w: %d distinct characters, need at least %d",
+			ErrConfigWeakBootstrapKey,
+			len(distinct),
+			MinBootstrapKeyDistinctChars,
+		)
+	}
+	return nil
+}
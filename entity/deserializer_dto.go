@@ -1,5 +1,219 @@
 package entity
 
+import (
+	"encoding/json"
+	"errors"
+	"feature-flag-2/models"
+	"time"
+)
+
+var ErrEntityInvalidTargetingKey = errors.New("targetingKey should be a string")
+
+// ofrepTargetingKey - ключ пользователя в контексте OFREP
+const ofrepTargetingKey = "targetingKey"
+
+// FlagDecode - флаг в теле POST /flag и PUT /flag/{name}. Автор, даты и версия
+// задаются сервером, клиент их не передает
+type FlagDecode struct {
+	FlagName      string               `json:"flag_name"`
+	IsEnabled     bool                 `json:"is_enabled"`
+	ActiveFrom    time.Time            `json:"active_from"`
+	ActiveUntil   *time.Time           `json:"active_until,omitempty"`
+	Data          models.JSONmap       `json:"data"`
+	DefaultData   models.JSONmap       `json:"default_data"`
+	Rules         models.Rules         `json:"rules" required:"false"`
+	Rollout       *models.Rollout      `json:"rollout,omitempty"`
+	Variations    models.Variations    `json:"variations" required:"false"`
+	OffVariation  string               `json:"off_variation,omitempty"`
+	Fallthrough   *models.Serve        `json:"fallthrough,omitempty"`
+	Prerequisites models.Prerequisites `json:"prerequisites" required:"false"`
+	Tags          models.StringList    `json:"tags" required:"false"`
+}
+
+// Flag - флаг из тела запроса без автора, дат и версии
+func (fd FlagDecode) Flag() models.Flag {
+	return models.Flag{
+		FlagName:      fd.FlagName,
+		IsEnabled:     fd.IsEnabled,
+		ActiveFrom:    fd.ActiveFrom,
+		ActiveUntil:   fd.ActiveUntil,
+		Data:          fd.Data,
+		DefaultData:   fd.DefaultData,
+		Rules:         fd.Rules,
+		Rollout:       fd.Rollout,
+		Variations:    fd.Variations,
+		OffVariation:  fd.OffVariation,
+		Fallthrough:   fd.Fallthrough,
+		Prerequisites: fd.Prerequisites,
+		Tags:          fd.Tags,
+	}
+}
+
+// SegmentDecode - сегмент в теле POST /segment и PUT /segment/{name}. Автор, даты, версия
+// и удаление задаются сервером, клиент их не передает
+type SegmentDecode struct {
+	SegmentName string              `json:"segment_name"`
+	Description string              `json:"description,omitempty"`
+	Included    models.StringList   `json:"included" required:"false"`
+	Excluded    models.StringList   `json:"excluded" required:"false"`
+	Rules       models.SegmentRules `json:"rules" required:"false"`
+}
+
+// Segment - сегмент из тела запроса без автора, дат и версии
+func (sd SegmentDecode) Segment() models.Segment {
+	return models.Segment{
+		SegmentName: sd.SegmentName,
+		Description: sd.Description,
+		Included:    sd.Included,
+		Excluded:    sd.Excluded,
+		Rules:       sd.Rules,
+	}
+}
+
 type FlagNamesDecode struct {
 	FlagNames []string `json:"flag_names"`
 }
+
+type EvaluationContextDecode struct {
+	Key        string         `json:"key,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// BulkEvaluationDecode - контекст пользователя и необязательные фильтры флагов по именам и тегам
+type BulkEvaluationDecode struct {
+	Key        string         `json:"key,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	FlagNames  []string       `json:"flag_names,omitempty"`
+	Tags       []string       `json:"tags,omitempty"`
+}
+
+// ScheduledChangeDecode - отложенное изменение флага, changes - JSON Merge Patch поверх флага,
+// автор изменения - субъект запроса
+type ScheduledChangeDecode struct {
+	ApplyAt time.Time      `json:"apply_at"`
+	Changes models.JSONmap `json:"changes"`
+	Comment string         `json:"comment,omitempty"`
+}
+
+// RollbackDecode - версия флага из истории, которую нужно восстановить
+type RollbackDecode struct {
+	Version int64  `json:"version" minimum:"1"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// PromoteDecode - перенос состояния флагов из окружения source в окружение target того же проекта.
+// Без confirm - только разница, с confirm - запись, если разница не устарела (If-Match с ETag разницы)
+type PromoteDecode struct {
+	Source    string   `json:"source" minLength:"1" maxLength:"100"`
+	Target    string   `json:"target" minLength:"1" maxLength:"100"`
+	FlagNames []string `json:"flag_names" minItems:"1" uniqueItems:"true"`
+	Confirm   bool     `json:"confirm,omitempty"`
+	Comment   string   `json:"comment,omitempty"`
+}
+
+// APIKeyDecode - новый API ключ: имя для списка и права
+type APIKeyDecode struct {
+	Name   string   `json:"name" minLength:"1" maxLength:"100"`
+	Scopes []string `json:"scopes" minItems:"1" enum:"flags:read,flags:write,flags:evaluate,flags:approve,admin"`
+}
+
+// UserDecode - новый пользователь, Subject - claim sub из JWT
+type UserDecode struct {
+	Subject string `json:"subject" minLength:"1" maxLength:"255"`
+	Email   string `json:"email,omitempty" maxLength:"255"`
+	Name    string `json:"name,omitempty" maxLength:"255"`
+}
+
+// UserUpdateDecode - изменение пользователя, subject не меняется
+type UserUpdateDecode struct {
+	Email      string `json:"email,omitempty" maxLength:"255"`
+	Name       string `json:"name,omitempty" maxLength:"255"`
+	IsDisabled bool   `json:"is_disabled,omitempty"`
+}
+
+// RoleBindingDecode - роль пользователя, без project и environment - во всех проектах и окружениях,
+// environment без project - в этом окружении всех проектов
+type RoleBindingDecode struct {
+	Role        string `json:"role" enum:"viewer,editor,approver,admin"`
+	Project     string `json:"project,omitempty" maxLength:"100"`
+	Environment string `json:"environment,omitempty" maxLength:"100"`
+}
+
+// ProjectDecode - новый проект, без environments у проекта одно окружение default
+type ProjectDecode struct {
+	Name         string   `json:"name" minLength:"1" maxLength:"100" pattern:"^[a-z0-9][a-z0-9_-]*$"`
+	Description  string   `json:"description,omitempty" maxLength:"1000"`
+	Environments []string `json:"environments,omitempty" uniqueItems:"true"`
+}
+
+// EnvironmentDecode - новое окружение проекта, флаги копируются выключенными из окружения source,
+// без source - из первого окружения проекта
+type EnvironmentDecode struct {
+	Name   string `json:"name" minLength:"1" maxLength:"100" pattern:"^[a-z0-9][a-z0-9_-]*$"`
+	Source string `json:"source,omitempty" maxLength:"100"`
+}
+
+// EnvironmentSettingsDecode - настройки окружения: required_approvals больше 0 - изменение и удаление
+// флагов окружения открывают запрос на изменение, который применяется после стольких одобрений
+type EnvironmentSettingsDecode struct {
+	RequiredApprovals int `json:"required_approvals" minimum:"0" maximum:"10"`
+}
+
+// ChangeRequestDecisionDecode - комментарий к одобрению или отклонению запроса на изменение
+type ChangeRequestDecisionDecode struct {
+	Comment string `json:"comment,omitempty" maxLength:"1000"`
+}
+
+// OFREPEvaluationRequest - запрос OFREP, targetingKey и атрибуты пользователя лежат в одном объекте context
+type OFREPEvaluationRequest struct {
+	Context map[string]any `json:"context,omitempty"`
+}
+
+// DecodeOFREPEvaluationRequest разбирает тело запроса OFREP, пустое тело - пустой контекст
+func DecodeOFREPEvaluationRequest(rawBody []byte) (OFREPEvaluationRequest, error) {
+	var request OFREPEvaluationRequest
+	if len(rawBody) == 0 {
+		return request, nil
+	}
+	err := json.Unmarshal(rawBody, &request)
+	return request, err
+}
+
+// EvaluationContext переводит контекст OFREP в контекст вычисления флага
+func (r OFREPEvaluationRequest) EvaluationContext() (EvaluationContextDecode, error) {
+	evalCtx := EvaluationContextDecode{Attributes: make(map[string]any, len(r.Context))}
+	for name, value := range r.Context {
+		if name != ofrepTargetingKey {
+			evalCtx.Attributes[name] = value
+			continue
+		}
+		key, ok := value.(string)
+		if !ok {
+			return evalCtx, ErrEntityInvalidTargetingKey
+		}
+		evalCtx.Key = key
+	}
+	return evalCtx, nil
+}
+
+// TenantDecode - новый арендатор, id попадает в ключи кэша и потоков
+type TenantDecode struct {
+	ID   string `json:"id" minLength:"1" maxLength:"100" pattern:"^[a-z0-9][a-z0-9_-]*$"`
+	Name string `json:"name,omitempty" maxLength:"200"`
+}
+
+// FlagListParams - параметры страницы GET /flags. По умолчанию удаленные флаги скрыты,
+// cursor - next_cursor предыдущей страницы с тем же sort, prefix и search ищут по имени без учета регистра
+type FlagListParams struct {
+	Limit        int       `query:"limit" minimum:"1" maximum:"1000" default:"100"`
+	Cursor       string    `query:"cursor" maxLength:"1000" required:"false"`
+	Sort         string    `query:"sort" enum:"flag_name,-flag_name,created_at,-created_at,updated_at,-updated_at" default:"flag_name"`
+	State        string    `query:"state" enum:"scheduled,active,expired" required:"false"`
+	Enabled      string    `query:"enabled" enum:"true,false" required:"false"`
+	Deleted      string    `query:"deleted" enum:"false,true,all" default:"false"`
+	CreatedBy    string    `query:"created_by" maxLength:"200" required:"false"`
+	Tag          string    `query:"tag" maxLength:"100" required:"false"`
+	UpdatedSince time.Time `query:"updated_since" required:"false"`
+	Prefix       string    `query:"prefix" maxLength:"200" required:"false"`
+	Search       string    `query:"search" maxLength:"200" required:"false"`
+}
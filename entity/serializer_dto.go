@@ -1,8 +1,29 @@
 package entity
 
-import "feature-flag-2/models"
+import (
+	"encoding/json"
+	"feature-flag-2/evaluator"
+	"feature-flag-2/models"
+	"net/http"
+	"strconv"
+)
 
+// коды ошибок OFREP
+const (
+	OFREPErrorCodeParseError          = "PARSE_ERROR"
+	OFREPErrorCodeTargetingKeyMissing = "TARGETING_KEY_MISSING"
+	OFREPErrorCodeInvalidContext      = "INVALID_CONTEXT"
+	OFREPErrorCodeFlagNotFound        = "FLAG_NOT_FOUND"
+	OFREPErrorCodeGeneral             = "GENERAL"
+)
+
+// OFREPReasonTargetingMatch - в OpenFeature причина TARGET_MATCH называется TARGETING_MATCH,
+// остальные причины передаем как есть
+const OFREPReasonTargetingMatch = "TARGETING_MATCH"
+
+// FlagResponse - флаг и его ETag, ETag - версия флага в кавычках
 type FlagResponse struct {
+	ETag string `header:"ETag"`
 	Body struct {
 		Flag models.Flag `json:"flag"`
 	}
@@ -10,13 +31,45 @@ type FlagResponse struct {
 
 func NewFlagResponse(flag models.Flag) *FlagResponse {
 	responseFlag := &FlagResponse{}
+	responseFlag.ETag = `"` + FlagETag(flag.Version) + `"`
 	responseFlag.Body.Flag = flag
 	return responseFlag
 }
 
+// FlagChangeResponse - изменение флага: 200 и новый флаг с его ETag или 202 и открытый запрос
+// на изменение, если окружение требует одобрения
+type FlagChangeResponse struct {
+	Status int
+	ETag   string `header:"ETag"`
+	Body   struct {
+		Flag          *models.Flag          `json:"flag,omitempty"`
+		ChangeRequest *models.ChangeRequest `json:"change_request,omitempty"`
+	}
+}
+
+func NewFlagChangeResponse(flag models.Flag) *FlagChangeResponse {
+	responseFlagChange := &FlagChangeResponse{Status: http.StatusOK}
+	responseFlagChange.ETag = `"` + FlagETag(flag.Version) + `"`
+	responseFlagChange.Body.Flag = &flag
+	return responseFlagChange
+}
+
+func NewPendingFlagChangeResponse(changeRequest models.ChangeRequest) *FlagChangeResponse {
+	responseFlagChange := &FlagChangeResponse{Status: http.StatusAccepted}
+	responseFlagChange.Body.ChangeRequest = &changeRequest
+	return responseFlagChange
+}
+
+// FlagETag - значение ETag флага без кавычек, меняется с каждой версией флага
+func FlagETag(version int64) string {
+	return strconv.FormatInt(version, 10)
+}
+
+// ListOfFlagResponse - флаги, у страницы GET /flags еще next_cursor, пустой - страница последняя
 type ListOfFlagResponse struct {
 	Body struct {
-		Flags []models.Flag `json:"flags"`
+		Flags      []models.Flag `json:"flags"`
+		NextCursor string        `json:"next_cursor,omitempty"`
 	}
 }
 
@@ -25,3 +78,422 @@ func NewListOfFlagResponse(flags []models.Flag) *ListOfFlagResponse {
 	responseListOfFlag.Body.Flags = flags
 	return responseListOfFlag
 }
+
+func NewPageOfFlagResponse(flags []models.Flag, nextCursor string) *ListOfFlagResponse {
+	responseListOfFlag := NewListOfFlagResponse(flags)
+	responseListOfFlag.Body.NextCursor = nextCursor
+	return responseListOfFlag
+}
+
+type EvaluationResponse struct {
+	Body struct {
+		Evaluation evaluator.Result `json:"evaluation"`
+	}
+}
+
+func NewEvaluationResponse(result evaluator.Result) *EvaluationResponse {
+	responseEvaluation := &EvaluationResponse{}
+	responseEvaluation.Body.Evaluation = result
+	return responseEvaluation
+}
+
+type BulkEvaluationResponse struct {
+	Body struct {
+		Evaluations map[string]evaluator.Result `json:"evaluations"`
+	}
+}
+
+func NewBulkEvaluationResponse(results map[string]evaluator.Result) *BulkEvaluationResponse {
+	responseBulkEvaluation := &BulkEvaluationResponse{}
+	responseBulkEvaluation.Body.Evaluations = results
+	return responseBulkEvaluation
+}
+
+type SegmentResponse struct {
+	Body struct {
+		Segment models.Segment `json:"segment"`
+	}
+}
+
+func NewSegmentResponse(segment models.Segment) *SegmentResponse {
+	responseSegment := &SegmentResponse{}
+	responseSegment.Body.Segment = segment
+	return responseSegment
+}
+
+type ListOfSegmentResponse struct {
+	Body struct {
+		Segments []models.Segment `json:"segments"`
+	}
+}
+
+func NewListOfSegmentResponse(segments []models.Segment) *ListOfSegmentResponse {
+	responseListOfSegment := &ListOfSegmentResponse{}
+	responseListOfSegment.Body.Segments = segments
+	return responseListOfSegment
+}
+
+type ListOfLifecycleEventResponse struct {
+	Body struct {
+		Events []models.FlagLifecycleEvent `json:"events"`
+	}
+}
+
+func NewListOfLifecycleEventResponse(events []models.FlagLifecycleEvent) *ListOfLifecycleEventResponse {
+	responseListOfEvents := &ListOfLifecycleEventResponse{}
+	responseListOfEvents.Body.Events = events
+	return responseListOfEvents
+}
+
+type FlagVersionResponse struct {
+	Body struct {
+		FlagVersion models.FlagVersion `json:"flag_version"`
+	}
+}
+
+func NewFlagVersionResponse(flagVersion models.FlagVersion) *FlagVersionResponse {
+	responseFlagVersion := &FlagVersionResponse{}
+	responseFlagVersion.Body.FlagVersion = flagVersion
+	return responseFlagVersion
+}
+
+// ListOfFlagVersionResponse - страница истории флага, Total - число всех версий
+type ListOfFlagVersionResponse struct {
+	Body struct {
+		Versions []models.FlagVersion `json:"versions"`
+		Total    int64                `json:"total"`
+		Limit    int                  `json:"limit"`
+		Offset   int                  `json:"offset"`
+	}
+}
+
+func NewListOfFlagVersionResponse(
+	versions []models.FlagVersion,
+	total int64,
+	limit int,
+	offset int,
+) *ListOfFlagVersionResponse {
+	responseListOfVersions := &ListOfFlagVersionResponse{}
+	responseListOfVersions.Body.Versions = versions
+	responseListOfVersions.Body.Total = total
+	responseListOfVersions.Body.Limit = limit
+	responseListOfVersions.Body.Offset = offset
+	return responseListOfVersions
+}
+
+type ScheduledChangeResponse struct {
+	Body struct {
+		ScheduledChange models.ScheduledChange `json:"scheduled_change"`
+	}
+}
+
+func NewScheduledChangeResponse(change models.ScheduledChange) *ScheduledChangeResponse {
+	responseScheduledChange := &ScheduledChangeResponse{}
+	responseScheduledChange.Body.ScheduledChange = change
+	return responseScheduledChange
+}
+
+type ListOfScheduledChangeResponse struct {
+	Body struct {
+		ScheduledChanges []models.ScheduledChange `json:"scheduled_changes"`
+	}
+}
+
+func NewListOfScheduledChangeResponse(changes []models.ScheduledChange) *ListOfScheduledChangeResponse {
+	responseListOfScheduledChanges := &ListOfScheduledChangeResponse{}
+	responseListOfScheduledChanges.Body.ScheduledChanges = changes
+	return responseListOfScheduledChanges
+}
+
+// APIKeyResponse - ключ без секрета, Key заполнен только в ответе на создание
+type APIKeyResponse struct {
+	Body struct {
+		APIKey models.APIKey `json:"api_key"`
+		Key    string        `json:"key,omitempty"`
+	}
+}
+
+func NewAPIKeyResponse(apiKey models.APIKey, key string) *APIKeyResponse {
+	responseAPIKey := &APIKeyResponse{}
+	responseAPIKey.Body.APIKey = apiKey
+	responseAPIKey.Body.Key = key
+	return responseAPIKey
+}
+
+type ListOfAPIKeyResponse struct {
+	Body struct {
+		APIKeys []models.APIKey `json:"api_keys"`
+	}
+}
+
+func NewListOfAPIKeyResponse(apiKeys []models.APIKey) *ListOfAPIKeyResponse {
+	responseListOfAPIKeys := &ListOfAPIKeyResponse{}
+	responseListOfAPIKeys.Body.APIKeys = apiKeys
+	return responseListOfAPIKeys
+}
+
+// UserResponse - пользователь и его роли
+type UserResponse struct {
+	Body models.UserAccess
+}
+
+func NewUserResponse(access models.UserAccess) *UserResponse {
+	responseUser := &UserResponse{}
+	responseUser.Body = access
+	return responseUser
+}
+
+type ListOfUserResponse struct {
+	Body struct {
+		Users []models.User `json:"users"`
+	}
+}
+
+func NewListOfUserResponse(users []models.User) *ListOfUserResponse {
+	responseListOfUsers := &ListOfUserResponse{}
+	responseListOfUsers.Body.Users = users
+	return responseListOfUsers
+}
+
+// FieldChange - поле флага до и после изменения
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// FlagPromotion - разница флага между окружениями, без Changes флаг уже совпадает
+type FlagPromotion struct {
+	FlagName      string        `json:"flag_name"`
+	SourceVersion int64         `json:"source_version"`
+	TargetVersion int64         `json:"target_version"`
+	Changes       []FieldChange `json:"changes"`
+}
+
+// PromoteResponse - разница по флагам и ее ETag, Applied - разница записана в окружение target
+type PromoteResponse struct {
+	ETag string `header:"ETag"`
+	Body struct {
+		Source  string          `json:"source"`
+		Target  string          `json:"target"`
+		Applied bool            `json:"applied"`
+		Flags   []FlagPromotion `json:"flags"`
+	}
+}
+
+func NewPromoteResponse(
+	source string,
+	target string,
+	promotions []FlagPromotion,
+	etag string,
+	applied bool,
+) *PromoteResponse {
+	responsePromote := &PromoteResponse{ETag: `"` + etag + `"`}
+	responsePromote.Body.Source = source
+	responsePromote.Body.Target = target
+	responsePromote.Body.Applied = applied
+	responsePromote.Body.Flags = promotions
+	return responsePromote
+}
+
+type ProjectResponse struct {
+	Body models.Project
+}
+
+func NewProjectResponse(project models.Project) *ProjectResponse {
+	responseProject := &ProjectResponse{}
+	responseProject.Body = project
+	return responseProject
+}
+
+type ListOfProjectResponse struct {
+	Body struct {
+		Projects []models.Project `json:"projects"`
+	}
+}
+
+func NewListOfProjectResponse(projects []models.Project) *ListOfProjectResponse {
+	responseListOfProjects := &ListOfProjectResponse{}
+	responseListOfProjects.Body.Projects = projects
+	return responseListOfProjects
+}
+
+type EnvironmentResponse struct {
+	Body models.Environment
+}
+
+func NewEnvironmentResponse(environment models.Environment) *EnvironmentResponse {
+	responseEnvironment := &EnvironmentResponse{}
+	responseEnvironment.Body = environment
+	return responseEnvironment
+}
+
+type ListOfEnvironmentResponse struct {
+	Body struct {
+		Environments []models.Environment `json:"environments"`
+	}
+}
+
+func NewListOfEnvironmentResponse(environments []models.Environment) *ListOfEnvironmentResponse {
+	responseListOfEnvironments := &ListOfEnvironmentResponse{}
+	responseListOfEnvironments.Body.Environments = environments
+	return responseListOfEnvironments
+}
+
+// ChangeRequestResponse - запрос на изменение и разница между флагом в версии base_version
+// и предложенным флагом. Status - 202 у запроса, открытого вместо удаления флага
+type ChangeRequestResponse struct {
+	Status int
+	Body   struct {
+		ChangeRequest models.ChangeRequest `json:"change_request"`
+		Changes       []FieldChange        `json:"changes"`
+	}
+}
+
+func NewChangeRequestResponse(changeRequest models.ChangeRequest, changes []FieldChange) *ChangeRequestResponse {
+	responseChangeRequest := &ChangeRequestResponse{Status: http.StatusOK}
+	responseChangeRequest.Body.ChangeRequest = changeRequest
+	responseChangeRequest.Body.Changes = changes
+	return responseChangeRequest
+}
+
+type ListOfChangeRequestResponse struct {
+	Body struct {
+		ChangeRequests []models.ChangeRequest `json:"change_requests"`
+	}
+}
+
+func NewListOfChangeRequestResponse(changeRequests []models.ChangeRequest) *ListOfChangeRequestResponse {
+	responseListOfChangeRequests := &ListOfChangeRequestResponse{}
+	responseListOfChangeRequests.Body.ChangeRequests = changeRequests
+	return responseListOfChangeRequests
+}
+
+// ofrepValueKey - поле вариации со значением для типизированных провайдеров OpenFeature
+const ofrepValueKey = "value"
+
+// OFREPEvaluation - вычисленный флаг в формате OFREP, при ошибке заполнены только key, errorCode и errorDetails
+type OFREPEvaluation struct {
+	Key          string         `json:"key"`
+	Value        any            `json:"value,omitempty"`
+	Reason       string         `json:"reason,omitempty"`
+	Variant      string         `json:"variant,omitempty"`
+	Metadata     map[string]any `json:"metadata,omitempty"`
+	ErrorCode    string         `json:"errorCode,omitempty"`
+	ErrorDetails string         `json:"errorDetails,omitempty"`
+}
+
+// NewOFREPEvaluation переводит результат вычисления в OFREP, удаленный флаг - FLAG_NOT_FOUND,
+// нехватка атрибута бакетирования - TARGETING_KEY_MISSING, если в контексте нет targetingKey.
+// Value - значение вариации: {"value": x} отдается как x, чтобы провайдеры OpenFeature
+// получали bool/string/number, вариация с другими полями отдается объектом целиком
+func NewOFREPEvaluation(result evaluator.Result, targetingKey string) OFREPEvaluation {
+	switch result.Reason {
+	case evaluator.ReasonDeleted:
+		return NewOFREPError(result.FlagName, OFREPErrorCodeFlagNotFound, "flag is deleted")
+	case evaluator.ReasonError:
+		errorCode := OFREPErrorCodeGeneral
+		if result.ErrorCode == evaluator.ErrorCodeBucketAttributeMissing {
+			errorCode = OFREPErrorCodeInvalidContext
+			if targetingKey == "" {
+				errorCode = OFREPErrorCodeTargetingKeyMissing
+			}
+		}
+		return NewOFREPError(result.FlagName, errorCode, result.ErrorCode)
+	}
+	reason := string(result.Reason)
+	if result.Reason == evaluator.ReasonTargetMatch {
+		reason = OFREPReasonTargetingMatch
+	}
+	return OFREPEvaluation{
+		Key:      result.FlagName,
+		Value:    ofrepValue(result.Value),
+		Reason:   reason,
+		Variant:  result.Variation,
+		Metadata: map[string]any{"version": result.Version},
+	}
+}
+
+// ofrepValue разворачивает вариацию из одного поля value в скалярное значение
+func ofrepValue(variation models.JSONmap) any {
+	if value, ok := variation[ofrepValueKey]; ok && len(variation) == 1 {
+		return value
+	}
+	return variation
+}
+
+func NewOFREPError(flagKey, errorCode, errorDetails string) OFREPEvaluation {
+	return OFREPEvaluation{Key: flagKey, ErrorCode: errorCode, ErrorDetails: errorDetails}
+}
+
+type OFREPEvaluationResponse struct {
+	Status int
+	Body   OFREPEvaluation
+}
+
+// NewOFREPEvaluationResponse - 200 для вычисленного флага, 404 для FLAG_NOT_FOUND, 400 для остальных ошибок
+func NewOFREPEvaluationResponse(evaluation OFREPEvaluation) *OFREPEvaluationResponse {
+	responseEvaluation := &OFREPEvaluationResponse{Status: http.StatusOK}
+	switch evaluation.ErrorCode {
+	case "":
+	case OFREPErrorCodeFlagNotFound:
+		responseEvaluation.Status = http.StatusNotFound
+	default:
+		responseEvaluation.Status = http.StatusBadRequest
+	}
+	responseEvaluation.Body = evaluation
+	return responseEvaluation
+}
+
+type OFREPBulkEvaluationResponse struct {
+	Status int
+	ETag   string `header:"ETag"`
+	Body   struct {
+		Flags        []OFREPEvaluation `json:"flags,omitzero"`
+		ErrorCode    string            `json:"errorCode,omitempty"`
+		ErrorDetails string            `json:"errorDetails,omitempty"`
+	}
+}
+
+func NewOFREPBulkEvaluationResponse(evaluations []OFREPEvaluation, etag string) *OFREPBulkEvaluationResponse {
+	responseBulkEvaluation := &OFREPBulkEvaluationResponse{Status: http.StatusOK, ETag: etag}
+	responseBulkEvaluation.Body.Flags = evaluations
+	return responseBulkEvaluation
+}
+
+// NewOFREPBulkErrorResponse - ошибка всего запроса (400), а не отдельного флага
+func NewOFREPBulkErrorResponse(errorCode, errorDetails string) *OFREPBulkEvaluationResponse {
+	responseBulkEvaluation := &OFREPBulkEvaluationResponse{Status: http.StatusBadRequest}
+	responseBulkEvaluation.Body.ErrorCode = errorCode
+	responseBulkEvaluation.Body.ErrorDetails = errorDetails
+	return responseBulkEvaluation
+}
+
+// TenantResponse - арендатор, в ответе на создание - его ключ admin, ключ показывается один раз
+type TenantResponse struct {
+	Body struct {
+		Tenant models.Tenant  `json:"tenant"`
+		APIKey *models.APIKey `json:"api_key,omitempty"`
+		Key    string         `json:"key,omitempty"`
+	}
+}
+
+func NewTenantResponse(tenant models.Tenant, apiKey *models.APIKey, key string) *TenantResponse {
+	responseTenant := &TenantResponse{}
+	responseTenant.Body.Tenant = tenant
+	responseTenant.Body.APIKey = apiKey
+	responseTenant.Body.Key = key
+	return responseTenant
+}
+
+type ListOfTenantResponse struct {
+	Body struct {
+		Tenants []models.Tenant `json:"tenants"`
+	}
+}
+
+func NewListOfTenantResponse(tenants []models.Tenant) *ListOfTenantResponse {
+	responseListOfTenants := &ListOfTenantResponse{}
+	responseListOfTenants.Body.Tenants = tenants
+	return responseListOfTenants
+}
@@ -0,0 +1,66 @@
+package evaluator
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"feature-flag-2/models"
+	"strconv"
+)
+
+// BucketCount - количество бакетов, 1% раскатки = 1000 бакетов
+const BucketCount = 100000
+
+// DefaultBucketBy - атрибут бакетирования по умолчанию
+const DefaultBucketBy = attributeKey
+
+// splitSaltSuffix - добавка к соли для бакета распределения по весам
+const splitSaltSuffix = ".split"
+
+// Bucket возвращает бакет [0, BucketCount) для значения атрибута,
+// одно и то же значение всегда попадает в один и тот же бакет флага
+func Bucket(flagName, salt, value string) int {
+	sum := sha1.Sum([]byte(flagName + "." + salt + "." + value))
+	return int(binary.BigEndian.Uint64(sum[:8]) % BucketCount)
+}
+
+// bucketValue - значение атрибута бакетирования в виде строки
+func bucketValue(rollout *models.Rollout, evalCtx Context) (string, bool) {
+	bucketBy := rollout.BucketBy
+	if bucketBy == "" {
+		bucketBy = DefaultBucketBy
+	}
+	value, ok := evalCtx.attribute(bucketBy)
+	if !ok {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	}
+	return "", false
+}
+
+// inRollout - пользователь в раскатке, если его бакет меньше порога процента,
+// поэтому увеличение процента никого не выкидывает из раскатки
+func inRollout(bucket int, percentage float64) bool {
+	return bucket < int(percentage*BucketCount/100)
+}
+
+// splitVariation выбирает вариацию по бакету и накопленным весам,
+// остаток от округления весов достается последней вариации
+func splitVariation(split []models.WeightedVariation, bucket int) string {
+	threshold := 0
+	for _, weighted := range split {
+		threshold += int(weighted.Weight * BucketCount / 100)
+		if bucket < threshold {
+			return weighted.Variation
+		}
+	}
+	return split[len(split)-1].Variation
+}
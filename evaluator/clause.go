@@ -0,0 +1,240 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"feature-flag-2/models"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// attributeKey - имя атрибута, которое указывает на Context.Key
+const attributeKey = "key"
+
+// размер и время жизни кэша регулярок: правила пишутся через API всеми арендаторами,
+// поэтому кэш ограничен, а регулярки удаленных и измененных правил со временем вытесняются
+const (
+	regexCacheSize = 1024
+	regexCacheTTL  = time.Hour
+)
+
+// regexCache - скомпилированные регулярки из правил, чтобы не компилировать на каждый запрос
+var regexCache = expirable.NewLRU[string, *regexp.Regexp](regexCacheSize, nil, regexCacheTTL)
+
+// attribute возвращает значение атрибута из контекста
+func (c Context) attribute(name string) (any, bool) {
+	if name == attributeKey {
+		return c.Key, c.Key != ""
+	}
+	value, ok := c.Attributes[name]
+	return value, ok && value != nil
+}
+
+// matchClauses - правило срабатывает, если выполнены все его условия
+func matchClauses(clauses []models.Clause, evalCtx Context, store Store) bool {
+	for _, clause := range clauses {
+		if !matchClause(clause, evalCtx, store) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchClause проверяет одно условие, атрибут-массив совпадает, если совпал любой его элемент
+func matchClause(clause models.Clause, evalCtx Context, store Store) bool {
+	if clause.Operator.IsSegmentOperator() {
+		inSegment := matchAnySegment(clause.Values, evalCtx, store)
+		if clause.Operator == models.OperatorNotInSegment {
+			return !inSegment
+		}
+		return inSegment
+	}
+	value, ok := evalCtx.attribute(clause.Attribute)
+	if !ok {
+		return clause.Operator == models.OperatorNotIn
+	}
+	attrValues, ok := value.([]any)
+	if !ok {
+		attrValues = []any{value}
+	}
+	if clause.Operator == models.OperatorNotIn {
+		for _, attrValue := range attrValues {
+			if matchAnyValue(models.OperatorIn, attrValue, clause.Values) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, attrValue := range attrValues {
+		if matchAnyValue(clause.Operator, attrValue, clause.Values) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnySegment(segmentNames []any, evalCtx Context, store Store) bool {
+	if store == nil {
+		return false
+	}
+	for _, value := range segmentNames {
+		segmentName, ok := value.(string)
+		if !ok {
+			continue
+		}
+		segment, ok := store.Segment(segmentName)
+		if ok && matchSegment(segment, evalCtx) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegment - пользователь в сегменте, если он в included, либо не в excluded и сработало правило
+func matchSegment(segment models.Segment, evalCtx Context) bool {
+	if evalCtx.Key != "" {
+		if segment.Included.Contains(evalCtx.Key) {
+			return true
+		}
+		if segment.Excluded.Contains(evalCtx.Key) {
+			return false
+		}
+	}
+	for _, rule := range segment.Rules {
+		// правила сегмента не ссылаются на другие сегменты, store не нужен
+		if matchClauses(rule.Clauses, evalCtx, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyValue(op models.Operator, attrValue any, clauseValues []any) bool {
+	for _, clauseValue := range clauseValues {
+		if matchValue(op, attrValue, clauseValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchValue(op models.Operator, attrValue, clauseValue any) bool {
+	switch op {
+	case models.OperatorIn, models.OperatorEquals:
+		return equalValues(attrValue, clauseValue)
+	case models.OperatorStartsWith, models.OperatorEndsWith, models.OperatorContains:
+		attr, ok1 := attrValue.(string)
+		target, ok2 := clauseValue.(string)
+		if !ok1 || !ok2 {
+			return false
+		}
+		switch op {
+		case models.OperatorStartsWith:
+			return strings.HasPrefix(attr, target)
+		case models.OperatorEndsWith:
+			return strings.HasSuffix(attr, target)
+		default:
+			return strings.Contains(attr, target)
+		}
+	case models.OperatorRegex:
+		attr, ok1 := attrValue.(string)
+		pattern, ok2 := clauseValue.(string)
+		if !ok1 || !ok2 {
+			return false
+		}
+		re, err := compileRegex(pattern)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(attr)
+	case models.OperatorSemverGT, models.OperatorSemverLT:
+		attr, ok1 := toSemver(attrValue)
+		target, ok2 := toSemver(clauseValue)
+		if !ok1 || !ok2 {
+			return false
+		}
+		if op == models.OperatorSemverGT {
+			return attr.compare(target) > 0
+		}
+		return attr.compare(target) < 0
+	case models.OperatorNumberGT, models.OperatorNumberLT:
+		attr, ok1 := toFloat(attrValue)
+		target, ok2 := toFloat(clauseValue)
+		if !ok1 || !ok2 {
+			return false
+		}
+		if op == models.OperatorNumberGT {
+			return attr > target
+		}
+		return attr < target
+	case models.OperatorBefore, models.OperatorAfter:
+		attr, ok1 := toTime(attrValue)
+		target, ok2 := toTime(clauseValue)
+		if !ok1 || !ok2 {
+			return false
+		}
+		if op == models.OperatorBefore {
+			return attr.Before(target)
+		}
+		return attr.After(target)
+	}
+	return false
+}
+
+func equalValues(a, b any) bool {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		return ok && af == bf
+	}
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	}
+	return false
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toTime - дата в RFC3339 либо unix время в миллисекундах
+func toTime(value any) (time.Time, bool) {
+	if s, ok := value.(string); ok {
+		t, err := time.Parse(time.RFC3339, s)
+		return t, err == nil
+	}
+	if ms, ok := toFloat(value); ok {
+		return time.UnixMilli(int64(ms)), true
+	}
+	return time.Time{}, false
+}
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Get(pattern); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Add(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,198 @@
+package evaluator
+
+import (
+	"feature-flag-2/models"
+	"time"
+)
+
+// Reason - причина, по которой флаг вернул значение
+type Reason string
+
+const (
+	// ReasonDisabled - флаг выключен, отдаем off вариацию
+	ReasonDisabled Reason = "DISABLED"
+
+	// ReasonNotYetActive - ActiveFrom еще не наступил, отдаем off вариацию
+	ReasonNotYetActive Reason = "NOT_YET_ACTIVE"
+
+	// ReasonExpired - ActiveUntil уже прошел, отдаем off вариацию
+	ReasonExpired Reason = "EXPIRED"
+
+	// ReasonDeleted - флаг удален (soft delete), отдаем off вариацию
+	ReasonDeleted Reason = "DELETED"
+
+	// ReasonTargetMatch - контекст попал под правило таргетинга
+	ReasonTargetMatch Reason = "TARGET_MATCH"
+
+	// ReasonDefault - флаг включен, правила не сработали, отдаем fallthrough вариацию
+	ReasonDefault Reason = "DEFAULT"
+
+	// ReasonSplit - вариация выбрана процентной раскаткой по бакету пользователя
+	ReasonSplit Reason = "SPLIT"
+
+	// ReasonError - флаг не удалось вычислить, отдаем off вариацию
+	ReasonError Reason = "ERROR"
+
+	// ReasonPrerequisiteFailed - флаг-пререквизит не отдал нужную вариацию, отдаем off вариацию
+	ReasonPrerequisiteFailed Reason = "PREREQUISITE_FAILED"
+)
+
+const (
+	// ErrorCodeBucketAttributeMissing - в контексте нет атрибута для бакетирования
+	ErrorCodeBucketAttributeMissing = "BUCKET_ATTRIBUTE_MISSING"
+
+	// ErrorCodePrerequisiteCycle - пререквизиты флага образуют цикл
+	ErrorCodePrerequisiteCycle = "PREREQUISITE_CYCLE"
+)
+
+// Context - контекст вычисления флага: ключ пользователя и его атрибуты
+type Context struct {
+	Key        string         `json:"key"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Result - результат вычисления флага
+type Result struct {
+	FlagName  string         `json:"flag_name"`
+	Value     models.JSONmap `json:"value"`
+	Variation string         `json:"variation"`
+	Reason    Reason         `json:"reason"`
+	// RuleIndex - индекс сработавшего правила, если Reason = TARGET_MATCH
+	RuleIndex *int `json:"rule_index,omitempty"`
+	// PrerequisiteFlag - флаг-пререквизит, если Reason = PREREQUISITE_FAILED
+	PrerequisiteFlag string `json:"prerequisite_flag,omitempty"`
+	// ErrorCode - код ошибки, если Reason = ERROR
+	ErrorCode string `json:"error_code,omitempty"`
+	Version   int64  `json:"version"`
+}
+
+// evaluation - состояние одного вычисления, общее для флага и его пререквизитов
+type evaluation struct {
+	evalCtx Context
+	store   Store
+	now     time.Time
+	// visiting - флаги на текущем пути по пререквизитам, для защиты от циклов
+	visiting map[string]struct{}
+}
+
+// Evaluate вычисляет значение флага для контекста на момент now,
+// сегменты и флаги-пререквизиты берутся из store
+func Evaluate(flag models.Flag, evalCtx Context, store Store, now time.Time) Result {
+	e := &evaluation{
+		evalCtx:  evalCtx,
+		store:    store,
+		now:      now,
+		visiting: make(map[string]struct{}),
+	}
+	return e.evaluate(flag)
+}
+
+func (e *evaluation) evaluate(flag models.Flag) Result {
+	result := Result{
+		FlagName: flag.FlagName,
+		Version:  flag.Version,
+	}
+	switch {
+	case flag.IsDeleted:
+		result.Reason = ReasonDeleted
+	case !flag.IsEnabled:
+		result.Reason = ReasonDisabled
+	case flag.StateAt(e.now) == models.FlagStateScheduled:
+		result.Reason = ReasonNotYetActive
+	case flag.StateAt(e.now) == models.FlagStateExpired:
+		result.Reason = ReasonExpired
+	default:
+		if !e.checkPrerequisites(flag, &result) {
+			break
+		}
+		for i, rule := range flag.Rules {
+			if matchClauses(rule.Clauses, e.evalCtx, e.store) {
+				result.Reason = ReasonTargetMatch
+				result.RuleIndex = &i
+				result.serve(flag, rule.Serve)
+				return result
+			}
+		}
+		evaluateFallthrough(flag, e.evalCtx, &result)
+		return result
+	}
+	result.serve(flag, flag.OffVariationName())
+	return result
+}
+
+// checkPrerequisites рекурсивно вычисляет пререквизиты, false - если хотя бы один не выполнен
+func (e *evaluation) checkPrerequisites(flag models.Flag, result *Result) bool {
+	if len(flag.Prerequisites) == 0 {
+		return true
+	}
+	if _, ok := e.visiting[flag.FlagName]; ok {
+		result.Reason = ReasonError
+		result.ErrorCode = ErrorCodePrerequisiteCycle
+		return false
+	}
+	e.visiting[flag.FlagName] = struct{}{}
+	defer delete(e.visiting, flag.FlagName)
+	for _, prerequisite := range flag.Prerequisites {
+		var prerequisiteResult Result
+		prerequisiteFlag, ok := e.store.Flag(prerequisite.Flag)
+		if ok {
+			prerequisiteResult = e.evaluate(prerequisiteFlag)
+		}
+		if !ok || !prerequisiteResult.Reason.isOn() || prerequisiteResult.Variation != prerequisite.Variation {
+			if prerequisiteResult.ErrorCode == ErrorCodePrerequisiteCycle {
+				result.Reason = ReasonError
+				result.ErrorCode = ErrorCodePrerequisiteCycle
+				return false
+			}
+			result.Reason = ReasonPrerequisiteFailed
+			result.PrerequisiteFlag = prerequisite.Flag
+			return false
+		}
+	}
+	return true
+}
+
+// isOn - флаг включен и вычислен по правилам, а не отдал off вариацию
+func (r Reason) isOn() bool {
+	return r == ReasonTargetMatch || r == ReasonDefault || r == ReasonSplit
+}
+
+// evaluateFallthrough - ни одно правило не сработало: раскатка по проценту и/или распределение по весам
+func evaluateFallthrough(flag models.Flag, evalCtx Context, result *Result) {
+	fallthroughServe := flag.FallthroughServe()
+	if flag.Rollout == nil && len(fallthroughServe.Split) == 0 {
+		result.Reason = ReasonDefault
+		result.serve(flag, fallthroughServe.Variation)
+		return
+	}
+	rollout := flag.Rollout
+	if rollout == nil {
+		rollout = &models.Rollout{Percentage: 100}
+	}
+	value, ok := bucketValue(rollout, evalCtx)
+	if !ok {
+		result.Reason = ReasonError
+		result.ErrorCode = ErrorCodeBucketAttributeMissing
+		result.serve(flag, flag.OffVariationName())
+		return
+	}
+	result.Reason = ReasonSplit
+	if !inRollout(Bucket(flag.FlagName, rollout.Salt, value), rollout.Percentage) {
+		result.serve(flag, flag.OffVariationName())
+		return
+	}
+	if len(fallthroughServe.Split) == 0 {
+		result.serve(flag, fallthroughServe.Variation)
+		return
+	}
+	// для распределения по весам отдельный хэш, иначе он коррелирует с порогом раскатки
+	bucket := Bucket(flag.FlagName, rollout.Salt+splitSaltSuffix, value)
+	result.serve(flag, splitVariation(fallthroughServe.Split, bucket))
+}
+
+// serve записывает в результат вариацию флага по имени
+func (r *Result) serve(flag models.Flag, variationName string) {
+	variation, _ := flag.FindVariation(variationName)
+	r.Variation = variationName
+	r.Value = variation.Value
+}
@@ -0,0 +1,100 @@
+package evaluator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semver - версия вида MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]
+type semver struct {
+	parts      [3]int
+	prerelease []string
+}
+
+// parseSemver разбирает версию, допускает префикс "v" и неполные версии ("1", "1.2")
+func parseSemver(s string) (semver, bool) {
+	var v semver
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		if i == len(s)-1 {
+			return v, false
+		}
+		v.prerelease = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.parts[i] = n
+	}
+	return v, true
+}
+
+func toSemver(value any) (semver, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return semver{}, false
+	}
+	return parseSemver(s)
+}
+
+// compare сравнивает версии по правилам semver 2.0.0
+func (v semver) compare(other semver) int {
+	for i := range v.parts {
+		if v.parts[i] != other.parts[i] {
+			if v.parts[i] > other.parts[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+	switch {
+	case len(v.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(other.prerelease); i++ {
+		if c := comparePrerelease(v.prerelease[i], other.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.prerelease) > len(other.prerelease):
+		return 1
+	case len(v.prerelease) < len(other.prerelease):
+		return -1
+	}
+	return 0
+}
+
+func comparePrerelease(a, b string) int {
+	an, errA := strconv.Atoi(a)
+	bn, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		switch {
+		case an > bn:
+			return 1
+		case an < bn:
+			return -1
+		}
+		return 0
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
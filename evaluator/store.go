@@ -0,0 +1,44 @@
+package evaluator
+
+import "feature-flag-2/models"
+
+// Store - флаги-пререквизиты и сегменты, доступные при вычислении флага
+type Store interface {
+	Flag(name string) (models.Flag, bool)
+	Segment(name string) (models.Segment, bool)
+}
+
+// MapStore - Store поверх заранее загруженных флагов и сегментов
+type MapStore struct {
+	flags    map[string]models.Flag
+	segments map[string]models.Segment
+}
+
+func NewMapStore(flags []models.Flag, segments []models.Segment) *MapStore {
+	store := &MapStore{
+		flags:    make(map[string]models.Flag, len(flags)),
+		segments: make(map[string]models.Segment, len(segments)),
+	}
+	for _, flag := range flags {
+		store.flags[flag.FlagName] = flag
+	}
+	for _, segment := range segments {
+		store.segments[segment.SegmentName] = segment
+	}
+	return store
+}
+
+// Flag возвращает флаг по имени, удаленный флаг тоже отдаем - он вычислится в DELETED
+func (s *MapStore) Flag(name string) (models.Flag, bool) {
+	flag, ok := s.flags[name]
+	return flag, ok
+}
+
+// Segment возвращает сегмент по имени, удаленные сегменты не отдаем
+func (s *MapStore) Segment(name string) (models.Segment, bool) {
+	segment, ok := s.segments[name]
+	if !ok || segment.IsDeleted {
+		return models.Segment{}, false
+	}
+	return segment, true
+}
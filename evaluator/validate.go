@@ -0,0 +1,169 @@
+package evaluator
+
+import (
+	"errors"
+	"feature-flag-2/models"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	ErrEvaluatorInvalidRule = errors.New("invalid targeting rule")
+
+	ErrEvaluatorInvalidRollout = errors.New("invalid rollout")
+
+	ErrEvaluatorInvalidVariation = errors.New("invalid variation")
+
+	ErrEvaluatorInvalidSegment = errors.New("invalid segment")
+
+	ErrEvaluatorInvalidSchedule = errors.New("invalid schedule")
+)
+
+// weightsEpsilon - допустимая погрешность суммы весов
+const weightsEpsilon = 0.001
+
+// ValidateFlag проверяет расписание, вариации, правила и раскатку флага до записи в БД
+func ValidateFlag(flag models.Flag) error {
+	if flag.ActiveUntil != nil && !flag.ActiveUntil.After(flag.ActiveFrom) {
+		return fmt.Errorf("%w: active_until must be after active_from", ErrEvaluatorInvalidSchedule)
+	}
+	if err := validateVariations(flag); err != nil {
+		return fmt.Errorf("%w: %v", ErrEvaluatorInvalidVariation, err)
+	}
+	for i, rule := range flag.Rules {
+		if err := validateRule(flag, rule); err != nil {
+			return fmt.Errorf("%w: rule {%d} - %v", ErrEvaluatorInvalidRule, i, err)
+		}
+	}
+	if flag.Rollout != nil {
+		if flag.Rollout.Percentage < 0 || flag.Rollout.Percentage > 100 {
+			return fmt.Errorf("%w: percentage {%v} out of range [0, 100]", ErrEvaluatorInvalidRollout, flag.Rollout.Percentage)
+		}
+	}
+	return nil
+}
+
+func validateVariations(flag models.Flag) error {
+	names := make(map[string]struct{}, len(flag.Variations))
+	for _, variation := range flag.Variations {
+		if strings.TrimSpace(variation.Name) == "" {
+			return errors.New("empty variation name")
+		}
+		if _, ok := names[variation.Name]; ok {
+			return fmt.Errorf("duplicate variation {%s}", variation.Name)
+		}
+		names[variation.Name] = struct{}{}
+	}
+	if _, ok := flag.FindVariation(flag.OffVariationName()); !ok {
+		return fmt.Errorf("unknown off_variation {%s}", flag.OffVariation)
+	}
+	fallthroughServe := flag.FallthroughServe()
+	if len(fallthroughServe.Split) == 0 {
+		if _, ok := flag.FindVariation(fallthroughServe.Variation); !ok {
+			return fmt.Errorf("unknown fallthrough variation {%s}", fallthroughServe.Variation)
+		}
+		return nil
+	}
+	total := 0.0
+	for _, weighted := range fallthroughServe.Split {
+		if _, ok := flag.FindVariation(weighted.Variation); !ok {
+			return fmt.Errorf("unknown split variation {%s}", weighted.Variation)
+		}
+		if weighted.Weight < 0 {
+			return fmt.Errorf("negative weight of variation {%s}", weighted.Variation)
+		}
+		total += weighted.Weight
+	}
+	if math.Abs(total-100) > weightsEpsilon {
+		return fmt.Errorf("sum of split weights {%v} must be 100", total)
+	}
+	return nil
+}
+
+func validateRule(flag models.Flag, rule models.Rule) error {
+	if _, ok := flag.FindVariation(rule.Serve); !ok {
+		return fmt.Errorf("unknown serve variation {%s}", rule.Serve)
+	}
+	if len(rule.Clauses) == 0 {
+		return errors.New("rule without clauses")
+	}
+	for i, clause := range rule.Clauses {
+		if err := validateClause(clause); err != nil {
+			return fmt.Errorf("clause {%d} - %v", i, err)
+		}
+	}
+	return nil
+}
+
+// ValidateSegment проверяет правила сегмента до записи в БД
+func ValidateSegment(segment models.Segment) error {
+	for i, rule := range segment.Rules {
+		if len(rule.Clauses) == 0 {
+			return fmt.Errorf("%w: rule {%d} - rule without clauses", ErrEvaluatorInvalidSegment, i)
+		}
+		for j, clause := range rule.Clauses {
+			if clause.Operator.IsSegmentOperator() {
+				return fmt.Errorf("%w: rule {%d} clause {%d} - segment can not reference segments", ErrEvaluatorInvalidSegment, i, j)
+			}
+			if err := validateClause(clause); err != nil {
+				return fmt.Errorf("%w: rule {%d} clause {%d} - %v", ErrEvaluatorInvalidSegment, i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateClause(clause models.Clause) error {
+	if strings.TrimSpace(clause.Attribute) == "" && !clause.Operator.IsSegmentOperator() {
+		return errors.New("empty attribute")
+	}
+	if len(clause.Values) == 0 {
+		return errors.New("empty values")
+	}
+	for _, value := range clause.Values {
+		if err := validateClauseValue(clause.Operator, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateClauseValue(op models.Operator, value any) error {
+	switch op {
+	case models.OperatorIn, models.OperatorNotIn, models.OperatorEquals:
+		switch value.(type) {
+		case string, bool, float64:
+			return nil
+		}
+		return fmt.Errorf("operator {%s} expects string, number or bool, got {%v}", op, value)
+	case models.OperatorStartsWith, models.OperatorEndsWith, models.OperatorContains,
+		models.OperatorInSegment, models.OperatorNotInSegment:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("operator {%s} expects string, got {%v}", op, value)
+		}
+	case models.OperatorRegex:
+		pattern, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("operator {%s} expects string, got {%v}", op, value)
+		}
+		if _, err := compileRegex(pattern); err != nil {
+			return err
+		}
+	case models.OperatorSemverGT, models.OperatorSemverLT:
+		if _, ok := toSemver(value); !ok {
+			return fmt.Errorf("operator {%s} expects semver, got {%v}", op, value)
+		}
+	case models.OperatorNumberGT, models.OperatorNumberLT:
+		if _, ok := toFloat(value); !ok {
+			return fmt.Errorf("operator {%s} expects number, got {%v}", op, value)
+		}
+	case models.OperatorBefore, models.OperatorAfter:
+		if _, ok := toTime(value); !ok {
+			return fmt.Errorf("operator {%s} expects RFC3339 date or unix milliseconds, got {%v}", op, value)
+		}
+	default:
+		return fmt.Errorf("unknown operator {%s}", op)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package httpcache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+type item struct {
+	value    []byte
+	expireAt time.Time
+}
+
+// Storage - in-memory хранилище для fiber cache middleware,
+// в отличие от встроенного умеет удалять ключи по префиксу пути
+type Storage struct {
+	mu    sync.RWMutex
+	items map[string]item
+	done  chan struct{}
+}
+
+// New создает хранилище и запускает очистку просроченных ключей раз в gcInterval
+func New(gcInterval time.Duration) *Storage {
+	s := &Storage{
+		items: make(map[string]item),
+		done:  make(chan struct{}),
+	}
+	if gcInterval > 0 {
+		go s.gc(gcInterval)
+	}
+	return s
+}
+
+func (s *Storage) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for key, it := range s.items {
+				if !it.expireAt.IsZero() && now.After(it.expireAt) {
+					delete(s.items, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Storage) GetWithContext(_ context.Context, key string) ([]byte, error) {
+	return s.Get(key)
+}
+
+func (s *Storage) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	it, ok := s.items[key]
+	s.mu.RUnlock()
+	if !ok || (!it.expireAt.IsZero() && time.Now().After(it.expireAt)) {
+		return nil, nil
+	}
+	return it.value, nil
+}
+
+func (s *Storage) SetWithContext(_ context.Context, key string, val []byte, exp time.Duration) error {
+	return s.Set(key, val, exp)
+}
+
+func (s *Storage) Set(key string, val []byte, exp time.Duration) error {
+	if key == "" || len(val) == 0 {
+		return nil
+	}
+	it := item{value: append([]byte(nil), val...)}
+	if exp > 0 {
+		it.expireAt = time.Now().Add(exp)
+	}
+	s.mu.Lock()
+	s.items[key] = it
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Storage) DeleteWithContext(_ context.Context, key string) error {
+	return s.Delete(key)
+}
+
+func (s *Storage) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.items, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// DeletePrefix удаляет все ключи, начинающиеся с одного из префиксов
+// (ключ fiber cache - путь запроса с суффиксом метода)
+func (s *Storage) DeletePrefix(prefixes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.items {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				delete(s.items, key)
+				break
+			}
+		}
+	}
+}
+
+func (s *Storage) ResetWithContext(_ context.Context) error {
+	return s.Reset()
+}
+
+func (s *Storage) Reset() error {
+	s.mu.Lock()
+	s.items = make(map[string]item)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Storage) Close() error {
+	close(s.done)
+	return nil
+}
@@ -3,16 +3,25 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"feature-flag-2/adapter/humafiberv3"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
 	"feature-flag-2/config"
 	"feature-flag-2/entity"
+	"feature-flag-2/httpcache"
 	_ "feature-flag-2/migrations"
 	"feature-flag-2/models"
+	"feature-flag-2/patch"
+	"feature-flag-2/project"
 	mydb "feature-flag-2/repository/db"
 	"feature-flag-2/service"
+	"feature-flag-2/stream"
+	"feature-flag-2/tenant"
 	"fmt"
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/conditional"
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/cache"
 	"github.com/hashicorp/golang-lru/v2/expirable"
@@ -25,12 +34,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"strings"
 	"syscall"
+	"time"
 )
 
 var (
 	ErrMainFlagNamesNotEqual        = errors.New("flag name from param not equal falg name from body")
+	ErrMainSegmentNamesNotEqual     = errors.New("segment name from param not equal segment name from body")
 	ErrMainInvalidActionOfMigration = errors.New("invalid action of migration")
 )
 
@@ -56,151 +68,1433 @@ func main() {
 		log.Printf("main: doMigrations error - {%v}", err)
 		return
 	}
+	srv, err := newServer(ctx, cfg, db)
+	if err != nil {
+		log.Printf("main: newServer error - {%v}", err)
+		return
+	}
+	defer srv.cacheStorage.Close()
+
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	go srv.serviceFlag.RunLifecycleRecorder(workersCtx, cfg.Worker.LifecycleInterval)
+	go srv.serviceSchedule.RunScheduler(workersCtx, cfg.Worker.ScheduleInterval, cfg.Worker.ScheduleBatchSize)
+	// изменения, сделанные другими репликами, сбрасывают кэши этой реплики
+	go srv.listener.Run(workersCtx, cfg.Worker.ListenMaxBackoff)
+
+	go func() {
+		addr := net.JoinHostPort(cfg.Server.Host, cfg.Server.Port)
+		if err := srv.app.Listen(addr); !errors.Is(err, http.ErrServerClosed) {
+			db.Close()
+			log.Fatalf("failed to listen: %v", err)
+		}
+	}()
+
+	chStop := make(chan os.Signal, 3)
+	signal.Notify(chStop,
+		os.Interrupt,
+		syscall.SIGTERM,
+		syscall.SIGINT,
+	)
+
+	<-chStop
+	stopWorkers()
+	// открытые SSE соединения иначе держат остановку сервера до таймаута
+	srv.streamHub.Close()
+
+	log.Println("Получен сигнал завершения, останавливаем сервер...")
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Server.ShutDown)
+	defer cancel()
+
+	if err := srv.app.ShutdownWithContext(ctx); err != nil {
+		log.Printf("main: app.Shutdown error - {%v}", err)
+		return
+	}
+
+	log.Println("Сервер остановлен корректно.")
+}
+
+// server - API одной реплики и то, что main запускает и останавливает вместе с ним
+type server struct {
+	app             *fiber.App
+	cacheStorage    *httpcache.Storage
+	streamHub       *stream.Hub
+	serviceFlag     *service.ServiceFlag
+	serviceSchedule *service.ServiceSchedule
+	listener        *mydb.Listener
+}
+
+// newServer собирает репозитории, сервисы и маршруты поверх db, миграции уже применены.
+// Воркеры не запускаются, их запускает main
+func newServer(ctx context.Context, cfg *config.Config, db *sql.DB) (*server, error) {
+	var err error
 	lru := expirable.NewLRU[string, models.Flag](cfg.Cache.SizeLRU, nil, cfg.Cache.TTLLRU)
+	lruSegments := expirable.NewLRU[string, models.Segment](cfg.Cache.SizeLRU, nil, cfg.Cache.TTLLRU)
 	reformDB := reform.NewDB(db, postgresql.Dialect, reform.NewPrintfLogger(log.Printf))
-	repoDB := mydb.NewRepoFlagDB(reformDB, lru)
-	serviceFlag := service.NewServiceFlag(repoDB)
+	repoDB := mydb.NewRepoFlagDB(reformDB, lru, cfg.Cache.TTLLRU)
+	repoSegment := mydb.NewRepoSegmentDB(reformDB, lruSegments)
+	lruEnvironments := expirable.NewLRU[string, models.Environment](cfg.Cache.SizeLRU, nil, cfg.Cache.TTLLRU)
+	repoProject := mydb.NewRepoProjectDB(reformDB, lruEnvironments)
+	serviceProject := service.NewServiceProject(repoProject)
+	repoChangeRequest := mydb.NewRepoChangeRequestDB(reformDB, repoDB)
+	serviceFlag := service.NewServiceFlag(repoDB, repoSegment, repoProject, repoChangeRequest)
+	serviceSegment := service.NewServiceSegment(repoSegment, repoDB)
+	repoSchedule := mydb.NewRepoScheduleDB(reformDB, repoDB)
+	serviceSchedule := service.NewServiceSchedule(repoSchedule, serviceFlag)
+	lruAPIKeys := expirable.NewLRU[string, models.APIKey](cfg.Cache.SizeLRU, nil, cfg.Cache.TTLLRU)
+	repoAPIKey := mydb.NewRepoAPIKeyDB(reformDB, lruAPIKeys)
+	serviceAPIKey := service.NewServiceAPIKey(repoAPIKey)
+	if cfg.Auth.BootstrapKey != "" {
+		if err := serviceAPIKey.EnsureBootstrapAPIKey(ctx, cfg.Auth.BootstrapKey); err != nil {
+			return nil, fmt.Errorf("serviceAPIKey.EnsureBootstrapAPIKey error - {%w}", err)
+		}
+	}
+	lruUsers := expirable.NewLRU[string, models.UserAccess](cfg.Cache.SizeLRU, nil, cfg.Cache.TTLLRU)
+	repoUser := mydb.NewRepoUserDB(reformDB, lruUsers)
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.Auth.JWKSPath != "" {
+		jwtVerifier, err = auth.NewJWTVerifier(cfg.Auth.JWKSPath, cfg.Auth.JWTIssuer, cfg.Auth.JWTAudience)
+		if err != nil {
+			return nil, fmt.Errorf("auth.NewJWTVerifier error - {%w}", err)
+		}
+	}
+	serviceUser := service.NewServiceUser(repoUser, jwtVerifier)
+	// API ключи для сервисов, JWT - для людей с ролями
+	var authenticateJWT auth.AuthenticateFunc
+	if jwtVerifier != nil {
+		authenticateJWT = serviceUser.Authenticate
+	}
+	authenticate := auth.ByTokenType(serviceAPIKey.Authenticate, authenticateJWT)
+	serviceTenant := service.NewServiceTenant(mydb.NewRepoTenantDB(reformDB))
+	listener, err := mydb.NewListener(cfg.DB.URL, repoDB, repoSegment, repoAPIKey, repoUser, repoProject)
+	if err != nil {
+		return nil, fmt.Errorf("mydb.NewListener error - {%w}", err)
+	}
 
 	// Create a new Fiber app
 	app := fiber.New()
+	fcacheStorage := httpcache.New(cfg.Cache.TTLMiddlewareFiber)
 	fcache := cache.New(cache.Config{
 		Expiration:   cfg.Cache.TTLMiddlewareFiber,
 		CacheControl: true,
 		Methods:      []string{"GET"},
+		Storage:      fcacheStorage,
+		// фильтры в query, поэтому ключ - путь вместе с query, у каждого арендатора свой ответ.
+		// "#" в RequestURI не встречается, поэтому сброс по префиксу пути сбрасывает всех арендаторов
+		KeyGenerator: func(c fiber.Ctx) string {
+			return string(c.Request().RequestURI()) + "#" + tenant.FromContext(c)
+		},
+	})
+	// fiber cache висит только на группах /flags, сбрасываем все их ключи
+	repoDB.OnEvict(func(flagNames ...string) {
+		fcacheStorage.DeletePrefix("/flags")
+		fcacheStorage.DeletePrefix("/projects/")
 	})
-	app.Group("/flags").Use(fcache)
+	// fiber cache отвечает раньше huma, поэтому права /flags и /stream проверяем на уровне fiber
+	readFlags := func(c fiber.Ctx) error {
+		return c.Next()
+	}
+	if cfg.Auth.Enabled {
+		readFlags = auth.FiberMiddleware(authenticate, auth.ScopeFlagsRead)
+	}
+	// окружение из пути проверяем до fiber cache, несуществующее окружение - 404
+	environmentExists := func(c fiber.Ctx) error {
+		if err := serviceProject.EnvironmentExists(c, fiberEnvironment(c)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("environment {%s} - not found", fiberEnvironment(c)))
+			}
+			return err
+		}
+		return c.Next()
+	}
+	// fiber cache отвечает только на GET, остальные методы групп (POST /flags/promote) проверяет huma
+	readCachedFlags := func(c fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet {
+			return c.Next()
+		}
+		return readFlags(c)
+	}
+	app.Group("/flags").Use(readCachedFlags, fcache)
+	app.Group("/projects/:project/environments/:environment/flags").Use(readCachedFlags, environmentExists, fcache)
+	// SSE: снимок при подключении, затем изменения флагов окружения после commit
+	streamHub := stream.NewHub(cfg.Stream.LogSize)
+	repoDB.OnChange(streamHub.PublishFlag)
+	streamHandler := stream.Handler(streamHub, fiberEnvironment, func(ctx context.Context) ([]byte, error) {
+		flags, err := serviceFlag.RetrieveListOfAllFlags(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(flags.Body)
+	}, cfg.Stream.Heartbeat)
+	app.Get("/stream", readFlags, streamHandler)
+	app.Get("/projects/:project/environments/:environment/stream", readFlags, environmentExists, streamHandler)
 	api := humafiberv3.New(app, huma.DefaultConfig("feature Flags API", "1.0.0"))
+	// автор и комментарий изменения попадают в историю версий флагов
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		next(huma.WithContext(ctx, audit.WithChange(ctx.Context(), audit.Change{
+			Actor:   ctx.Header("X-Actor"),
+			Comment: ctx.Header("X-Change-Comment"),
+		})))
+	})
+	api.OpenAPI().Components.SecuritySchemes = auth.SecuritySchemes()
+	if cfg.Auth.Enabled {
+		// автором изменения становится владелец ключа или пользователь, а не X-Actor
+		api.UseMiddleware(auth.HumaMiddleware(api, authenticate))
+	}
+	// операции по пути окружения работают с флагами этого окружения, старые пути - с окружением default
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		environmentName := ctx.Param("environment")
+		if environmentName == "" {
+			next(ctx)
+			return
+		}
+		environment := project.Environment{Project: ctx.Param("project"), Name: environmentName}
+		if err := serviceProject.EnvironmentExists(ctx.Context(), environment); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				_ = huma.WriteErr(api, ctx, http.StatusNotFound, fmt.Sprintf("environment {%s} - not found", environment), err)
+				return
+			}
+			_ = huma.WriteErr(api, ctx, http.StatusInternalServerError, "environment was not checked", err)
+			return
+		}
+		next(huma.WithContext(ctx, project.WithEnvironment(ctx.Context(), environment)))
+	})
 
-	huma.Register(api, huma.Operation{
+	registerInEnvironment(api, huma.Operation{
 		OperationID: "get-list-of-flags",
 		Method:      "GET",
 		Path:        "/flags",
-		Summary:     "get list of flags and cached",
-	}, func(ctx context.Context, input *struct{}) (*entity.ListOfFlagResponse, error) {
-		flags, err := serviceFlag.RetrieveListOfAllFlags(ctx)
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get page of flags with filters, search and sort, cached",
+	}, func(ctx context.Context, input *entity.FlagListParams) (*entity.ListOfFlagResponse, error) {
+		flags, err := serviceFlag.RetrieveListOfFlags(ctx, *input)
 		if err != nil {
-			return nil, huma.Error404NotFound("empty list of flags", err)
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, service.ErrServiceInvalidCursor) {
+				return nil, huma.Error400BadRequest("invalid cursor", err)
+			}
+			return nil, huma.Error500InternalServerError("flags were not loaded", err)
 		}
 		return flags, nil
 	})
 
-	huma.Register(api, huma.Operation{
+	registerInEnvironment(api, huma.Operation{
 		OperationID: "post-list-of-flags",
 		Method:      "POST",
 		Path:        "/flags",
+		Security:    auth.Require(auth.ScopeFlagsRead),
 		Summary:     "get list of flags by names",
 	}, func(ctx context.Context, input *struct {
-		Body entity.FlagNamesDecode `json:"body"`
+		State string                 `query:"state" enum:"scheduled,active,expired" required:"false"`
+		Body  entity.FlagNamesDecode `json:"body"`
 	}) (*entity.ListOfFlagResponse, error) {
 		flagNames := input.Body
-		flagsByNames, err := serviceFlag.RetrieveListOfFlagsByNames(ctx, flagNames.FlagNames)
+		flagsByNames, err := serviceFlag.RetrieveListOfFlagsByNames(
+			ctx,
+			flagNames.FlagNames,
+			models.FlagState(input.State),
+		)
 		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
 			return nil, huma.Error404NotFound("empty list of flags1", err)
 		}
 		return flagsByNames, nil
 	})
 
-	huma.Register(api, huma.Operation{
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "post-ofrep-evaluate-flag",
+		Method:      "POST",
+		Path:        "/ofrep/v1/evaluate/flags/{key}",
+		Security:    auth.Require(auth.ScopeFlagsEvaluate),
+		Summary:     "evaluate flag by OpenFeature Remote Evaluation Protocol",
+		// тело разбираем сами, чтобы на невалидный JSON ответить PARSE_ERROR в формате OFREP
+		SkipValidateBody: true,
+	}, func(ctx context.Context, input *struct {
+		Key     string `path:"key"`
+		RawBody []byte `contentType:"application/json"`
+	}) (*entity.OFREPEvaluationResponse, error) {
+		request, err := entity.DecodeOFREPEvaluationRequest(input.RawBody)
+		if err != nil {
+			return entity.NewOFREPEvaluationResponse(
+				entity.NewOFREPError(input.Key, entity.OFREPErrorCodeParseError, err.Error()),
+			), nil
+		}
+		respEvaluation, err := serviceFlag.EvaluateFlagOFREP(ctx, input.Key, request)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			return nil, huma.Error500InternalServerError("flag was not evaluated", err)
+		}
+		return respEvaluation, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "post-ofrep-evaluate-flags",
+		Method:      "POST",
+		Path:        "/ofrep/v1/evaluate/flags",
+		Security:    auth.Require(auth.ScopeFlagsEvaluate),
+		Summary:     "evaluate all flags by OpenFeature Remote Evaluation Protocol, 304 if ETag matches",
+		// тело разбираем сами, чтобы на невалидный JSON ответить PARSE_ERROR в формате OFREP
+		SkipValidateBody: true,
+	}, func(ctx context.Context, input *struct {
+		IfNoneMatch string `header:"If-None-Match" required:"false"`
+		RawBody     []byte `contentType:"application/json"`
+	}) (*entity.OFREPBulkEvaluationResponse, error) {
+		request, err := entity.DecodeOFREPEvaluationRequest(input.RawBody)
+		if err != nil {
+			return entity.NewOFREPBulkErrorResponse(entity.OFREPErrorCodeParseError, err.Error()), nil
+		}
+		respEvaluations, etag, err := serviceFlag.EvaluateAllFlagsOFREP(ctx, request, input.IfNoneMatch)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, service.ErrServiceNotModified) {
+				return nil, huma.ErrorWithHeaders(huma.Status304NotModified(), http.Header{"ETag": {etag}})
+			}
+			return nil, huma.Error500InternalServerError("flags were not evaluated", err)
+		}
+		return respEvaluations, nil
+	})
+
+	// для RawBody huma описывает тело как бинарное и обязательное, схему и необязательность указываем сами
+	ofrepRequestSchema := api.OpenAPI().Components.Schemas.Schema(
+		reflect.TypeOf(entity.OFREPEvaluationRequest{}),
+		true,
+		"",
+	)
+	for _, path := range []string{
+		"/ofrep/v1/evaluate/flags/{key}",
+		"/ofrep/v1/evaluate/flags",
+		environmentPathPrefix + "/ofrep/v1/evaluate/flags/{key}",
+		environmentPathPrefix + "/ofrep/v1/evaluate/flags",
+	} {
+		requestBody := api.OpenAPI().Paths[path].Post.RequestBody
+		requestBody.Required = false
+		requestBody.Content["application/json"].Schema = ofrepRequestSchema
+	}
+
+	registerInEnvironment(api, huma.Operation{
 		OperationID:   "post-new-flag",
 		Method:        "POST",
 		DefaultStatus: 201,
 		Path:          "/flag",
+		Security:      auth.Require(auth.ScopeFlagsWrite),
 		Summary:       "create a new flag",
 	}, func(ctx context.Context, input *struct {
-		Body models.Flag `json:"body"`
+		Body entity.FlagDecode `json:"body"`
 	}) (*entity.FlagResponse, error) {
-		newFlag := input.Body
-		respFlag, err := serviceFlag.CreateNewFlag(ctx, newFlag)
+		respFlag, err := serviceFlag.CreateNewFlag(ctx, input.Body)
 		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, service.ErrServiceInvalidFlag) {
+				return nil, huma.Error422UnprocessableEntity("flag is invalid", err)
+			}
 			return nil, huma.NewError(http.StatusConflict, "flag was not created", err)
 		}
 		return respFlag, nil
 	})
 
-	huma.Register(api, huma.Operation{
+	registerInEnvironment(api, huma.Operation{
 		OperationID: "get-flag-by-name",
 		Method:      "GET",
 		Path:        "/flag/{name}",
+		Security:    auth.Require(auth.ScopeFlagsRead),
 		Summary:     "get flag name from param and return flag",
 	}, func(ctx context.Context, input *struct {
 		Name string `path:"name" maxLength:"30" example:"world"`
+		conditional.Params
 	}) (*entity.FlagResponse, error) {
 		flagName := input.Name
 		respFlag, err := serviceFlag.GetFlagByName(ctx, flagName)
 		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
 			return nil, huma.Error404NotFound(
 				fmt.Sprintf("flag by name {%s} - not found", flagName),
 				err,
 			)
 		}
+		// клиент с актуальной версией получает 304 без тела
+		if err := flagPrecondition(&input.Params)(respFlag.Body.Flag); err != nil {
+			return nil, huma.ErrorWithHeaders(err, http.Header{"ETag": {respFlag.ETag}})
+		}
 		return respFlag, nil
 	})
 
-	huma.Register(api, huma.Operation{
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "post-evaluate-all-flags",
+		Method:      "POST",
+		Path:        "/evaluate",
+		Security:    auth.Require(auth.ScopeFlagsEvaluate),
+		Summary:     "evaluate all flags (or filtered by names and tags) for context",
+	}, func(ctx context.Context, input *struct {
+		Body entity.BulkEvaluationDecode `json:"body"`
+	}) (*entity.BulkEvaluationResponse, error) {
+		respEvaluations, err := serviceFlag.EvaluateAllFlags(ctx, input.Body)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			return nil, huma.Error500InternalServerError("flags were not evaluated", err)
+		}
+		return respEvaluations, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "post-evaluate-flag-by-name",
+		Method:      "POST",
+		Path:        "/evaluate/{name}",
+		Security:    auth.Require(auth.ScopeFlagsEvaluate),
+		Summary:     "evaluate flag by name for context and return value with reason",
+	}, func(ctx context.Context, input *struct {
+		Name string                         `path:"name" maxLength:"30" example:"world"`
+		Body entity.EvaluationContextDecode `json:"body"`
+	}) (*entity.EvaluationResponse, error) {
+		flagName := input.Name
+		respEvaluation, err := serviceFlag.EvaluateFlag(ctx, flagName, input.Body)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			return nil, huma.Error404NotFound(
+				fmt.Sprintf("flag by name {%s} - not found", flagName),
+				err,
+			)
+		}
+		return respEvaluation, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "get-lifecycle-of-flag",
+		Method:      "GET",
+		Path:        "/flag/{name}/lifecycle",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get history of scheduled transitions of flag (active/expired)",
+	}, func(ctx context.Context, input *struct {
+		Name string `path:"name" maxLength:"30" example:"world"`
+	}) (*entity.ListOfLifecycleEventResponse, error) {
+		flagName := input.Name
+		events, err := serviceFlag.RetrieveLifecycleOfFlag(ctx, flagName)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			return nil, huma.Error404NotFound(fmt.Sprintf("flag by name {%s} - not found", flagName), err)
+		}
+		return events, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "get-history-of-flag",
+		Method:      "GET",
+		Path:        "/flag/{name}/history",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get versions of flag with author and comment, newest first",
+	}, func(ctx context.Context, input *struct {
+		Name   string `path:"name" maxLength:"30" example:"world"`
+		Limit  int    `query:"limit" minimum:"1" maximum:"100" default:"20"`
+		Offset int    `query:"offset" minimum:"0" default:"0"`
+	}) (*entity.ListOfFlagVersionResponse, error) {
+		flagName := input.Name
+		versions, err := serviceFlag.RetrieveHistoryOfFlag(ctx, flagName, input.Limit, input.Offset)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			return nil, huma.Error404NotFound(fmt.Sprintf("flag by name {%s} - not found", flagName), err)
+		}
+		return versions, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "get-version-of-flag",
+		Method:      "GET",
+		Path:        "/flag/{name}/history/{version}",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get flag as it was in version",
+	}, func(ctx context.Context, input *struct {
+		Name    string `path:"name" maxLength:"30" example:"world"`
+		Version int64  `path:"version" minimum:"1"`
+	}) (*entity.FlagVersionResponse, error) {
+		flagName := input.Name
+		flagVersion, err := serviceFlag.RetrieveVersionOfFlag(ctx, flagName, input.Version)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			return nil, huma.Error404NotFound(
+				fmt.Sprintf("version {%d} of flag {%s} - not found", input.Version, flagName),
+				err,
+			)
+		}
+		return flagVersion, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "post-rollback-flag",
+		Method:      "POST",
+		Path:        "/flag/{name}/rollback",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Summary:     "restore flag from version of history as a new version",
+	}, func(ctx context.Context, input *struct {
+		Name string                `path:"name" maxLength:"30" example:"world"`
+		Body entity.RollbackDecode `json:"body"`
+	}) (*entity.FlagResponse, error) {
+		flagName := input.Name
+		respFlag, err := serviceFlag.RollbackFlag(ctx, flagName, input.Body)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, service.ErrServiceInvalidFlag) {
+				return nil, huma.Error422UnprocessableEntity("restored flag is invalid", err)
+			}
+			if errors.Is(err, service.ErrServiceApprovalRequired) {
+				return nil, huma.Error409Conflict("rollback requires approval", err)
+			}
+			if errors.Is(err, mydb.ErrDBOutdated) {
+				return nil, huma.Error409Conflict(fmt.Sprintf("flag {%s} was changed during rollback", flagName), err)
+			}
+			if errors.Is(err, mydb.ErrDBIsDeleted) {
+				return nil, huma.Error409Conflict(
+					fmt.Sprintf("version {%d} of flag {%s} is deleted", input.Body.Version, flagName),
+					err,
+				)
+			}
+			return nil, huma.Error404NotFound(
+				fmt.Sprintf("version {%d} of flag {%s} - not found", input.Body.Version, flagName),
+				err,
+			)
+		}
+		return respFlag, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
 		OperationID: "put-flag-by-name",
 		Method:      "PUT",
 		Path:        "/flag/{name}",
-		Summary:     "get flag name from param and return flag after update",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Summary:     "update flag and return it, 202 and change request if environment requires approval",
 	}, func(ctx context.Context, input *struct {
-		Name string      `path:"name"`
-		Body models.Flag `json:"body"`
-	}) (*entity.FlagResponse, error) {
+		Name string            `path:"name"`
+		Body entity.FlagDecode `json:"body"`
+		conditional.Params
+	}) (*entity.FlagChangeResponse, error) {
 		flagName := input.Name
 		flagDecode := input.Body
 		if strings.TrimSpace(flagName) != strings.TrimSpace(flagDecode.FlagName) {
 			return nil, huma.Error400BadRequest("flag name is invalid", ErrMainFlagNamesNotEqual)
 		}
 		flagName = flagDecode.FlagName
-		respFlag, err := serviceFlag.UpdateFlag(ctx, flagDecode)
+		respFlag, err := serviceFlag.UpdateFlag(ctx, flagDecode, flagPrecondition(&input.Params))
 		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, service.ErrServiceInvalidFlag) {
+				return nil, huma.Error422UnprocessableEntity("flag is invalid", err)
+			}
 			return nil, huma.Error404NotFound(fmt.Sprintf("flag by name {%s} - not found", flagName), err)
 		}
 		return respFlag, nil
 	})
 
-	huma.Register(api, huma.Operation{
-		OperationID: "delete-flag-by-name",
-		Method:      "DELETE",
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "patch-flag-by-name",
+		Method:      "PATCH",
 		Path:        "/flag/{name}",
-		Summary:     "get flag name from param and delete",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Summary:     "partial update of flag by json merge patch or json patch, 202 if environment requires approval",
+		// тело - патч, а не флаг, его разбирает и проверяет сервис
+		SkipValidateBody: true,
+	}, func(ctx context.Context, input *struct {
+		Name        string `path:"name"`
+		ContentType string `header:"Content-Type"`
+		RawBody     []byte `contentType:"application/merge-patch+json"`
+		conditional.Params
+	}) (*entity.FlagChangeResponse, error) {
+		flagName := input.Name
+		respFlag, err := serviceFlag.PatchFlag(
+			ctx,
+			flagName,
+			input.ContentType,
+			input.RawBody,
+			flagPrecondition(&input.Params),
+		)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, service.ErrServiceUnsupportedPatch) {
+				return nil, huma.Error415UnsupportedMediaType("patch content type is not supported", err)
+			}
+			if errors.Is(err, service.ErrServiceInvalidPatch) || errors.Is(err, service.ErrServiceInvalidFlag) {
+				return nil, huma.Error422UnprocessableEntity("flag is invalid after patch", err)
+			}
+			if errors.Is(err, service.ErrServiceConflict) {
+				return nil, huma.Error409Conflict(fmt.Sprintf("flag by name {%s} is changed concurrently", flagName), err)
+			}
+			return nil, huma.Error404NotFound(fmt.Sprintf("flag by name {%s} - not found", flagName), err)
+		}
+		return respFlag, nil
+	})
+
+	// для RawBody huma описывает одно бинарное тело, PATCH принимает оба формата патча
+	for _, path := range []string{"/flag/{name}", environmentPathPrefix + "/flag/{name}"} {
+		patchRequestBody := api.OpenAPI().Paths[path].Patch.RequestBody
+		patchRequestBody.Content = map[string]*huma.MediaType{
+			service.ContentTypeMergePatch: {Schema: &huma.Schema{
+				Type:        huma.TypeObject,
+				Description: "JSON Merge Patch (RFC 7386) over flag",
+			}},
+			service.ContentTypeJSONPatch: {Schema: api.OpenAPI().Components.Schemas.Schema(
+				reflect.TypeOf([]patch.Operation{}),
+				true,
+				"",
+			)},
+		}
+	}
+
+	// окружения переноса приходят в теле, права на них проверяет сервис
+	promoteFlags := func(
+		ctx context.Context,
+		projectName string,
+		promoteDecode entity.PromoteDecode,
+		params *conditional.Params,
+	) (*entity.PromoteResponse, error) {
+		if promoteDecode.Confirm && len(params.IfMatch) == 0 {
+			return nil, huma.NewError(
+				http.StatusPreconditionRequired,
+				"If-Match with ETag of diff is required to apply promotion",
+			)
+		}
+		respPromote, err := serviceFlag.PromoteFlags(ctx, projectName, promoteDecode, func(etag string) error {
+			if err := params.PreconditionFailed(etag, time.Time{}); err != nil {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, service.ErrServiceInvalidPromotion) || errors.Is(err, service.ErrServiceInvalidFlag) {
+				return nil, huma.Error422UnprocessableEntity("promotion is invalid", err)
+			}
+			if errors.Is(err, service.ErrServiceApprovalRequired) {
+				return nil, huma.Error409Conflict("promotion to target environment requires approval", err)
+			}
+			if errors.Is(err, mydb.ErrDBNotFound) ||
+				errors.Is(err, mydb.ErrDBIsDeleted) ||
+				errors.Is(err, models.ErrModelsUnknownModel) {
+				return nil, huma.Error404NotFound("flags for promotion - not found", err)
+			}
+			return nil, huma.Error500InternalServerError("flags were not promoted", err)
+		}
+		return respPromote, nil
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-promote-flags",
+		Method:      "POST",
+		Path:        "/flags/promote",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Metadata:    map[string]any{auth.MetadataAuthorizedByService: true},
+		Summary:     "diff of flags between environments of project default, with confirm and If-Match - promote",
+	}, func(ctx context.Context, input *struct {
+		Body entity.PromoteDecode `json:"body"`
+		conditional.Params
+	}) (*entity.PromoteResponse, error) {
+		return promoteFlags(ctx, project.DefaultProject, input.Body, &input.Params)
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-promote-flags-in-project",
+		Method:      "POST",
+		Path:        "/projects/{project}/flags/promote",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Metadata:    map[string]any{auth.MetadataAuthorizedByService: true},
+		Summary:     "diff of flags between environments of project, with confirm and If-Match - promote",
+	}, func(ctx context.Context, input *struct {
+		Project string               `path:"project" maxLength:"100"`
+		Body    entity.PromoteDecode `json:"body"`
+		conditional.Params
+	}) (*entity.PromoteResponse, error) {
+		return promoteFlags(ctx, input.Project, input.Body, &input.Params)
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID:   "delete-flag-by-name",
+		Method:        "DELETE",
+		DefaultStatus: http.StatusNoContent,
+		Path:          "/flag/{name}",
+		Security:      auth.Require(auth.ScopeFlagsWrite),
+		Summary:       "delete flag, 202 and change request if environment of project requires approval",
 	}, func(ctx context.Context, input *struct {
 		Name string `path:"name"`
-	}) (*struct{}, error) {
+		conditional.Params
+	}) (*entity.ChangeRequestResponse, error) {
 		flagName := input.Name
-		if err := serviceFlag.DeleteFlag(ctx, flagName); err != nil {
+		respChangeRequest, err := serviceFlag.DeleteFlag(ctx, flagName, flagPrecondition(&input.Params))
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, mydb.ErrDBHasDependents) {
+				return nil, huma.Error409Conflict(fmt.Sprintf("flag by name {%s} is a prerequisite of other flags", flagName), err)
+			}
 			return nil, huma.Error404NotFound(fmt.Sprintf("flag by name {%s} - not found", flagName), err)
 		}
+		return respChangeRequest, nil
+	})
+
+	// ошибки отложенных изменений одинаковы для всех операций над очередью флага,
+	// notFound - что не найдено: флаг или изменение
+	scheduleError := func(notFound string, err error) error {
+		var statusErr huma.StatusError
+		if errors.As(err, &statusErr) {
+			return statusErr
+		}
+		if errors.Is(err, service.ErrServiceInvalidScheduledChange) {
+			return huma.Error422UnprocessableEntity("scheduled change is invalid", err)
+		}
+		if errors.Is(err, service.ErrServiceApprovalRequired) {
+			return huma.Error409Conflict("scheduled change requires approval", err)
+		}
+		if errors.Is(err, mydb.ErrDBNotPending) {
+			return huma.Error409Conflict("scheduled change is not pending", err)
+		}
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, mydb.ErrDBNotFound) || errors.Is(err, mydb.ErrDBIsDeleted) {
+			return huma.Error404NotFound(notFound, err)
+		}
+		return huma.Error500InternalServerError("scheduled change was not processed", err)
+	}
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID:   "post-scheduled-change-of-flag",
+		Method:        "POST",
+		DefaultStatus: 201,
+		Path:          "/flag/{name}/schedule",
+		Security:      auth.Require(auth.ScopeFlagsWrite),
+		Summary:       "schedule a change of flag (json merge patch) at apply_at",
+	}, func(ctx context.Context, input *struct {
+		Name string                       `path:"name" maxLength:"30" example:"world"`
+		Body entity.ScheduledChangeDecode `json:"body"`
+	}) (*entity.ScheduledChangeResponse, error) {
+		flagName := input.Name
+		respChange, err := serviceSchedule.CreateScheduledChange(ctx, flagName, input.Body)
+		if err != nil {
+			return nil, scheduleError(fmt.Sprintf("flag by name {%s} - not found", flagName), err)
+		}
+		return respChange, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "get-scheduled-changes-of-flag",
+		Method:      "GET",
+		Path:        "/flag/{name}/schedule",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get scheduled changes of flag ordered by apply_at",
+	}, func(ctx context.Context, input *struct {
+		Name   string `path:"name" maxLength:"30" example:"world"`
+		Status string `query:"status" enum:"pending,applied,failed,cancelled" required:"false"`
+	}) (*entity.ListOfScheduledChangeResponse, error) {
+		flagName := input.Name
+		respChanges, err := serviceSchedule.RetrieveListOfScheduledChanges(
+			ctx,
+			flagName,
+			models.ScheduleStatus(input.Status),
+		)
+		if err != nil {
+			return nil, scheduleError(fmt.Sprintf("flag by name {%s} - not found", flagName), err)
+		}
+		return respChanges, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "get-scheduled-change-of-flag",
+		Method:      "GET",
+		Path:        "/flag/{name}/schedule/{id}",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get scheduled change of flag by id",
+	}, func(ctx context.Context, input *struct {
+		Name string `path:"name" maxLength:"30" example:"world"`
+		ID   string `path:"id" format:"uuid"`
+	}) (*entity.ScheduledChangeResponse, error) {
+		respChange, err := serviceSchedule.GetScheduledChange(ctx, input.Name, input.ID)
+		if err != nil {
+			return nil, scheduleError(fmt.Sprintf("scheduled change by id {%s} - not found", input.ID), err)
+		}
+		return respChange, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "put-scheduled-change-of-flag",
+		Method:      "PUT",
+		Path:        "/flag/{name}/schedule/{id}",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Summary:     "update pending scheduled change of flag",
+	}, func(ctx context.Context, input *struct {
+		Name string                       `path:"name" maxLength:"30" example:"world"`
+		ID   string                       `path:"id" format:"uuid"`
+		Body entity.ScheduledChangeDecode `json:"body"`
+	}) (*entity.ScheduledChangeResponse, error) {
+		respChange, err := serviceSchedule.UpdateScheduledChange(ctx, input.Name, input.ID, input.Body)
+		if err != nil {
+			return nil, scheduleError(fmt.Sprintf("scheduled change by id {%s} - not found", input.ID), err)
+		}
+		return respChange, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "delete-scheduled-change-of-flag",
+		Method:      "DELETE",
+		Path:        "/flag/{name}/schedule/{id}",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Summary:     "cancel pending scheduled change of flag",
+	}, func(ctx context.Context, input *struct {
+		Name string `path:"name" maxLength:"30" example:"world"`
+		ID   string `path:"id" format:"uuid"`
+	}) (*entity.ScheduledChangeResponse, error) {
+		respChange, err := serviceSchedule.CancelScheduledChange(ctx, input.Name, input.ID)
+		if err != nil {
+			return nil, scheduleError(fmt.Sprintf("scheduled change by id {%s} - not found", input.ID), err)
+		}
+		return respChange, nil
+	})
+
+	// ошибки запросов на изменение одинаковы для всех операций над запросом
+	changeRequestError := func(id string, err error) error {
+		var statusErr huma.StatusError
+		if errors.As(err, &statusErr) {
+			return statusErr
+		}
+		if errors.Is(err, service.ErrServiceSelfApproval) {
+			return huma.Error403Forbidden("author of change request can not approve it", err)
+		}
+		if errors.Is(err, service.ErrServiceUnauthenticatedApprover) {
+			return huma.Error403Forbidden("change request is approved only with an API key or JWT", err)
+		}
+		if errors.Is(err, service.ErrServiceInvalidDecision) || errors.Is(err, mydb.ErrDBOutdated) {
+			return huma.Error409Conflict(fmt.Sprintf("change request by id {%s} can not be resolved", id), err)
+		}
+		if errors.Is(err, mydb.ErrDBNotPending) {
+			return huma.Error409Conflict(fmt.Sprintf("change request by id {%s} is closed", id), err)
+		}
+		if errors.Is(err, mydb.ErrDBHasDependents) {
+			return huma.Error409Conflict("flag is a prerequisite of other flags", err)
+		}
+		if errors.Is(err, service.ErrServiceInvalidFlag) {
+			return huma.Error422UnprocessableEntity("proposed flag is invalid", err)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return huma.Error404NotFound(fmt.Sprintf("change request by id {%s} - not found", id), err)
+		}
+		return huma.Error500InternalServerError("change request was not processed", err)
+	}
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "get-list-of-change-requests",
+		Method:      "GET",
+		Path:        "/change-requests",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get list of change requests of flags",
+	}, func(ctx context.Context, input *struct {
+		Status string `query:"status" enum:"pending,approved,rejected,applied,invalidated" required:"false"`
+	}) (*entity.ListOfChangeRequestResponse, error) {
+		changeRequests, err := serviceFlag.RetrieveListOfChangeRequests(ctx, models.ChangeRequestStatus(input.Status))
+		if err != nil {
+			return nil, changeRequestError("", err)
+		}
+		return changeRequests, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "get-change-request",
+		Method:      "GET",
+		Path:        "/change-requests/{id}",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get change request and diff with version of flag it is based on",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id" format:"uuid"`
+	}) (*entity.ChangeRequestResponse, error) {
+		respChangeRequest, err := serviceFlag.RetrieveChangeRequest(ctx, input.ID)
+		if err != nil {
+			return nil, changeRequestError(input.ID, err)
+		}
+		return respChangeRequest, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "post-approve-change-request",
+		Method:      "POST",
+		Path:        "/change-requests/{id}/approve",
+		Security:    auth.Require(auth.ScopeFlagsApprove),
+		Summary:     "approve change request, author can not approve own request",
+	}, func(ctx context.Context, input *struct {
+		ID   string                              `path:"id" format:"uuid"`
+		Body *entity.ChangeRequestDecisionDecode `json:"body" required:"false"`
+	}) (*entity.ChangeRequestResponse, error) {
+		decision := entity.ChangeRequestDecisionDecode{}
+		if input.Body != nil {
+			decision = *input.Body
+		}
+		respChangeRequest, err := serviceFlag.ApproveChangeRequest(ctx, input.ID, decision)
+		if err != nil {
+			return nil, changeRequestError(input.ID, err)
+		}
+		return respChangeRequest, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "post-reject-change-request",
+		Method:      "POST",
+		Path:        "/change-requests/{id}/reject",
+		Security:    auth.Require(auth.ScopeFlagsApprove),
+		Summary:     "reject change request",
+	}, func(ctx context.Context, input *struct {
+		ID   string                              `path:"id" format:"uuid"`
+		Body *entity.ChangeRequestDecisionDecode `json:"body" required:"false"`
+	}) (*entity.ChangeRequestResponse, error) {
+		decision := entity.ChangeRequestDecisionDecode{}
+		if input.Body != nil {
+			decision = *input.Body
+		}
+		respChangeRequest, err := serviceFlag.RejectChangeRequest(ctx, input.ID, decision)
+		if err != nil {
+			return nil, changeRequestError(input.ID, err)
+		}
+		return respChangeRequest, nil
+	})
+
+	registerInEnvironment(api, huma.Operation{
+		OperationID: "post-apply-change-request",
+		Method:      "POST",
+		Path:        "/change-requests/{id}/apply",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Summary:     "apply approved change request if flag has not changed since it was opened",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id" format:"uuid"`
+	}) (*entity.ChangeRequestResponse, error) {
+		respChangeRequest, err := serviceFlag.ApplyChangeRequest(ctx, input.ID)
+		if err != nil {
+			return nil, changeRequestError(input.ID, err)
+		}
+		return respChangeRequest, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-list-of-segments",
+		Method:      "GET",
+		Path:        "/segments",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get list of segments",
+	}, func(ctx context.Context, input *struct{}) (*entity.ListOfSegmentResponse, error) {
+		segments, err := serviceSegment.RetrieveListOfAllSegments(ctx)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			return nil, huma.Error500InternalServerError("segments were not loaded", err)
+		}
+		return segments, nil
+	})
+
+	// ошибки операций над сегментом: 404 только для отсутствующего сегмента, сбой БД - 500
+	segmentError := func(segmentName string, err error) error {
+		var statusErr huma.StatusError
+		if errors.As(err, &statusErr) {
+			return statusErr
+		}
+		if errors.Is(err, service.ErrServiceInvalidSegment) {
+			return huma.Error422UnprocessableEntity("segment is invalid", err)
+		}
+		if errors.Is(err, mydb.ErrDBAlreadyExists) {
+			return huma.Error409Conflict(fmt.Sprintf("segment by name {%s} already exists", segmentName), err)
+		}
+		if errors.Is(err, mydb.ErrDBHasDependents) {
+			return huma.Error409Conflict(fmt.Sprintf("segment by name {%s} is used by flags", segmentName), err)
+		}
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, mydb.ErrDBNotFound) || errors.Is(err, mydb.ErrDBIsDeleted) {
+			return huma.Error404NotFound(fmt.Sprintf("segment by name {%s} - not found", segmentName), err)
+		}
+		return huma.Error500InternalServerError("segment was not processed", err)
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "post-new-segment",
+		Method:        "POST",
+		DefaultStatus: 201,
+		Path:          "/segment",
+		Security:      auth.Require(auth.ScopeFlagsWrite),
+		Summary:       "create a new segment",
+	}, func(ctx context.Context, input *struct {
+		Body entity.SegmentDecode `json:"body"`
+	}) (*entity.SegmentResponse, error) {
+		respSegment, err := serviceSegment.CreateNewSegment(ctx, input.Body)
+		if err != nil {
+			return nil, segmentError(input.Body.SegmentName, err)
+		}
+		return respSegment, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-segment-by-name",
+		Method:      "GET",
+		Path:        "/segment/{name}",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get segment name from param and return segment",
+	}, func(ctx context.Context, input *struct {
+		Name string `path:"name" maxLength:"30" example:"beta_testers"`
+	}) (*entity.SegmentResponse, error) {
+		segmentName := input.Name
+		respSegment, err := serviceSegment.GetSegmentByName(ctx, segmentName)
+		if err != nil {
+			return nil, segmentError(segmentName, err)
+		}
+		return respSegment, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-segment-by-name",
+		Method:      "PUT",
+		Path:        "/segment/{name}",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Summary:     "get segment name from param and return segment after update",
+	}, func(ctx context.Context, input *struct {
+		Name string               `path:"name"`
+		Body entity.SegmentDecode `json:"body"`
+	}) (*entity.SegmentResponse, error) {
+		segmentName := input.Name
+		segmentDecode := input.Body
+		if strings.TrimSpace(segmentName) != strings.TrimSpace(segmentDecode.SegmentName) {
+			return nil, huma.Error400BadRequest("segment name is invalid", ErrMainSegmentNamesNotEqual)
+		}
+		respSegment, err := serviceSegment.UpdateSegment(ctx, segmentDecode)
+		if err != nil {
+			return nil, segmentError(segmentName, err)
+		}
+		return respSegment, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-segment-by-name",
+		Method:      "DELETE",
+		Path:        "/segment/{name}",
+		Security:    auth.Require(auth.ScopeFlagsWrite),
+		Summary:     "get segment name from param and delete",
+	}, func(ctx context.Context, input *struct {
+		Name string `path:"name"`
+	}) (*struct{}, error) {
+		segmentName := input.Name
+		if err := serviceSegment.DeleteSegment(ctx, segmentName); err != nil {
+			return nil, segmentError(segmentName, err)
+		}
 		return nil, nil
 	})
 
-	go func() {
-		addr := net.JoinHostPort(cfg.Server.Host, cfg.Server.Port)
-		if err := app.Listen(addr); !errors.Is(err, http.ErrServerClosed) {
-			db.Close()
-			log.Fatalf("failed to listen: %v", err)
+	huma.Register(api, huma.Operation{
+		OperationID:   "post-new-api-key",
+		Method:        "POST",
+		DefaultStatus: 201,
+		Path:          "/api-keys",
+		Security:      auth.Require(auth.ScopeAdmin),
+		Summary:       "create api key with scopes, the key is returned only once",
+	}, func(ctx context.Context, input *struct {
+		Body entity.APIKeyDecode `json:"body"`
+	}) (*entity.APIKeyResponse, error) {
+		respAPIKey, err := serviceAPIKey.CreateAPIKey(ctx, input.Body)
+		if err != nil {
+			if errors.Is(err, service.ErrServiceInvalidAPIKey) {
+				return nil, huma.Error422UnprocessableEntity("api key is invalid", err)
+			}
+			return nil, huma.Error500InternalServerError("api key was not created", err)
 		}
-	}()
+		return respAPIKey, nil
+	})
 
-	chStop := make(chan os.Signal, 3)
-	signal.Notify(chStop,
-		os.Interrupt,
-		syscall.SIGTERM,
-		syscall.SIGINT,
-	)
+	huma.Register(api, huma.Operation{
+		OperationID: "get-list-of-api-keys",
+		Method:      "GET",
+		Path:        "/api-keys",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "get list of api keys without secrets",
+	}, func(ctx context.Context, input *struct{}) (*entity.ListOfAPIKeyResponse, error) {
+		apiKeys, err := serviceAPIKey.RetrieveListOfAPIKeys(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("api keys were not loaded", err)
+		}
+		return apiKeys, nil
+	})
 
-	<-chStop
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-api-key",
+		Method:      "DELETE",
+		Path:        "/api-keys/{id}",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "revoke api key",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id" format:"uuid"`
+	}) (*entity.APIKeyResponse, error) {
+		respAPIKey, err := serviceAPIKey.RevokeAPIKey(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound(fmt.Sprintf("active api key {%s} - not found", input.ID), err)
+		}
+		return respAPIKey, nil
+	})
 
-	log.Println("Получен сигнал завершения, останавливаем сервер...")
+	huma.Register(api, huma.Operation{
+		OperationID:   "post-new-tenant",
+		Method:        "POST",
+		DefaultStatus: 201,
+		Path:          "/tenants",
+		Security:      auth.Require(auth.ScopeAdmin),
+		Summary:       "create tenant with environment default and admin api key, the key is returned only once",
+	}, func(ctx context.Context, input *struct {
+		Body entity.TenantDecode `json:"body"`
+	}) (*entity.TenantResponse, error) {
+		respTenant, err := serviceTenant.CreateTenant(ctx, input.Body)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			if errors.Is(err, mydb.ErrDBAlreadyExists) {
+				return nil, huma.Error409Conflict(fmt.Sprintf("tenant {%s} already exists", input.Body.ID), err)
+			}
+			return nil, huma.Error500InternalServerError("tenant was not created", err)
+		}
+		return respTenant, nil
+	})
 
-	ctx, cancel := context.WithTimeout(ctx, cfg.Server.ShutDown)
-	defer cancel()
+	huma.Register(api, huma.Operation{
+		OperationID: "get-list-of-tenants",
+		Method:      "GET",
+		Path:        "/tenants",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "get list of tenants",
+	}, func(ctx context.Context, input *struct{}) (*entity.ListOfTenantResponse, error) {
+		tenants, err := serviceTenant.RetrieveListOfTenants(ctx)
+		if err != nil {
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				return nil, statusErr
+			}
+			return nil, huma.Error500InternalServerError("tenants were not loaded", err)
+		}
+		return tenants, nil
+	})
 
-	if err := app.ShutdownWithContext(ctx); err != nil {
-		log.Printf("main: app.Shutdown error - {%v}", err)
-		return
+	huma.Register(api, huma.Operation{
+		OperationID:   "post-new-user",
+		Method:        "POST",
+		DefaultStatus: 201,
+		Path:          "/users",
+		Security:      auth.Require(auth.ScopeAdmin),
+		Summary:       "create user by subject (sub claim of jwt) without roles",
+	}, func(ctx context.Context, input *struct {
+		Body entity.UserDecode `json:"body"`
+	}) (*entity.UserResponse, error) {
+		respUser, err := serviceUser.CreateUser(ctx, input.Body)
+		if err != nil {
+			if errors.Is(err, mydb.ErrDBAlreadyExists) {
+				return nil, huma.Error409Conflict(fmt.Sprintf("user by subject {%s} already exists", input.Body.Subject), err)
+			}
+			return nil, huma.Error500InternalServerError("user was not created", err)
+		}
+		return respUser, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-list-of-users",
+		Method:      "GET",
+		Path:        "/users",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "get list of users without roles",
+	}, func(ctx context.Context, input *struct{}) (*entity.ListOfUserResponse, error) {
+		users, err := serviceUser.RetrieveListOfUsers(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("users were not loaded", err)
+		}
+		return users, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-user",
+		Method:      "GET",
+		Path:        "/users/{id}",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "get user with roles",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id" format:"uuid"`
+	}) (*entity.UserResponse, error) {
+		respUser, err := serviceUser.GetUser(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound(fmt.Sprintf("user {%s} - not found", input.ID), err)
+		}
+		return respUser, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-user",
+		Method:      "PUT",
+		Path:        "/users/{id}",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "update email, name and disable user",
+	}, func(ctx context.Context, input *struct {
+		ID   string                  `path:"id" format:"uuid"`
+		Body entity.UserUpdateDecode `json:"body"`
+	}) (*entity.UserResponse, error) {
+		respUser, err := serviceUser.UpdateUser(ctx, input.ID, input.Body)
+		if err != nil {
+			return nil, huma.Error404NotFound(fmt.Sprintf("user {%s} - not found", input.ID), err)
+		}
+		return respUser, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-user",
+		Method:      "DELETE",
+		Path:        "/users/{id}",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "delete user with roles",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id" format:"uuid"`
+	}) (*struct{}, error) {
+		if err := serviceUser.DeleteUser(ctx, input.ID); err != nil {
+			return nil, huma.Error404NotFound(fmt.Sprintf("user {%s} - not found", input.ID), err)
+		}
+		return nil, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "post-role-binding-of-user",
+		Method:        "POST",
+		DefaultStatus: 201,
+		Path:          "/users/{id}/roles",
+		Security:      auth.Require(auth.ScopeAdmin),
+		Summary:       "grant role to user globally, in project or in environment",
+	}, func(ctx context.Context, input *struct {
+		ID   string                   `path:"id" format:"uuid"`
+		Body entity.RoleBindingDecode `json:"body"`
+	}) (*entity.UserResponse, error) {
+		respUser, err := serviceUser.CreateRoleBinding(ctx, input.ID, input.Body)
+		if err != nil {
+			if errors.Is(err, service.ErrServiceInvalidRoleBinding) {
+				return nil, huma.Error422UnprocessableEntity("role binding is invalid", err)
+			}
+			if errors.Is(err, mydb.ErrDBAlreadyExists) {
+				return nil, huma.Error409Conflict(fmt.Sprintf("user {%s} already has this role", input.ID), err)
+			}
+			return nil, huma.Error404NotFound(fmt.Sprintf("user {%s} - not found", input.ID), err)
+		}
+		return respUser, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-role-binding-of-user",
+		Method:      "DELETE",
+		Path:        "/users/{id}/roles/{binding_id}",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "revoke role of user",
+	}, func(ctx context.Context, input *struct {
+		ID        string `path:"id" format:"uuid"`
+		BindingID string `path:"binding_id" format:"uuid"`
+	}) (*entity.UserResponse, error) {
+		respUser, err := serviceUser.DeleteRoleBinding(ctx, input.ID, input.BindingID)
+		if err != nil {
+			return nil, huma.Error404NotFound(
+				fmt.Sprintf("role binding {%s} of user {%s} - not found", input.BindingID, input.ID),
+				err,
+			)
+		}
+		return respUser, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "post-new-project",
+		Method:        "POST",
+		DefaultStatus: 201,
+		Path:          "/projects",
+		Security:      auth.Require(auth.ScopeAdmin),
+		Summary:       "create project with environments, without environments - with environment default",
+	}, func(ctx context.Context, input *struct {
+		Body entity.ProjectDecode `json:"body"`
+	}) (*entity.ProjectResponse, error) {
+		respProject, err := serviceProject.CreateProject(ctx, input.Body)
+		if err != nil {
+			if errors.Is(err, service.ErrServiceInvalidEnvironment) {
+				return nil, huma.Error422UnprocessableEntity("environment is invalid", err)
+			}
+			if errors.Is(err, mydb.ErrDBAlreadyExists) {
+				return nil, huma.Error409Conflict(fmt.Sprintf("project {%s} already exists", input.Body.Name), err)
+			}
+			return nil, huma.Error500InternalServerError("project was not created", err)
+		}
+		return respProject, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-list-of-projects",
+		Method:      "GET",
+		Path:        "/projects",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get list of projects",
+	}, func(ctx context.Context, input *struct{}) (*entity.ListOfProjectResponse, error) {
+		projects, err := serviceProject.RetrieveListOfProjects(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("projects were not loaded", err)
+		}
+		return projects, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "post-new-environment",
+		Method:        "POST",
+		DefaultStatus: 201,
+		Path:          "/projects/{project}/environments",
+		Security:      auth.Require(auth.ScopeAdmin),
+		Summary:       "create environment, flags of project are copied from source environment and disabled",
+	}, func(ctx context.Context, input *struct {
+		Project string                   `path:"project" maxLength:"100"`
+		Body    entity.EnvironmentDecode `json:"body"`
+	}) (*entity.EnvironmentResponse, error) {
+		respEnvironment, err := serviceProject.CreateEnvironment(ctx, input.Project, input.Body)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, huma.Error404NotFound(
+					fmt.Sprintf("project {%s} or source environment - not found", input.Project),
+					err,
+				)
+			}
+			if errors.Is(err, mydb.ErrDBAlreadyExists) {
+				return nil, huma.Error409Conflict(
+					fmt.Sprintf("environment {%s} already exists in project {%s}", input.Body.Name, input.Project),
+					err,
+				)
+			}
+			return nil, huma.Error500InternalServerError("environment was not created", err)
+		}
+		return respEnvironment, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-list-of-environments",
+		Method:      "GET",
+		Path:        "/projects/{project}/environments",
+		Security:    auth.Require(auth.ScopeFlagsRead),
+		Summary:     "get list of environments of project",
+	}, func(ctx context.Context, input *struct {
+		Project string `path:"project" maxLength:"100"`
+	}) (*entity.ListOfEnvironmentResponse, error) {
+		environments, err := serviceProject.RetrieveListOfEnvironments(ctx, input.Project)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, huma.Error404NotFound(fmt.Sprintf("project {%s} - not found", input.Project), err)
+			}
+			return nil, huma.Error500InternalServerError("environments were not loaded", err)
+		}
+		return environments, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-environment-settings",
+		Method:      "PUT",
+		Path:        "/projects/{project}/environments/{environment}",
+		Security:    auth.Require(auth.ScopeAdmin),
+		Summary:     "update settings of environment, required_approvals > 0 - flag changes need approval",
+	}, func(ctx context.Context, input *struct {
+		Project     string                           `path:"project" maxLength:"100"`
+		Environment string                           `path:"environment" maxLength:"100"`
+		Body        entity.EnvironmentSettingsDecode `json:"body"`
+	}) (*entity.EnvironmentResponse, error) {
+		environment := project.Environment{Project: input.Project, Name: input.Environment}
+		respEnvironment, err := serviceProject.UpdateEnvironment(ctx, environment, input.Body)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, huma.Error404NotFound(fmt.Sprintf("environment {%s} - not found", environment), err)
+			}
+			return nil, huma.Error500InternalServerError("environment was not updated", err)
+		}
+		return respEnvironment, nil
+	})
+
+	return &server{
+		app:             app,
+		cacheStorage:    fcacheStorage,
+		streamHub:       streamHub,
+		serviceFlag:     serviceFlag,
+		serviceSchedule: serviceSchedule,
+		listener:        listener,
+	}, nil
+}
+
+// environmentPathPrefix - путь окружения проекта, под ним доступны все операции над флагами
+const environmentPathPrefix = "/projects/{project}/environments/{environment}"
+
+// registerInEnvironment регистрирует операцию над флагами дважды: по старому пути для окружения default
+// проекта default и по пути окружения environmentPathPrefix
+func registerInEnvironment[I, O any](
+	api huma.API,
+	op huma.Operation,
+	handler func(context.Context, *I) (*O, error),
+) {
+	op.Metadata = map[string]any{auth.MetadataEnvironmentScoped: true}
+	huma.Register(api, op, handler)
+	op.OperationID += "-in-environment"
+	op.Path = environmentPathPrefix + op.Path
+	op.Metadata = map[string]any{auth.MetadataEnvironmentScoped: true}
+	op.Parameters = []*huma.Param{
+		{Name: "project", In: "path", Required: true, Schema: &huma.Schema{Type: huma.TypeString}},
+		{Name: "environment", In: "path", Required: true, Schema: &huma.Schema{Type: huma.TypeString}},
 	}
+	huma.Register(api, op, handler)
+}
 
-	log.Println("Сервер остановлен корректно.")
+// fiberEnvironment - окружение из параметров пути маршрута fiber, без них - окружение default
+func fiberEnvironment(c fiber.Ctx) project.Environment {
+	if c.Params("environment") == "" {
+		return project.Default()
+	}
+	return project.Environment{Project: c.Params("project"), Name: c.Params("environment")}
+}
+
+// flagPrecondition - проверка If-Match / If-None-Match / If-(Un)Modified-Since по ETag и updated_at флага:
+// для GET - 304, для PUT и DELETE - 412
+func flagPrecondition(params *conditional.Params) mydb.PreconditionFunc {
+	return func(flag models.Flag) error {
+		if err := params.PreconditionFailed(entity.FlagETag(flag.Version), flag.UpdatedAt); err != nil {
+			return err
+		}
+		return nil
+	}
 }
 
 func doMigrations(ctx context.Context, cfg *config.MigrationConfig, db *sql.DB) error {
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"feature-flag-2/config"
+	"feature-flag-2/entity"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// testServer - сервер поверх базы из DATABASE_URL со всеми миграциями, без базы тест пропускается.
+// Роль базы должна быть обычной: суперпользователь и BYPASSRLS не проверяют политики RLS
+func testServer(t *testing.T) (*server, *sql.DB, string) {
+	t.Helper()
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	db, err := sql.Open("pgx", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := doMigrations(ctx, &config.MigrationConfig{PathToMigrations: "migrations", Action: "up"}, db); err != nil {
+		t.Fatalf("doMigrations - %v", err)
+	}
+	var bypassRLS bool
+	if err := db.QueryRowContext(
+		ctx,
+		`SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`,
+	).Scan(&bypassRLS); err != nil {
+		t.Fatal(err)
+	}
+	if bypassRLS {
+		t.Fatal("role of DATABASE_URL is SUPERUSER or BYPASSRLS, row-level security is not checked")
+	}
+	bootstrapKey := "ffk_" + randomHex(t)
+	srv, err := newServer(ctx, &config.Config{
+		DB: config.DataBaseConfig{URL: dbURL},
+		Cache: config.CacheConfig{
+			TTLMiddlewareFiber: time.Second,
+			TTLLRU:             time.Minute,
+			SizeLRU:            1000,
+		},
+		Stream: config.StreamConfig{Heartbeat: time.Second, LogSize: 100},
+		Auth:   config.AuthConfig{Enabled: true, BootstrapKey: bootstrapKey},
+	}, db)
+	if err != nil {
+		t.Fatalf("newServer - %v", err)
+	}
+	t.Cleanup(func() {
+		srv.streamHub.Close()
+		srv.cacheStorage.Close()
+	})
+	return srv, db, bootstrapKey
+}
+
+func randomHex(t *testing.T) string {
+	t.Helper()
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// call выполняет запрос к app с ключом key, ответ 2xx разбирается в out
+func call(t *testing.T, app *fiber.App, method, path, key string, body, out any) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Authorization", "Bearer "+key)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("%s %s - %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil && resp.StatusCode/100 == 2 {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s - %v: %s", method, path, err, data)
+		}
+	}
+	return resp.StatusCode
+}
+
+func createTenant(t *testing.T, app *fiber.App, operatorKey, id string) string {
+	t.Helper()
+	var created entity.TenantResponse
+	if status := call(t, app, http.MethodPost, "/tenants", operatorKey, entity.TenantDecode{ID: id}, &created.Body); status != http.StatusCreated {
+		t.Fatalf("POST /tenants {%s} - %d", id, status)
+	}
+	return created.Body.Key
+}
+
+func createFlag(t *testing.T, app *fiber.App, key, flagName, owner, tag string) {
+	t.Helper()
+	flag := map[string]any{
+		"flag_name":    flagName,
+		"is_enabled":   true,
+		"active_from":  time.Now().Add(-time.Hour),
+		"data":         map[string]any{"value": owner},
+		"default_data": map[string]any{"value": "off"},
+		"tags":         []string{tag},
+	}
+	if status := call(t, app, http.MethodPost, "/flag", key, flag, nil); status != http.StatusCreated {
+		t.Fatalf("POST /flag {%s} of tenant {%s} - %d", flagName, owner, status)
+	}
+}
+
+func TestTenantKeyDoesNotSeeOtherTenantFlags(t *testing.T) {
+	srv, db, operatorKey := testServer(t)
+	suffix := randomHex(t)
+	keyA := createTenant(t, srv.app, operatorKey, "a-"+suffix)
+	keyB := createTenant(t, srv.app, operatorKey, "b-"+suffix)
+	tag := "tag_" + suffix
+	onlyB := "only_b_" + suffix
+	shared := "shared_" + suffix
+	createFlag(t, srv.app, keyB, onlyB, "b", tag)
+	createFlag(t, srv.app, keyB, shared, "b", tag)
+	createFlag(t, srv.app, keyA, shared, "a", tag)
+
+	if status := call(t, srv.app, http.MethodGet, "/flag/"+onlyB, keyB, nil, nil); status != http.StatusOK {
+		t.Fatalf("GET /flag/%s with key of tenant B - %d, want 200", onlyB, status)
+	}
+	if status := call(t, srv.app, http.MethodGet, "/flag/"+onlyB, keyA, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("GET /flag/%s with key of tenant A - %d, want 404", onlyB, status)
+	}
+	if status := call(t, srv.app, http.MethodGet, "/flag/"+onlyB+"/history", keyA, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("GET /flag/%s/history with key of tenant A - %d, want 404", onlyB, status)
+	}
+	evalCtx := map[string]any{"key": "user"}
+	if status := call(t, srv.app, http.MethodPost, "/evaluate/"+onlyB, keyA, evalCtx, nil); status != http.StatusNotFound {
+		t.Fatalf("POST /evaluate/%s with key of tenant A - %d, want 404", onlyB, status)
+	}
+
+	var list entity.ListOfFlagResponse
+	if status := call(t, srv.app, http.MethodGet, "/flags?deleted=all&tag="+tag, keyA, nil, &list.Body); status != http.StatusOK {
+		t.Fatalf("GET /flags with key of tenant A - %d", status)
+	}
+	if len(list.Body.Flags) != 1 || list.Body.Flags[0].FlagName != shared || list.Body.Flags[0].Data["value"] != "a" {
+		t.Fatalf("flags of tenant A - %+v, want only its own {%s}", list.Body.Flags, shared)
+	}
+
+	var evaluations entity.BulkEvaluationResponse
+	if status := call(t, srv.app, http.MethodPost, "/evaluate", keyA, entity.BulkEvaluationDecode{
+		Key:  "user",
+		Tags: []string{tag},
+	}, &evaluations.Body); status != http.StatusOK {
+		t.Fatalf("POST /evaluate with key of tenant A - %d", status)
+	}
+	if _, ok := evaluations.Body.Evaluations[onlyB]; ok || len(evaluations.Body.Evaluations) != 1 {
+		t.Fatalf("evaluations of tenant A - %+v, want only {%s}", evaluations.Body.Evaluations, shared)
+	}
+	if value := evaluations.Body.Evaluations[shared].Value["value"]; value != "a" {
+		t.Fatalf("{%s} of tenant A evaluates to {%v}, want its own value", shared, value)
+	}
+
+	// флаг с тем же именем у B не попадает в историю A
+	var history entity.ListOfFlagVersionResponse
+	if status := call(t, srv.app, http.MethodGet, "/flag/"+shared+"/history", keyA, nil, &history.Body); status != http.StatusOK {
+		t.Fatalf("GET /flag/%s/history with key of tenant A - %d", shared, status)
+	}
+	if history.Body.Total != 1 || len(history.Body.Versions) != 1 || history.Body.Versions[0].Snapshot.Data["value"] != "a" {
+		t.Fatalf("history of {%s} for tenant A - %+v, want one own version", shared, history.Body)
+	}
+
+	// то же на уровне БД: под FORCE ROW LEVEL SECURITY транзакция A не видит строк B
+	for _, table := range []string{"flags", "flag_versions"} {
+		var forced bool
+		if err := db.QueryRow(
+			`SELECT relrowsecurity AND relforcerowsecurity FROM pg_class WHERE oid = $1::regclass`,
+			"public."+table,
+		).Scan(&forced); err != nil {
+			t.Fatal(err)
+		}
+		if !forced {
+			t.Fatalf("row-level security of {%s} is not forced", table)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Exec(`SELECT set_config('app.tenant_id', $1, true)`, "a-"+suffix); err != nil {
+			t.Fatal(err)
+		}
+		var rowsOfB int
+		if err := tx.QueryRow(
+			`SELECT count(*) FROM public.`+table+` WHERE flag_name IN ($1, $2) AND tenant_id <> $3`,
+			onlyB,
+			shared,
+			"a-"+suffix,
+		).Scan(&rowsOfB); err != nil {
+			t.Fatal(err)
+		}
+		_ = tx.Rollback()
+		if rowsOfB != 0 {
+			t.Fatalf("transaction of tenant A sees %d rows of tenant B in {%s}", rowsOfB, table)
+		}
+	}
+}
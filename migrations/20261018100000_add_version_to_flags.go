@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up2, Down2)
+}
+
+func Up2(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down2(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags DROP COLUMN IF EXISTS version;`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up3, Down3)
+}
+
+func Up3(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD COLUMN IF NOT EXISTS rules JSONB NOT NULL DEFAULT '[]'::JSONB;`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down3(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags DROP COLUMN IF EXISTS rules;`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up4, Down4)
+}
+
+func Up4(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD COLUMN IF NOT EXISTS rollout JSONB;`); err != nil {
+		return err
+	}
+	// переносим "percentage" из data в rollout, чтобы сервис начал его применять
+	if _, err := tx.ExecContext(ctx, `UPDATE public.flags SET rollout = jsonb_build_object(
+	'percentage', LEAST(GREATEST((data->>'percentage')::NUMERIC, 0), 100),
+	'bucket_by',  'key',
+	'salt',       md5(flag_name)
+) WHERE rollout IS NULL AND jsonb_typeof(data->'percentage') = 'number';`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down4(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags DROP COLUMN IF EXISTS rollout;`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up5, Down5)
+}
+
+func Up5(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD COLUMN IF NOT EXISTS variations    JSONB NOT NULL DEFAULT '[]'::JSONB,
+	ADD COLUMN IF NOT EXISTS off_variation TEXT  NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS fallthrough   JSONB;`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down5(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	DROP COLUMN IF EXISTS variations,
+	DROP COLUMN IF EXISTS off_variation,
+	DROP COLUMN IF EXISTS fallthrough;`); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up6, Down6)
+}
+
+func Up6(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.segments (
+	segment_name   TEXT                        NOT NULL,
+	description    TEXT                        NOT NULL DEFAULT '',
+	is_deleted     BOOLEAN                     NOT NULL,
+	included       JSONB                       NOT NULL DEFAULT '[]'::JSONB,
+	excluded       JSONB                       NOT NULL DEFAULT '[]'::JSONB,
+	rules          JSONB                       NOT NULL DEFAULT '[]'::JSONB,
+	created_by     UUID                        NOT NULL,
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	updated_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	version        BIGINT                      NOT NULL DEFAULT 1,
+	CONSTRAINT pk_segments PRIMARY KEY (segment_name)
+);`); err != nil {
+		return err
+	}
+	// поиск флагов, ссылающихся на сегмент через in_segment/not_in_segment
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_flags_rules
+	ON public.flags USING GIN (rules jsonb_path_ops);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down6(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS public.idx_flags_rules;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.segments;"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up7, Down7)
+}
+
+func Up7(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD COLUMN IF NOT EXISTS prerequisites JSONB NOT NULL DEFAULT '[]'::JSONB;`); err != nil {
+		return err
+	}
+	// поиск зависимых флагов при удалении
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_flags_prerequisites
+	ON public.flags USING GIN (prerequisites jsonb_path_ops);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down7(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS public.idx_flags_prerequisites;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags DROP COLUMN IF EXISTS prerequisites;`); err != nil {
+		return err
+	}
+	return nil
+}
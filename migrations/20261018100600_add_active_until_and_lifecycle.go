@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up8, Down8)
+}
+
+func Up8(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD COLUMN IF NOT EXISTS active_until TIMESTAMP WITH TIME ZONE;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.flag_lifecycle_events (
+	id             BIGSERIAL                   NOT NULL,
+	flag_name      TEXT                        NOT NULL,
+	state          TEXT                        NOT NULL,
+	occurred_at    TIMESTAMP WITH TIME ZONE    NOT NULL,
+	recorded_at    TIMESTAMP WITH TIME ZONE    NOT NULL,
+	CONSTRAINT pk_flag_lifecycle_events PRIMARY KEY (id),
+	CONSTRAINT uq_flag_lifecycle_events UNIQUE (flag_name, state, occurred_at)
+);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down8(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.flag_lifecycle_events;"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags DROP COLUMN IF EXISTS active_until;`); err != nil {
+		return err
+	}
+	return nil
+}
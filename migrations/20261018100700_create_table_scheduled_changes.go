@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up9, Down9)
+}
+
+func Up9(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.scheduled_changes (
+	id             UUID                        NOT NULL,
+	flag_name      TEXT                        NOT NULL,
+	apply_at       TIMESTAMP WITH TIME ZONE    NOT NULL,
+	changes        JSONB                       NOT NULL,
+	comment        TEXT                        NOT NULL DEFAULT '',
+	status         TEXT                        NOT NULL,
+	error          TEXT                        NOT NULL DEFAULT '',
+	created_by     UUID                        NOT NULL,
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	applied_at     TIMESTAMP WITH TIME ZONE,
+	CONSTRAINT pk_scheduled_changes PRIMARY KEY (id)
+);`); err != nil {
+		return err
+	}
+	// воркер выбирает только pending изменения с наступившим apply_at
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_scheduled_changes_pending
+	ON public.scheduled_changes (apply_at) WHERE status = 'pending';`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_scheduled_changes_flag_name
+	ON public.scheduled_changes (flag_name, apply_at);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down9(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.scheduled_changes;"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up11, Down11)
+}
+
+func Up11(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.flag_versions (
+	id             BIGSERIAL                   NOT NULL,
+	flag_name      TEXT                        NOT NULL,
+	version        BIGINT                      NOT NULL,
+	snapshot       JSONB                       NOT NULL,
+	actor          TEXT                        NOT NULL,
+	comment        TEXT                        NOT NULL DEFAULT '',
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	CONSTRAINT pk_flag_versions PRIMARY KEY (id),
+	CONSTRAINT uq_flag_versions_flag_name_version UNIQUE (flag_name, version)
+);`); err != nil {
+		return err
+	}
+	// текущее состояние существующих флагов - первая запись их истории,
+	// имена колонок flags совпадают с json полями models.Flag
+	if _, err := tx.ExecContext(ctx, `INSERT INTO public.flag_versions (
+	flag_name,
+	version,
+	snapshot,
+	actor,
+	created_at
+)
+SELECT flag_name, version, to_jsonb(flags), 'migration', updated_at FROM public.flags
+ON CONFLICT (flag_name, version) DO NOTHING;`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down11(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.flag_versions;"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up12, Down12)
+}
+
+// createdByTables - таблицы, где автор - идентификатор субъекта запроса
+var createdByTables = []string{"flags", "scheduled_changes", "segments"}
+
+// Up12 - автор флага, отложенного изменения и сегмента теперь идентификатор субъекта запроса,
+// а не присланный клиентом UUID
+func Up12(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range createdByTables {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	ALTER COLUMN created_by TYPE TEXT USING created_by::TEXT;`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Down12(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range createdByTables {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	ALTER COLUMN created_by TYPE UUID USING (
+		CASE WHEN created_by ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+		THEN created_by::UUID
+		ELSE '00000000-0000-0000-0000-000000000000'::UUID END
+	);`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up13, Down13)
+}
+
+func Up13(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.api_keys (
+	id             UUID                        NOT NULL,
+	name           TEXT                        NOT NULL,
+	prefix         TEXT                        NOT NULL,
+	key_hash       TEXT                        NOT NULL,
+	scopes         JSONB                       NOT NULL DEFAULT '[]'::JSONB,
+	created_by     TEXT                        NOT NULL,
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	revoked_at     TIMESTAMP WITH TIME ZONE,
+	CONSTRAINT pk_api_keys PRIMARY KEY (id),
+	CONSTRAINT uq_api_keys_key_hash UNIQUE (key_hash)
+);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down13(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.api_keys;"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up14, Down14)
+}
+
+func Up14(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.users (
+	id             UUID                        NOT NULL,
+	subject        TEXT                        NOT NULL,
+	email          TEXT                        NOT NULL DEFAULT '',
+	name           TEXT                        NOT NULL DEFAULT '',
+	is_disabled    BOOLEAN                     NOT NULL DEFAULT FALSE,
+	created_by     TEXT                        NOT NULL,
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	CONSTRAINT pk_users PRIMARY KEY (id),
+	CONSTRAINT uq_users_subject UNIQUE (subject)
+);`); err != nil {
+		return err
+	}
+	// пустые project и environment - роль во всех проектах и окружениях
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.role_bindings (
+	id             UUID                        NOT NULL,
+	user_id        UUID                        NOT NULL,
+	role           TEXT                        NOT NULL,
+	project        TEXT                        NOT NULL DEFAULT '',
+	environment    TEXT                        NOT NULL DEFAULT '',
+	created_by     TEXT                        NOT NULL,
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	CONSTRAINT pk_role_bindings PRIMARY KEY (id),
+	CONSTRAINT fk_role_bindings_user_id FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE,
+	CONSTRAINT uq_role_bindings UNIQUE (user_id, role, project, environment)
+);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down14(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.role_bindings;"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.users;"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up15, Down15)
+}
+
+func Up15(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.projects (
+	name           TEXT                        NOT NULL,
+	description    TEXT                        NOT NULL DEFAULT '',
+	created_by     TEXT                        NOT NULL,
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	CONSTRAINT pk_projects PRIMARY KEY (name)
+);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.environments (
+	id             UUID                        NOT NULL,
+	project        TEXT                        NOT NULL,
+	name           TEXT                        NOT NULL,
+	created_by     TEXT                        NOT NULL,
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	CONSTRAINT pk_environments PRIMARY KEY (id),
+	CONSTRAINT fk_environments_project FOREIGN KEY (project) REFERENCES public.projects (name),
+	CONSTRAINT uq_environments_project_name UNIQUE (project, name)
+);`); err != nil {
+		return err
+	}
+	// существующие флаги переезжают в окружение default проекта default, его обслуживают старые пути
+	if _, err := tx.ExecContext(ctx, `INSERT INTO public.projects (name, created_by, created_at)
+VALUES ('default', 'migration', now())
+ON CONFLICT (name) DO NOTHING;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO public.environments (id, project, name, created_by, created_at)
+VALUES (gen_random_uuid(), 'default', 'default', 'migration', now())
+ON CONFLICT (project, name) DO NOTHING;`); err != nil {
+		return err
+	}
+	// reform работает с первичным ключом из одной колонки, поэтому у флага суррогатный id,
+	// а имя уникально в окружении
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD COLUMN IF NOT EXISTS id UUID NOT NULL DEFAULT gen_random_uuid(),
+	ADD COLUMN IF NOT EXISTS project TEXT NOT NULL DEFAULT 'default',
+	ADD COLUMN IF NOT EXISTS environment TEXT NOT NULL DEFAULT 'default',
+	DROP CONSTRAINT IF EXISTS pk_flags;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD CONSTRAINT pk_flags PRIMARY KEY (id),
+	ADD CONSTRAINT uq_flags_project_environment_flag_name UNIQUE (project, environment, flag_name),
+	ADD CONSTRAINT fk_flags_environment FOREIGN KEY (project, environment)
+		REFERENCES public.environments (project, name);`); err != nil {
+		return err
+	}
+	for _, table := range []string{"flags", "flag_versions", "flag_lifecycle_events", "scheduled_changes"} {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	ADD COLUMN IF NOT EXISTS project TEXT NOT NULL DEFAULT 'default',
+	ADD COLUMN IF NOT EXISTS environment TEXT NOT NULL DEFAULT 'default';`); err != nil {
+			return err
+		}
+		// проект и окружение всегда задает репозиторий
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	ALTER COLUMN project DROP DEFAULT,
+	ALTER COLUMN environment DROP DEFAULT;`); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flag_versions
+	DROP CONSTRAINT IF EXISTS uq_flag_versions_flag_name_version,
+	ADD CONSTRAINT uq_flag_versions_flag_name_version UNIQUE (project, environment, flag_name, version);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flag_lifecycle_events
+	DROP CONSTRAINT IF EXISTS uq_flag_lifecycle_events,
+	ADD CONSTRAINT uq_flag_lifecycle_events UNIQUE (project, environment, flag_name, state, occurred_at);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS public.idx_scheduled_changes_flag_name;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_scheduled_changes_flag_name
+	ON public.scheduled_changes (project, environment, flag_name, apply_at);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Down15 оставляет только флаги окружения default проекта default
+func Down15(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range []string{"flags", "flag_versions", "flag_lifecycle_events", "scheduled_changes"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM public.`+table+`
+WHERE project <> 'default' OR environment <> 'default';`); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS public.idx_scheduled_changes_flag_name;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_scheduled_changes_flag_name
+	ON public.scheduled_changes (flag_name, apply_at);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flag_lifecycle_events
+	DROP CONSTRAINT IF EXISTS uq_flag_lifecycle_events,
+	ADD CONSTRAINT uq_flag_lifecycle_events UNIQUE (flag_name, state, occurred_at);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flag_versions
+	DROP CONSTRAINT IF EXISTS uq_flag_versions_flag_name_version,
+	ADD CONSTRAINT uq_flag_versions_flag_name_version UNIQUE (flag_name, version);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	DROP CONSTRAINT IF EXISTS fk_flags_environment,
+	DROP CONSTRAINT IF EXISTS uq_flags_project_environment_flag_name,
+	DROP CONSTRAINT IF EXISTS pk_flags,
+	DROP COLUMN IF EXISTS id;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags ADD CONSTRAINT pk_flags PRIMARY KEY (flag_name);`); err != nil {
+		return err
+	}
+	for _, table := range []string{"flags", "flag_versions", "flag_lifecycle_events", "scheduled_changes"} {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	DROP COLUMN IF EXISTS project,
+	DROP COLUMN IF EXISTS environment;`); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.environments;"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.projects;"); err != nil {
+		return err
+	}
+	return nil
+}
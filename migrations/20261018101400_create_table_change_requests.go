@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up16, Down16)
+}
+
+func Up16(ctx context.Context, tx *sql.Tx) error {
+	// 0 - изменения флагов окружения применяются сразу, иначе - через запрос на изменение
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.environments
+	ADD COLUMN IF NOT EXISTS required_approvals INT NOT NULL DEFAULT 0;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.change_requests (
+	id                    UUID                        NOT NULL,
+	project               TEXT                        NOT NULL,
+	environment           TEXT                        NOT NULL,
+	flag_name             TEXT                        NOT NULL,
+	kind                  TEXT                        NOT NULL,
+	base_version          BIGINT                      NOT NULL,
+	proposed              JSONB                       NOT NULL,
+	status                TEXT                        NOT NULL,
+	required_approvals    INT                         NOT NULL,
+	approvals             JSONB                       NOT NULL DEFAULT '[]',
+	comment               TEXT                        NOT NULL DEFAULT '',
+	created_by            TEXT                        NOT NULL,
+	created_at            TIMESTAMP WITH TIME ZONE    NOT NULL,
+	resolved_by           TEXT                        NOT NULL DEFAULT '',
+	resolved_at           TIMESTAMP WITH TIME ZONE,
+	reason                TEXT                        NOT NULL DEFAULT '',
+	CONSTRAINT pk_change_requests PRIMARY KEY (id),
+	CONSTRAINT fk_change_requests_environment FOREIGN KEY (project, environment)
+		REFERENCES public.environments (project, name)
+);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_change_requests_flag_status
+	ON public.change_requests (project, environment, flag_name, status);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Down16(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.change_requests;"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "ALTER TABLE public.environments DROP COLUMN IF EXISTS required_approvals;"); err != nil {
+		return err
+	}
+	return nil
+}
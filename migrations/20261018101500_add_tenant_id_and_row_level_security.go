@@ -0,0 +1,221 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up17, Down17)
+}
+
+// tenantTables - таблицы с данными арендатора, в порядке создания
+var tenantTables = []string{
+	"projects",
+	"environments",
+	"flags",
+	"flag_versions",
+	"flag_lifecycle_events",
+	"scheduled_changes",
+	"segments",
+	"change_requests",
+	"api_keys",
+	"users",
+	"role_bindings",
+}
+
+// credentialTables - по этим таблицам ищутся API ключи и пользователи до того, как известен арендатор
+var credentialTables = []string{"api_keys", "users", "role_bindings"}
+
+func Up17(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.tenants (
+	id             TEXT                        NOT NULL,
+	name           TEXT                        NOT NULL DEFAULT '',
+	created_by     TEXT                        NOT NULL,
+	created_at     TIMESTAMP WITH TIME ZONE    NOT NULL,
+	CONSTRAINT pk_tenants PRIMARY KEY (id)
+);`); err != nil {
+		return err
+	}
+	// существующие данные принадлежат арендатору default
+	if _, err := tx.ExecContext(ctx, `INSERT INTO public.tenants (id, name, created_by, created_at)
+VALUES ('default', 'default', 'migration', now())
+ON CONFLICT (id) DO NOTHING;`); err != nil {
+		return err
+	}
+	for _, table := range tenantTables {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';`); err != nil {
+			return err
+		}
+		// новые строки получают арендатора транзакции, вне транзакции арендатора вставка не пройдет
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id'),
+	ADD CONSTRAINT fk_`+table+`_tenant_id FOREIGN KEY (tenant_id) REFERENCES public.tenants (id);`); err != nil {
+			return err
+		}
+	}
+	// имена проектов, окружений, флагов и сегментов уникальны внутри арендатора
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.change_requests
+	DROP CONSTRAINT IF EXISTS fk_change_requests_environment;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	DROP CONSTRAINT IF EXISTS fk_flags_environment,
+	DROP CONSTRAINT IF EXISTS uq_flags_project_environment_flag_name;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.environments
+	DROP CONSTRAINT IF EXISTS fk_environments_project,
+	DROP CONSTRAINT IF EXISTS uq_environments_project_name;`); err != nil {
+		return err
+	}
+	// reform по-прежнему видит первичный ключ из одной колонки: строки чужих арендаторов
+	// скрывает RLS, поэтому имя внутри транзакции арендатора однозначно
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.projects
+	DROP CONSTRAINT IF EXISTS pk_projects,
+	ADD CONSTRAINT pk_projects PRIMARY KEY (tenant_id, name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.environments
+	ADD CONSTRAINT uq_environments_project_name UNIQUE (tenant_id, project, name),
+	ADD CONSTRAINT fk_environments_project FOREIGN KEY (tenant_id, project)
+		REFERENCES public.projects (tenant_id, name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD CONSTRAINT uq_flags_project_environment_flag_name UNIQUE (tenant_id, project, environment, flag_name),
+	ADD CONSTRAINT fk_flags_environment FOREIGN KEY (tenant_id, project, environment)
+		REFERENCES public.environments (tenant_id, project, name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.change_requests
+	ADD CONSTRAINT fk_change_requests_environment FOREIGN KEY (tenant_id, project, environment)
+		REFERENCES public.environments (tenant_id, project, name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flag_versions
+	DROP CONSTRAINT IF EXISTS uq_flag_versions_flag_name_version,
+	ADD CONSTRAINT uq_flag_versions_flag_name_version UNIQUE (tenant_id, project, environment, flag_name, version);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flag_lifecycle_events
+	DROP CONSTRAINT IF EXISTS uq_flag_lifecycle_events,
+	ADD CONSTRAINT uq_flag_lifecycle_events UNIQUE (tenant_id, project, environment, flag_name, state, occurred_at);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.segments
+	DROP CONSTRAINT IF EXISTS pk_segments,
+	ADD CONSTRAINT pk_segments PRIMARY KEY (tenant_id, segment_name);`); err != nil {
+		return err
+	}
+	// FORCE - политики действуют и на владельца таблиц, от которого работает сервис.
+	// Суперпользователь и роли с BYPASSRLS политики не проверяют, сервису нужна обычная роль
+	for _, table := range tenantTables {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	ENABLE ROW LEVEL SECURITY,
+	FORCE ROW LEVEL SECURITY;`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `CREATE POLICY tenant_isolation ON public.`+table+`
+	USING (tenant_id = current_setting('app.tenant_id', true))
+	WITH CHECK (tenant_id = current_setting('app.tenant_id', true));`); err != nil {
+			return err
+		}
+	}
+	// по ключу или subject из JWT арендатор только определяется - читать нужно учетные данные всех арендаторов
+	for _, table := range credentialTables {
+		if _, err := tx.ExecContext(ctx, `CREATE POLICY resolve_credentials ON public.`+table+`
+	FOR SELECT
+	USING (current_setting('app.resolve_credentials', true) = 'on');`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down17 оставляет только данные арендатора default
+func Down17(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range credentialTables {
+		if _, err := tx.ExecContext(ctx, `DROP POLICY IF EXISTS resolve_credentials ON public.`+table+`;`); err != nil {
+			return err
+		}
+	}
+	for _, table := range tenantTables {
+		if _, err := tx.ExecContext(ctx, `DROP POLICY IF EXISTS tenant_isolation ON public.`+table+`;`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	NO FORCE ROW LEVEL SECURITY,
+	DISABLE ROW LEVEL SECURITY;`); err != nil {
+			return err
+		}
+	}
+	for i := len(tenantTables) - 1; i >= 0; i-- {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM public.`+tenantTables[i]+` WHERE tenant_id <> 'default';`); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.segments
+	DROP CONSTRAINT IF EXISTS pk_segments,
+	ADD CONSTRAINT pk_segments PRIMARY KEY (segment_name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flag_lifecycle_events
+	DROP CONSTRAINT IF EXISTS uq_flag_lifecycle_events,
+	ADD CONSTRAINT uq_flag_lifecycle_events UNIQUE (project, environment, flag_name, state, occurred_at);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flag_versions
+	DROP CONSTRAINT IF EXISTS uq_flag_versions_flag_name_version,
+	ADD CONSTRAINT uq_flag_versions_flag_name_version UNIQUE (project, environment, flag_name, version);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.change_requests
+	DROP CONSTRAINT IF EXISTS fk_change_requests_environment;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	DROP CONSTRAINT IF EXISTS fk_flags_environment,
+	DROP CONSTRAINT IF EXISTS uq_flags_project_environment_flag_name;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.environments
+	DROP CONSTRAINT IF EXISTS fk_environments_project,
+	DROP CONSTRAINT IF EXISTS uq_environments_project_name;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.projects
+	DROP CONSTRAINT IF EXISTS pk_projects,
+	ADD CONSTRAINT pk_projects PRIMARY KEY (name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.environments
+	ADD CONSTRAINT fk_environments_project FOREIGN KEY (project) REFERENCES public.projects (name),
+	ADD CONSTRAINT uq_environments_project_name UNIQUE (project, name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.flags
+	ADD CONSTRAINT uq_flags_project_environment_flag_name UNIQUE (project, environment, flag_name),
+	ADD CONSTRAINT fk_flags_environment FOREIGN KEY (project, environment)
+		REFERENCES public.environments (project, name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE public.change_requests
+	ADD CONSTRAINT fk_change_requests_environment FOREIGN KEY (project, environment)
+		REFERENCES public.environments (project, name);`); err != nil {
+		return err
+	}
+	for _, table := range tenantTables {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE public.`+table+`
+	DROP CONSTRAINT IF EXISTS fk_`+table+`_tenant_id,
+	DROP COLUMN IF EXISTS tenant_id;`); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS public.tenants;"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(Up18, Down18)
+}
+
+// Up18 - индексы страниц GET /flags: порядок по датам с именем флага вторым ключом,
+// поиск по имени без учета регистра и фильтр по автору. Порядок по имени дает uq_flags_project_environment_flag_name,
+// фильтр по тегу - idx_flags_tags
+func Up18(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_flags_created_at
+	ON public.flags (tenant_id, project, environment, created_at, flag_name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_flags_updated_at
+	ON public.flags (tenant_id, project, environment, updated_at, flag_name);`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_flags_created_by
+	ON public.flags (tenant_id, project, environment, created_by);`); err != nil {
+		return err
+	}
+	// префикс: lower(flag_name) LIKE 'abc%' идет по text_pattern_ops при любой collation базы
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_flags_flag_name_prefix
+	ON public.flags (tenant_id, project, environment, lower(flag_name) text_pattern_ops);`); err != nil {
+		return err
+	}
+	// подстрока: lower(flag_name) LIKE '%abc%' идет по триграммам, pg_trgm доверенное расширение с PostgreSQL 13
+	if _, err := tx.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS pg_trgm;`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_flags_flag_name_trgm
+	ON public.flags USING GIN (lower(flag_name) gin_trgm_ops);`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Down18 оставляет pg_trgm: расширение могут использовать не только флаги
+func Down18(ctx context.Context, tx *sql.Tx) error {
+	for _, index := range []string{
+		"idx_flags_flag_name_trgm",
+		"idx_flags_flag_name_prefix",
+		"idx_flags_created_by",
+		"idx_flags_updated_at",
+		"idx_flags_created_at",
+	} {
+		if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS public.`+index+`;`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+//reform:public.api_keys
+type APIKey struct {
+	ID uuid.UUID `json:"id" reform:"id,pk"`
+	// TenantID - арендатор, к данным которого ключ дает доступ
+	TenantID string `json:"tenant_id" reform:"tenant_id"`
+	Name     string `json:"name" reform:"name"`
+	// Prefix - начало ключа, чтобы узнать ключ в списке, сам ключ не хранится
+	Prefix    string     `json:"prefix" reform:"prefix"`
+	KeyHash   string     `json:"-" reform:"key_hash"`
+	Scopes    StringList `json:"scopes" reform:"scopes"`
+	CreatedBy string     `json:"created_by" reform:"created_by"`
+	CreatedAt time.Time  `json:"created_at" reform:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" reform:"revoked_at"`
+}
+
+func (k APIKey) GetModelName() string {
+	return k.Name
+}
@@ -0,0 +1,172 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type aPIKeyTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *aPIKeyTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("api_keys").
+func (v *aPIKeyTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *aPIKeyTableType) Columns() []string {
+	return []string{
+		"id",
+		"tenant_id",
+		"name",
+		"prefix",
+		"key_hash",
+		"scopes",
+		"created_by",
+		"created_at",
+		"revoked_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *aPIKeyTableType) NewStruct() reform.Struct {
+	return new(APIKey)
+}
+
+// NewRecord makes a new record for that table.
+func (v *aPIKeyTableType) NewRecord() reform.Record {
+	return new(APIKey)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *aPIKeyTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// APIKeyTable represents api_keys view or table in SQL database.
+var APIKeyTable = &aPIKeyTableType{
+	s: parse.StructInfo{
+		Type:      "APIKey",
+		SQLSchema: "public",
+		SQLName:   "api_keys",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "uuid.UUID", Column: "id"},
+			{Name: "TenantID", Type: "string", Column: "tenant_id"},
+			{Name: "Name", Type: "string", Column: "name"},
+			{Name: "Prefix", Type: "string", Column: "prefix"},
+			{Name: "KeyHash", Type: "string", Column: "key_hash"},
+			{Name: "Scopes", Type: "StringList", Column: "scopes"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+			{Name: "RevokedAt", Type: "*time.Time", Column: "revoked_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(APIKey).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s APIKey) String() string {
+	res := make([]string, 9)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "TenantID: " + reform.Inspect(s.TenantID, true)
+	res[2] = "Name: " + reform.Inspect(s.Name, true)
+	res[3] = "Prefix: " + reform.Inspect(s.Prefix, true)
+	res[4] = "KeyHash: " + reform.Inspect(s.KeyHash, true)
+	res[5] = "Scopes: " + reform.Inspect(s.Scopes, true)
+	res[6] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[7] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	res[8] = "RevokedAt: " + reform.Inspect(s.RevokedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *APIKey) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.TenantID,
+		s.Name,
+		s.Prefix,
+		s.KeyHash,
+		s.Scopes,
+		s.CreatedBy,
+		s.CreatedAt,
+		s.RevokedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *APIKey) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.TenantID,
+		&s.Name,
+		&s.Prefix,
+		&s.KeyHash,
+		&s.Scopes,
+		&s.CreatedBy,
+		&s.CreatedAt,
+		&s.RevokedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *APIKey) View() reform.View {
+	return APIKeyTable
+}
+
+// Table returns Table object for that record.
+func (s *APIKey) Table() reform.Table {
+	return APIKeyTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *APIKey) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *APIKey) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *APIKey) HasPK() bool {
+	return s.ID != APIKeyTable.z[APIKeyTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *APIKey) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = APIKeyTable
+	_ reform.Struct = (*APIKey)(nil)
+	_ reform.Table  = APIKeyTable
+	_ reform.Record = (*APIKey)(nil)
+	_ fmt.Stringer  = (*APIKey)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&APIKeyTable.s, new(APIKey))
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+var ErrModelsApprovalsUnknownType = errors.New("models approvals unknown type")
+
+// ChangeRequestKind - какое изменение флага предлагает запрос
+type ChangeRequestKind string
+
+const (
+	ChangeRequestKindUpdate ChangeRequestKind = "update"
+	ChangeRequestKindDelete ChangeRequestKind = "delete"
+)
+
+// ChangeRequestStatus - статус запроса на изменение флага
+type ChangeRequestStatus string
+
+const (
+	ChangeRequestStatusPending  ChangeRequestStatus = "pending"
+	ChangeRequestStatusApproved ChangeRequestStatus = "approved"
+	ChangeRequestStatusRejected ChangeRequestStatus = "rejected"
+	ChangeRequestStatusApplied  ChangeRequestStatus = "applied"
+	// ChangeRequestStatusInvalidated - флаг изменился после открытия запроса, предложение устарело
+	ChangeRequestStatusInvalidated ChangeRequestStatus = "invalidated"
+)
+
+// IsOpen - запрос еще можно одобрить, отклонить или применить
+func (s ChangeRequestStatus) IsOpen() bool {
+	return s == ChangeRequestStatusPending || s == ChangeRequestStatusApproved
+}
+
+// Approval - одобрение запроса на изменение
+type Approval struct {
+	Actor     string    `json:"actor"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Approvals - одобрения запроса, хранятся в JSONB
+type Approvals []Approval
+
+func (a Approvals) Value() (driver.Value, error) {
+	if a == nil {
+		return []byte(`[]`), nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *Approvals) Scan(value any) error {
+	if value == nil {
+		*a = nil
+		return nil
+	}
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsApprovalsUnknownType
+	}
+	return json.Unmarshal(data, a)
+}
+
+// Contains - субъект actor уже одобрил запрос
+func (a Approvals) Contains(actor string) bool {
+	for _, approval := range a {
+		if approval.Actor == actor {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeRequest - предложенное изменение флага в окружении, которое требует одобрения.
+// BaseVersion - версия флага при открытии запроса, Proposed - состояние флага после применения
+//
+//reform:public.change_requests
+type ChangeRequest struct {
+	ID                uuid.UUID           `json:"id" reform:"id,pk"`
+	Project           string              `json:"project" reform:"project"`
+	Environment       string              `json:"environment" reform:"environment"`
+	FlagName          string              `json:"flag_name" reform:"flag_name"`
+	Kind              ChangeRequestKind   `json:"kind" reform:"kind"`
+	BaseVersion       int64               `json:"base_version" reform:"base_version"`
+	Proposed          FlagSnapshot        `json:"proposed" reform:"proposed"`
+	Status            ChangeRequestStatus `json:"status" reform:"status"`
+	RequiredApprovals int                 `json:"required_approvals" reform:"required_approvals"`
+	Approvals         Approvals           `json:"approvals" reform:"approvals"`
+	Comment           string              `json:"comment,omitempty" reform:"comment"`
+	CreatedBy         string              `json:"created_by" reform:"created_by"`
+	CreatedAt         time.Time           `json:"created_at" reform:"created_at"`
+	ResolvedBy        string              `json:"resolved_by,omitempty" reform:"resolved_by"`
+	ResolvedAt        *time.Time          `json:"resolved_at,omitempty" reform:"resolved_at"`
+	// Reason - почему запрос отклонен или устарел
+	Reason string `json:"reason,omitempty" reform:"reason"`
+}
+
+func (cr ChangeRequest) GetModelName() string {
+	return cr.ID.String()
+}
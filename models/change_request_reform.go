@@ -0,0 +1,207 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type changeRequestTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *changeRequestTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("change_requests").
+func (v *changeRequestTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *changeRequestTableType) Columns() []string {
+	return []string{
+		"id",
+		"project",
+		"environment",
+		"flag_name",
+		"kind",
+		"base_version",
+		"proposed",
+		"status",
+		"required_approvals",
+		"approvals",
+		"comment",
+		"created_by",
+		"created_at",
+		"resolved_by",
+		"resolved_at",
+		"reason",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *changeRequestTableType) NewStruct() reform.Struct {
+	return new(ChangeRequest)
+}
+
+// NewRecord makes a new record for that table.
+func (v *changeRequestTableType) NewRecord() reform.Record {
+	return new(ChangeRequest)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *changeRequestTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// ChangeRequestTable represents change_requests view or table in SQL database.
+var ChangeRequestTable = &changeRequestTableType{
+	s: parse.StructInfo{
+		Type:      "ChangeRequest",
+		SQLSchema: "public",
+		SQLName:   "change_requests",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "uuid.UUID", Column: "id"},
+			{Name: "Project", Type: "string", Column: "project"},
+			{Name: "Environment", Type: "string", Column: "environment"},
+			{Name: "FlagName", Type: "string", Column: "flag_name"},
+			{Name: "Kind", Type: "ChangeRequestKind", Column: "kind"},
+			{Name: "BaseVersion", Type: "int64", Column: "base_version"},
+			{Name: "Proposed", Type: "FlagSnapshot", Column: "proposed"},
+			{Name: "Status", Type: "ChangeRequestStatus", Column: "status"},
+			{Name: "RequiredApprovals", Type: "int", Column: "required_approvals"},
+			{Name: "Approvals", Type: "Approvals", Column: "approvals"},
+			{Name: "Comment", Type: "string", Column: "comment"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+			{Name: "ResolvedBy", Type: "string", Column: "resolved_by"},
+			{Name: "ResolvedAt", Type: "*time.Time", Column: "resolved_at"},
+			{Name: "Reason", Type: "string", Column: "reason"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(ChangeRequest).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s ChangeRequest) String() string {
+	res := make([]string, 16)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "Project: " + reform.Inspect(s.Project, true)
+	res[2] = "Environment: " + reform.Inspect(s.Environment, true)
+	res[3] = "FlagName: " + reform.Inspect(s.FlagName, true)
+	res[4] = "Kind: " + reform.Inspect(s.Kind, true)
+	res[5] = "BaseVersion: " + reform.Inspect(s.BaseVersion, true)
+	res[6] = "Proposed: " + reform.Inspect(s.Proposed, true)
+	res[7] = "Status: " + reform.Inspect(s.Status, true)
+	res[8] = "RequiredApprovals: " + reform.Inspect(s.RequiredApprovals, true)
+	res[9] = "Approvals: " + reform.Inspect(s.Approvals, true)
+	res[10] = "Comment: " + reform.Inspect(s.Comment, true)
+	res[11] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[12] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	res[13] = "ResolvedBy: " + reform.Inspect(s.ResolvedBy, true)
+	res[14] = "ResolvedAt: " + reform.Inspect(s.ResolvedAt, true)
+	res[15] = "Reason: " + reform.Inspect(s.Reason, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *ChangeRequest) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.Project,
+		s.Environment,
+		s.FlagName,
+		s.Kind,
+		s.BaseVersion,
+		s.Proposed,
+		s.Status,
+		s.RequiredApprovals,
+		s.Approvals,
+		s.Comment,
+		s.CreatedBy,
+		s.CreatedAt,
+		s.ResolvedBy,
+		s.ResolvedAt,
+		s.Reason,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *ChangeRequest) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.Project,
+		&s.Environment,
+		&s.FlagName,
+		&s.Kind,
+		&s.BaseVersion,
+		&s.Proposed,
+		&s.Status,
+		&s.RequiredApprovals,
+		&s.Approvals,
+		&s.Comment,
+		&s.CreatedBy,
+		&s.CreatedAt,
+		&s.ResolvedBy,
+		&s.ResolvedAt,
+		&s.Reason,
+	}
+}
+
+// View returns View object for that struct.
+func (s *ChangeRequest) View() reform.View {
+	return ChangeRequestTable
+}
+
+// Table returns Table object for that record.
+func (s *ChangeRequest) Table() reform.Table {
+	return ChangeRequestTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *ChangeRequest) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *ChangeRequest) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *ChangeRequest) HasPK() bool {
+	return s.ID != ChangeRequestTable.z[ChangeRequestTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *ChangeRequest) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = ChangeRequestTable
+	_ reform.Struct = (*ChangeRequest)(nil)
+	_ reform.Table  = ChangeRequestTable
+	_ reform.Record = (*ChangeRequest)(nil)
+	_ fmt.Stringer  = (*ChangeRequest)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&ChangeRequestTable.s, new(ChangeRequest))
+}
@@ -8,15 +8,28 @@ import (
 
 //reform:public.flags
 type Flag struct {
-	FlagName    string    `json:"flag_name" reform:"flag_name,pk"`
-	IsDeleted   bool      `json:"is_deleted" reform:"is_deleted"`
-	IsEnabled   bool      `json:"is_enabled" reform:"is_enabled"`
-	ActiveFrom  time.Time `json:"active_from" reform:"active_from"`
-	Data        JSONmap   `json:"data" reform:"data"`
-	DefaultData JSONmap   `json:"default_data" reform:"default_data"`
-	CreatedBy   uuid.UUID `json:"created_by" reform:"created_by"`
-	CreatedAt   time.Time `json:"created_at" reform:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" reform:"updated_at"`
+	ID            uuid.UUID     `json:"-" reform:"id,pk"`
+	TenantID      string        `json:"-" reform:"tenant_id"`
+	Project       string        `json:"project" required:"false" reform:"project"`
+	Environment   string        `json:"environment" required:"false" reform:"environment"`
+	FlagName      string        `json:"flag_name" reform:"flag_name"`
+	IsDeleted     bool          `json:"is_deleted" reform:"is_deleted"`
+	IsEnabled     bool          `json:"is_enabled" reform:"is_enabled"`
+	ActiveFrom    time.Time     `json:"active_from" reform:"active_from"`
+	ActiveUntil   *time.Time    `json:"active_until,omitempty" reform:"active_until"`
+	Data          JSONmap       `json:"data" reform:"data"`
+	DefaultData   JSONmap       `json:"default_data" reform:"default_data"`
+	Rules         Rules         `json:"rules" required:"false" reform:"rules"`
+	Rollout       *Rollout      `json:"rollout,omitempty" reform:"rollout"`
+	Variations    Variations    `json:"variations" required:"false" reform:"variations"`
+	OffVariation  string        `json:"off_variation,omitempty" reform:"off_variation"`
+	Fallthrough   *Serve        `json:"fallthrough,omitempty" reform:"fallthrough"`
+	Prerequisites Prerequisites `json:"prerequisites" required:"false" reform:"prerequisites"`
+	Tags          StringList    `json:"tags" required:"false" reform:"tags"`
+	CreatedBy     string        `json:"created_by" reform:"created_by"`
+	CreatedAt     time.Time     `json:"created_at" reform:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" reform:"updated_at"`
+	Version       int64         `json:"version" required:"false" reform:"version"`
 }
 
 func (f Flag) GetModelName() string {
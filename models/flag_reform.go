@@ -28,15 +28,28 @@ func (v *flagTableType) Name() string {
 // Columns returns a new slice of column names for that view or table in SQL database.
 func (v *flagTableType) Columns() []string {
 	return []string{
+		"id",
+		"tenant_id",
+		"project",
+		"environment",
 		"flag_name",
 		"is_deleted",
 		"is_enabled",
 		"active_from",
+		"active_until",
 		"data",
 		"default_data",
+		"rules",
+		"rollout",
+		"variations",
+		"off_variation",
+		"fallthrough",
+		"prerequisites",
+		"tags",
 		"created_by",
 		"created_at",
 		"updated_at",
+		"version",
 	}
 }
 
@@ -62,15 +75,28 @@ var FlagTable = &flagTableType{
 		SQLSchema: "public",
 		SQLName:   "flags",
 		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "uuid.UUID", Column: "id"},
+			{Name: "TenantID", Type: "string", Column: "tenant_id"},
+			{Name: "Project", Type: "string", Column: "project"},
+			{Name: "Environment", Type: "string", Column: "environment"},
 			{Name: "FlagName", Type: "string", Column: "flag_name"},
 			{Name: "IsDeleted", Type: "bool", Column: "is_deleted"},
 			{Name: "IsEnabled", Type: "bool", Column: "is_enabled"},
 			{Name: "ActiveFrom", Type: "time.Time", Column: "active_from"},
+			{Name: "ActiveUntil", Type: "*time.Time", Column: "active_until"},
 			{Name: "Data", Type: "JSONmap", Column: "data"},
 			{Name: "DefaultData", Type: "JSONmap", Column: "default_data"},
-			{Name: "CreatedBy", Type: "uuid.UUID", Column: "created_by"},
+			{Name: "Rules", Type: "Rules", Column: "rules"},
+			{Name: "Rollout", Type: "*Rollout", Column: "rollout"},
+			{Name: "Variations", Type: "Variations", Column: "variations"},
+			{Name: "OffVariation", Type: "string", Column: "off_variation"},
+			{Name: "Fallthrough", Type: "*Serve", Column: "fallthrough"},
+			{Name: "Prerequisites", Type: "Prerequisites", Column: "prerequisites"},
+			{Name: "Tags", Type: "StringList", Column: "tags"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
 			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
 			{Name: "UpdatedAt", Type: "time.Time", Column: "updated_at"},
+			{Name: "Version", Type: "int64", Column: "version"},
 		},
 		PKFieldIndex: 0,
 	},
@@ -79,16 +105,29 @@ var FlagTable = &flagTableType{
 
 // String returns a string representation of this struct or record.
 func (s Flag) String() string {
-	res := make([]string, 9)
-	res[0] = "FlagName: " + reform.Inspect(s.FlagName, true)
-	res[1] = "IsDeleted: " + reform.Inspect(s.IsDeleted, true)
-	res[2] = "IsEnabled: " + reform.Inspect(s.IsEnabled, true)
-	res[3] = "ActiveFrom: " + reform.Inspect(s.ActiveFrom, true)
-	res[4] = "Data: " + reform.Inspect(s.Data, true)
-	res[5] = "DefaultData: " + reform.Inspect(s.DefaultData, true)
-	res[6] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
-	res[7] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
-	res[8] = "UpdatedAt: " + reform.Inspect(s.UpdatedAt, true)
+	res := make([]string, 22)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "TenantID: " + reform.Inspect(s.TenantID, true)
+	res[2] = "Project: " + reform.Inspect(s.Project, true)
+	res[3] = "Environment: " + reform.Inspect(s.Environment, true)
+	res[4] = "FlagName: " + reform.Inspect(s.FlagName, true)
+	res[5] = "IsDeleted: " + reform.Inspect(s.IsDeleted, true)
+	res[6] = "IsEnabled: " + reform.Inspect(s.IsEnabled, true)
+	res[7] = "ActiveFrom: " + reform.Inspect(s.ActiveFrom, true)
+	res[8] = "ActiveUntil: " + reform.Inspect(s.ActiveUntil, true)
+	res[9] = "Data: " + reform.Inspect(s.Data, true)
+	res[10] = "DefaultData: " + reform.Inspect(s.DefaultData, true)
+	res[11] = "Rules: " + reform.Inspect(s.Rules, true)
+	res[12] = "Rollout: " + reform.Inspect(s.Rollout, true)
+	res[13] = "Variations: " + reform.Inspect(s.Variations, true)
+	res[14] = "OffVariation: " + reform.Inspect(s.OffVariation, true)
+	res[15] = "Fallthrough: " + reform.Inspect(s.Fallthrough, true)
+	res[16] = "Prerequisites: " + reform.Inspect(s.Prerequisites, true)
+	res[17] = "Tags: " + reform.Inspect(s.Tags, true)
+	res[18] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[19] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	res[20] = "UpdatedAt: " + reform.Inspect(s.UpdatedAt, true)
+	res[21] = "Version: " + reform.Inspect(s.Version, true)
 	return strings.Join(res, ", ")
 }
 
@@ -96,15 +135,28 @@ func (s Flag) String() string {
 // Returned interface{} values are never untyped nils.
 func (s *Flag) Values() []interface{} {
 	return []interface{}{
+		s.ID,
+		s.TenantID,
+		s.Project,
+		s.Environment,
 		s.FlagName,
 		s.IsDeleted,
 		s.IsEnabled,
 		s.ActiveFrom,
+		s.ActiveUntil,
 		s.Data,
 		s.DefaultData,
+		s.Rules,
+		s.Rollout,
+		s.Variations,
+		s.OffVariation,
+		s.Fallthrough,
+		s.Prerequisites,
+		s.Tags,
 		s.CreatedBy,
 		s.CreatedAt,
 		s.UpdatedAt,
+		s.Version,
 	}
 }
 
@@ -112,15 +164,28 @@ func (s *Flag) Values() []interface{} {
 // Returned interface{} values are never untyped nils.
 func (s *Flag) Pointers() []interface{} {
 	return []interface{}{
+		&s.ID,
+		&s.TenantID,
+		&s.Project,
+		&s.Environment,
 		&s.FlagName,
 		&s.IsDeleted,
 		&s.IsEnabled,
 		&s.ActiveFrom,
+		&s.ActiveUntil,
 		&s.Data,
 		&s.DefaultData,
+		&s.Rules,
+		&s.Rollout,
+		&s.Variations,
+		&s.OffVariation,
+		&s.Fallthrough,
+		&s.Prerequisites,
+		&s.Tags,
 		&s.CreatedBy,
 		&s.CreatedAt,
 		&s.UpdatedAt,
+		&s.Version,
 	}
 }
 
@@ -137,23 +202,23 @@ func (s *Flag) Table() reform.Table {
 // PKValue returns a value of primary key for that record.
 // Returned interface{} value is never untyped nil.
 func (s *Flag) PKValue() interface{} {
-	return s.FlagName
+	return s.ID
 }
 
 // PKPointer returns a pointer to primary key field for that record.
 // Returned interface{} value is never untyped nil.
 func (s *Flag) PKPointer() interface{} {
-	return &s.FlagName
+	return &s.ID
 }
 
 // HasPK returns true if record has non-zero primary key set, false otherwise.
 func (s *Flag) HasPK() bool {
-	return s.FlagName != FlagTable.z[FlagTable.s.PKFieldIndex]
+	return s.ID != FlagTable.z[FlagTable.s.PKFieldIndex]
 }
 
 // SetPK sets record primary key, if possible.
 //
-// Deprecated: prefer direct field assignment where possible: s.FlagName = pk.
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
 func (s *Flag) SetPK(pk interface{}) {
 	reform.SetPK(s, pk)
 }
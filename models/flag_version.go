@@ -0,0 +1,42 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrModelsFlagSnapshotUnknownType = errors.New("models flag snapshot unknown type")
+
+// FlagSnapshot - состояние флага целиком, хранится в JSONB
+type FlagSnapshot Flag
+
+func (fs FlagSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(fs)
+}
+
+func (fs *FlagSnapshot) Scan(value any) error {
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsFlagSnapshotUnknownType
+	}
+	return json.Unmarshal(data, fs)
+}
+
+//reform:public.flag_versions
+type FlagVersion struct {
+	ID          int64        `json:"-" reform:"id,pk"`
+	Project     string       `json:"project" reform:"project"`
+	Environment string       `json:"environment" reform:"environment"`
+	FlagName    string       `json:"flag_name" reform:"flag_name"`
+	Version     int64        `json:"version" reform:"version"`
+	Snapshot    FlagSnapshot `json:"snapshot" reform:"snapshot"`
+	Actor       string       `json:"actor" reform:"actor"`
+	Comment     string       `json:"comment,omitempty" reform:"comment"`
+	CreatedAt   time.Time    `json:"created_at" reform:"created_at"`
+}
+
+func (fv FlagVersion) GetModelName() string {
+	return fv.FlagName
+}
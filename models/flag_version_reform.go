@@ -0,0 +1,172 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type flagVersionTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *flagVersionTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("flag_versions").
+func (v *flagVersionTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *flagVersionTableType) Columns() []string {
+	return []string{
+		"id",
+		"project",
+		"environment",
+		"flag_name",
+		"version",
+		"snapshot",
+		"actor",
+		"comment",
+		"created_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *flagVersionTableType) NewStruct() reform.Struct {
+	return new(FlagVersion)
+}
+
+// NewRecord makes a new record for that table.
+func (v *flagVersionTableType) NewRecord() reform.Record {
+	return new(FlagVersion)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *flagVersionTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// FlagVersionTable represents flag_versions view or table in SQL database.
+var FlagVersionTable = &flagVersionTableType{
+	s: parse.StructInfo{
+		Type:      "FlagVersion",
+		SQLSchema: "public",
+		SQLName:   "flag_versions",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "int64", Column: "id"},
+			{Name: "Project", Type: "string", Column: "project"},
+			{Name: "Environment", Type: "string", Column: "environment"},
+			{Name: "FlagName", Type: "string", Column: "flag_name"},
+			{Name: "Version", Type: "int64", Column: "version"},
+			{Name: "Snapshot", Type: "FlagSnapshot", Column: "snapshot"},
+			{Name: "Actor", Type: "string", Column: "actor"},
+			{Name: "Comment", Type: "string", Column: "comment"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(FlagVersion).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s FlagVersion) String() string {
+	res := make([]string, 9)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "Project: " + reform.Inspect(s.Project, true)
+	res[2] = "Environment: " + reform.Inspect(s.Environment, true)
+	res[3] = "FlagName: " + reform.Inspect(s.FlagName, true)
+	res[4] = "Version: " + reform.Inspect(s.Version, true)
+	res[5] = "Snapshot: " + reform.Inspect(s.Snapshot, true)
+	res[6] = "Actor: " + reform.Inspect(s.Actor, true)
+	res[7] = "Comment: " + reform.Inspect(s.Comment, true)
+	res[8] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *FlagVersion) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.Project,
+		s.Environment,
+		s.FlagName,
+		s.Version,
+		s.Snapshot,
+		s.Actor,
+		s.Comment,
+		s.CreatedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *FlagVersion) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.Project,
+		&s.Environment,
+		&s.FlagName,
+		&s.Version,
+		&s.Snapshot,
+		&s.Actor,
+		&s.Comment,
+		&s.CreatedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *FlagVersion) View() reform.View {
+	return FlagVersionTable
+}
+
+// Table returns Table object for that record.
+func (s *FlagVersion) Table() reform.Table {
+	return FlagVersionTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *FlagVersion) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *FlagVersion) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *FlagVersion) HasPK() bool {
+	return s.ID != FlagVersionTable.z[FlagVersionTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *FlagVersion) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = FlagVersionTable
+	_ reform.Struct = (*FlagVersion)(nil)
+	_ reform.Table  = FlagVersionTable
+	_ reform.Record = (*FlagVersion)(nil)
+	_ fmt.Stringer  = (*FlagVersion)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&FlagVersionTable.s, new(FlagVersion))
+}
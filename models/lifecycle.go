@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// FlagState - состояние флага по расписанию active_from/active_until
+type FlagState string
+
+const (
+	// FlagStateScheduled - active_from еще не наступил
+	FlagStateScheduled FlagState = "scheduled"
+	// FlagStateActive - флаг в окне [active_from, active_until)
+	FlagStateActive FlagState = "active"
+	// FlagStateExpired - active_until уже прошел
+	FlagStateExpired FlagState = "expired"
+)
+
+// StateAt возвращает состояние флага по расписанию на момент now
+func (f Flag) StateAt(now time.Time) FlagState {
+	switch {
+	case now.Before(f.ActiveFrom):
+		return FlagStateScheduled
+	case f.ActiveUntil != nil && !now.Before(*f.ActiveUntil):
+		return FlagStateExpired
+	}
+	return FlagStateActive
+}
+
+//reform:public.flag_lifecycle_events
+type FlagLifecycleEvent struct {
+	ID          int64     `json:"id" reform:"id,pk"`
+	Project     string    `json:"project" reform:"project"`
+	Environment string    `json:"environment" reform:"environment"`
+	FlagName    string    `json:"flag_name" reform:"flag_name"`
+	State       FlagState `json:"state" reform:"state"`
+	OccurredAt  time.Time `json:"occurred_at" reform:"occurred_at"`
+	RecordedAt  time.Time `json:"recorded_at" reform:"recorded_at"`
+}
+
+func (e FlagLifecycleEvent) GetModelName() string {
+	return e.FlagName
+}
@@ -0,0 +1,162 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type flagLifecycleEventTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *flagLifecycleEventTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("flag_lifecycle_events").
+func (v *flagLifecycleEventTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *flagLifecycleEventTableType) Columns() []string {
+	return []string{
+		"id",
+		"project",
+		"environment",
+		"flag_name",
+		"state",
+		"occurred_at",
+		"recorded_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *flagLifecycleEventTableType) NewStruct() reform.Struct {
+	return new(FlagLifecycleEvent)
+}
+
+// NewRecord makes a new record for that table.
+func (v *flagLifecycleEventTableType) NewRecord() reform.Record {
+	return new(FlagLifecycleEvent)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *flagLifecycleEventTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// FlagLifecycleEventTable represents flag_lifecycle_events view or table in SQL database.
+var FlagLifecycleEventTable = &flagLifecycleEventTableType{
+	s: parse.StructInfo{
+		Type:      "FlagLifecycleEvent",
+		SQLSchema: "public",
+		SQLName:   "flag_lifecycle_events",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "int64", Column: "id"},
+			{Name: "Project", Type: "string", Column: "project"},
+			{Name: "Environment", Type: "string", Column: "environment"},
+			{Name: "FlagName", Type: "string", Column: "flag_name"},
+			{Name: "State", Type: "FlagState", Column: "state"},
+			{Name: "OccurredAt", Type: "time.Time", Column: "occurred_at"},
+			{Name: "RecordedAt", Type: "time.Time", Column: "recorded_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(FlagLifecycleEvent).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s FlagLifecycleEvent) String() string {
+	res := make([]string, 7)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "Project: " + reform.Inspect(s.Project, true)
+	res[2] = "Environment: " + reform.Inspect(s.Environment, true)
+	res[3] = "FlagName: " + reform.Inspect(s.FlagName, true)
+	res[4] = "State: " + reform.Inspect(s.State, true)
+	res[5] = "OccurredAt: " + reform.Inspect(s.OccurredAt, true)
+	res[6] = "RecordedAt: " + reform.Inspect(s.RecordedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *FlagLifecycleEvent) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.Project,
+		s.Environment,
+		s.FlagName,
+		s.State,
+		s.OccurredAt,
+		s.RecordedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *FlagLifecycleEvent) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.Project,
+		&s.Environment,
+		&s.FlagName,
+		&s.State,
+		&s.OccurredAt,
+		&s.RecordedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *FlagLifecycleEvent) View() reform.View {
+	return FlagLifecycleEventTable
+}
+
+// Table returns Table object for that record.
+func (s *FlagLifecycleEvent) Table() reform.Table {
+	return FlagLifecycleEventTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *FlagLifecycleEvent) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *FlagLifecycleEvent) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *FlagLifecycleEvent) HasPK() bool {
+	return s.ID != FlagLifecycleEventTable.z[FlagLifecycleEventTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *FlagLifecycleEvent) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = FlagLifecycleEventTable
+	_ reform.Struct = (*FlagLifecycleEvent)(nil)
+	_ reform.Table  = FlagLifecycleEventTable
+	_ reform.Record = (*FlagLifecycleEvent)(nil)
+	_ fmt.Stringer  = (*FlagLifecycleEvent)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&FlagLifecycleEventTable.s, new(FlagLifecycleEvent))
+}
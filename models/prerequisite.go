@@ -0,0 +1,48 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+var ErrModelsPrerequisitesUnknownType = errors.New("models prerequisites unknown type")
+
+// Prerequisite - флаг, который должен отдавать вариацию Variation, чтобы текущий флаг вычислялся
+type Prerequisite struct {
+	Flag      string `json:"flag" example:"new_cart"`
+	Variation string `json:"variation" example:"data"`
+}
+
+// Prerequisites - список пререквизитов флага, хранится в JSONB
+type Prerequisites []Prerequisite
+
+func (p Prerequisites) Value() (driver.Value, error) {
+	if p == nil {
+		return []byte(`[]`), nil
+	}
+	return json.Marshal(p)
+}
+
+func (p *Prerequisites) Scan(value any) error {
+	if value == nil {
+		*p = nil
+		return nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsPrerequisitesUnknownType
+	}
+
+	return json.Unmarshal(data, p)
+}
+
+// FlagNames - имена флагов-пререквизитов
+func (p Prerequisites) FlagNames() []string {
+	names := make([]string, 0, len(p))
+	for _, prerequisite := range p {
+		names = append(names, prerequisite.Flag)
+	}
+	return names
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+//reform:public.projects
+type Project struct {
+	Name        string    `json:"name" reform:"name,pk"`
+	Description string    `json:"description" reform:"description"`
+	CreatedBy   string    `json:"created_by" reform:"created_by"`
+	CreatedAt   time.Time `json:"created_at" reform:"created_at"`
+}
+
+func (p Project) GetModelName() string {
+	return p.Name
+}
+
+// Environment - окружение проекта: у каждого окружения свое состояние флагов проекта
+//
+//reform:public.environments
+type Environment struct {
+	ID      uuid.UUID `json:"id" reform:"id,pk"`
+	Project string    `json:"project" reform:"project"`
+	Name    string    `json:"name" reform:"name"`
+	// RequiredApprovals - сколько одобрений нужно изменению флага, 0 - изменения применяются сразу
+	RequiredApprovals int       `json:"required_approvals" reform:"required_approvals"`
+	CreatedBy         string    `json:"created_by" reform:"created_by"`
+	CreatedAt         time.Time `json:"created_at" reform:"created_at"`
+}
+
+func (e Environment) GetModelName() string {
+	return e.Name
+}
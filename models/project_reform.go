@@ -0,0 +1,290 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type projectTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *projectTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("projects").
+func (v *projectTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *projectTableType) Columns() []string {
+	return []string{
+		"name",
+		"description",
+		"created_by",
+		"created_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *projectTableType) NewStruct() reform.Struct {
+	return new(Project)
+}
+
+// NewRecord makes a new record for that table.
+func (v *projectTableType) NewRecord() reform.Record {
+	return new(Project)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *projectTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// ProjectTable represents projects view or table in SQL database.
+var ProjectTable = &projectTableType{
+	s: parse.StructInfo{
+		Type:      "Project",
+		SQLSchema: "public",
+		SQLName:   "projects",
+		Fields: []parse.FieldInfo{
+			{Name: "Name", Type: "string", Column: "name"},
+			{Name: "Description", Type: "string", Column: "description"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(Project).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s Project) String() string {
+	res := make([]string, 4)
+	res[0] = "Name: " + reform.Inspect(s.Name, true)
+	res[1] = "Description: " + reform.Inspect(s.Description, true)
+	res[2] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[3] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *Project) Values() []interface{} {
+	return []interface{}{
+		s.Name,
+		s.Description,
+		s.CreatedBy,
+		s.CreatedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *Project) Pointers() []interface{} {
+	return []interface{}{
+		&s.Name,
+		&s.Description,
+		&s.CreatedBy,
+		&s.CreatedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *Project) View() reform.View {
+	return ProjectTable
+}
+
+// Table returns Table object for that record.
+func (s *Project) Table() reform.Table {
+	return ProjectTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *Project) PKValue() interface{} {
+	return s.Name
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *Project) PKPointer() interface{} {
+	return &s.Name
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *Project) HasPK() bool {
+	return s.Name != ProjectTable.z[ProjectTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.Name = pk.
+func (s *Project) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = ProjectTable
+	_ reform.Struct = (*Project)(nil)
+	_ reform.Table  = ProjectTable
+	_ reform.Record = (*Project)(nil)
+	_ fmt.Stringer  = (*Project)(nil)
+)
+
+type environmentTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *environmentTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("environments").
+func (v *environmentTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *environmentTableType) Columns() []string {
+	return []string{
+		"id",
+		"project",
+		"name",
+		"required_approvals",
+		"created_by",
+		"created_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *environmentTableType) NewStruct() reform.Struct {
+	return new(Environment)
+}
+
+// NewRecord makes a new record for that table.
+func (v *environmentTableType) NewRecord() reform.Record {
+	return new(Environment)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *environmentTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// EnvironmentTable represents environments view or table in SQL database.
+var EnvironmentTable = &environmentTableType{
+	s: parse.StructInfo{
+		Type:      "Environment",
+		SQLSchema: "public",
+		SQLName:   "environments",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "uuid.UUID", Column: "id"},
+			{Name: "Project", Type: "string", Column: "project"},
+			{Name: "Name", Type: "string", Column: "name"},
+			{Name: "RequiredApprovals", Type: "int", Column: "required_approvals"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(Environment).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s Environment) String() string {
+	res := make([]string, 6)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "Project: " + reform.Inspect(s.Project, true)
+	res[2] = "Name: " + reform.Inspect(s.Name, true)
+	res[3] = "RequiredApprovals: " + reform.Inspect(s.RequiredApprovals, true)
+	res[4] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[5] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *Environment) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.Project,
+		s.Name,
+		s.RequiredApprovals,
+		s.CreatedBy,
+		s.CreatedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *Environment) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.Project,
+		&s.Name,
+		&s.RequiredApprovals,
+		&s.CreatedBy,
+		&s.CreatedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *Environment) View() reform.View {
+	return EnvironmentTable
+}
+
+// Table returns Table object for that record.
+func (s *Environment) Table() reform.Table {
+	return EnvironmentTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *Environment) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *Environment) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *Environment) HasPK() bool {
+	return s.ID != EnvironmentTable.z[EnvironmentTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *Environment) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = EnvironmentTable
+	_ reform.Struct = (*Environment)(nil)
+	_ reform.Table  = EnvironmentTable
+	_ reform.Record = (*Environment)(nil)
+	_ fmt.Stringer  = (*Environment)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&ProjectTable.s, new(Project))
+	parse.AssertUpToDate(&EnvironmentTable.s, new(Environment))
+}
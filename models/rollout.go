@@ -0,0 +1,36 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+var ErrModelsRolloutUnknownType = errors.New("models rollout unknown type")
+
+// Rollout - процентная раскатка флага по бакетам пользователей
+type Rollout struct {
+	// Percentage - доля пользователей (0-100), которым отдаем Data
+	Percentage float64 `json:"percentage" minimum:"0" maximum:"100" example:"10"`
+	// BucketBy - атрибут контекста для бакетирования, по умолчанию key
+	BucketBy string `json:"bucket_by,omitempty" example:"key"`
+	// Salt - соль флага, генерируется при создании, если не передана
+	Salt string `json:"salt,omitempty"`
+}
+
+func (r Rollout) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *Rollout) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsRolloutUnknownType
+	}
+
+	return json.Unmarshal(data, r)
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ScheduleStatus - статус отложенного изменения флага
+type ScheduleStatus string
+
+const (
+	ScheduleStatusPending   ScheduleStatus = "pending"
+	ScheduleStatusApplied   ScheduleStatus = "applied"
+	ScheduleStatusFailed    ScheduleStatus = "failed"
+	ScheduleStatusCancelled ScheduleStatus = "cancelled"
+)
+
+//reform:public.scheduled_changes
+type ScheduledChange struct {
+	ID          uuid.UUID `json:"id" reform:"id,pk"`
+	Project     string    `json:"project" reform:"project"`
+	Environment string    `json:"environment" reform:"environment"`
+	FlagName    string    `json:"flag_name" reform:"flag_name"`
+	ApplyAt     time.Time `json:"apply_at" reform:"apply_at"`
+	// Changes - JSON Merge Patch (RFC 7386) поверх флага
+	Changes   JSONmap        `json:"changes" reform:"changes"`
+	Comment   string         `json:"comment,omitempty" reform:"comment"`
+	Status    ScheduleStatus `json:"status" reform:"status"`
+	Error     string         `json:"error,omitempty" reform:"error"`
+	CreatedBy string         `json:"created_by" reform:"created_by"`
+	CreatedAt time.Time      `json:"created_at" reform:"created_at"`
+	AppliedAt *time.Time     `json:"applied_at,omitempty" reform:"applied_at"`
+}
+
+func (sc ScheduledChange) GetModelName() string {
+	return sc.ID.String()
+}
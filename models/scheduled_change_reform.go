@@ -0,0 +1,187 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type scheduledChangeTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *scheduledChangeTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("scheduled_changes").
+func (v *scheduledChangeTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *scheduledChangeTableType) Columns() []string {
+	return []string{
+		"id",
+		"project",
+		"environment",
+		"flag_name",
+		"apply_at",
+		"changes",
+		"comment",
+		"status",
+		"error",
+		"created_by",
+		"created_at",
+		"applied_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *scheduledChangeTableType) NewStruct() reform.Struct {
+	return new(ScheduledChange)
+}
+
+// NewRecord makes a new record for that table.
+func (v *scheduledChangeTableType) NewRecord() reform.Record {
+	return new(ScheduledChange)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *scheduledChangeTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// ScheduledChangeTable represents scheduled_changes view or table in SQL database.
+var ScheduledChangeTable = &scheduledChangeTableType{
+	s: parse.StructInfo{
+		Type:      "ScheduledChange",
+		SQLSchema: "public",
+		SQLName:   "scheduled_changes",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "uuid.UUID", Column: "id"},
+			{Name: "Project", Type: "string", Column: "project"},
+			{Name: "Environment", Type: "string", Column: "environment"},
+			{Name: "FlagName", Type: "string", Column: "flag_name"},
+			{Name: "ApplyAt", Type: "time.Time", Column: "apply_at"},
+			{Name: "Changes", Type: "JSONmap", Column: "changes"},
+			{Name: "Comment", Type: "string", Column: "comment"},
+			{Name: "Status", Type: "ScheduleStatus", Column: "status"},
+			{Name: "Error", Type: "string", Column: "error"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+			{Name: "AppliedAt", Type: "*time.Time", Column: "applied_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(ScheduledChange).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s ScheduledChange) String() string {
+	res := make([]string, 12)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "Project: " + reform.Inspect(s.Project, true)
+	res[2] = "Environment: " + reform.Inspect(s.Environment, true)
+	res[3] = "FlagName: " + reform.Inspect(s.FlagName, true)
+	res[4] = "ApplyAt: " + reform.Inspect(s.ApplyAt, true)
+	res[5] = "Changes: " + reform.Inspect(s.Changes, true)
+	res[6] = "Comment: " + reform.Inspect(s.Comment, true)
+	res[7] = "Status: " + reform.Inspect(s.Status, true)
+	res[8] = "Error: " + reform.Inspect(s.Error, true)
+	res[9] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[10] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	res[11] = "AppliedAt: " + reform.Inspect(s.AppliedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *ScheduledChange) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.Project,
+		s.Environment,
+		s.FlagName,
+		s.ApplyAt,
+		s.Changes,
+		s.Comment,
+		s.Status,
+		s.Error,
+		s.CreatedBy,
+		s.CreatedAt,
+		s.AppliedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *ScheduledChange) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.Project,
+		&s.Environment,
+		&s.FlagName,
+		&s.ApplyAt,
+		&s.Changes,
+		&s.Comment,
+		&s.Status,
+		&s.Error,
+		&s.CreatedBy,
+		&s.CreatedAt,
+		&s.AppliedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *ScheduledChange) View() reform.View {
+	return ScheduledChangeTable
+}
+
+// Table returns Table object for that record.
+func (s *ScheduledChange) Table() reform.Table {
+	return ScheduledChangeTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *ScheduledChange) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *ScheduledChange) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *ScheduledChange) HasPK() bool {
+	return s.ID != ScheduledChangeTable.z[ScheduledChangeTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *ScheduledChange) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = ScheduledChangeTable
+	_ reform.Struct = (*ScheduledChange)(nil)
+	_ reform.Table  = ScheduledChangeTable
+	_ reform.Record = (*ScheduledChange)(nil)
+	_ fmt.Stringer  = (*ScheduledChange)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&ScheduledChangeTable.s, new(ScheduledChange))
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrModelsSegmentRulesUnknownType = errors.New("models segment rules unknown type")
+
+//reform:public.segments
+type Segment struct {
+	SegmentName string       `json:"segment_name" reform:"segment_name,pk"`
+	Description string       `json:"description,omitempty" reform:"description"`
+	IsDeleted   bool         `json:"is_deleted" reform:"is_deleted"`
+	Included    StringList   `json:"included" required:"false" reform:"included"`
+	Excluded    StringList   `json:"excluded" required:"false" reform:"excluded"`
+	Rules       SegmentRules `json:"rules" required:"false" reform:"rules"`
+	CreatedBy   string       `json:"created_by" reform:"created_by"`
+	CreatedAt   time.Time    `json:"created_at" reform:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" reform:"updated_at"`
+	Version     int64        `json:"version" required:"false" reform:"version"`
+}
+
+func (s Segment) GetModelName() string {
+	return s.SegmentName
+}
+
+// TableName возвращает имя таблицы
+func (s *Segment) TableName() string {
+	return "segments"
+}
+
+// SegmentRule - правило сегмента: пользователь входит в сегмент, если выполнены все условия
+type SegmentRule struct {
+	Description string   `json:"description,omitempty"`
+	Clauses     []Clause `json:"clauses"`
+}
+
+// SegmentRules - список правил сегмента, хранится в JSONB
+type SegmentRules []SegmentRule
+
+func (sr SegmentRules) Value() (driver.Value, error) {
+	if sr == nil {
+		return []byte(`[]`), nil
+	}
+	return json.Marshal(sr)
+}
+
+func (sr *SegmentRules) Scan(value any) error {
+	if value == nil {
+		*sr = nil
+		return nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsSegmentRulesUnknownType
+	}
+
+	return json.Unmarshal(data, sr)
+}
@@ -0,0 +1,177 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type segmentTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *segmentTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("segments").
+func (v *segmentTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *segmentTableType) Columns() []string {
+	return []string{
+		"segment_name",
+		"description",
+		"is_deleted",
+		"included",
+		"excluded",
+		"rules",
+		"created_by",
+		"created_at",
+		"updated_at",
+		"version",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *segmentTableType) NewStruct() reform.Struct {
+	return new(Segment)
+}
+
+// NewRecord makes a new record for that table.
+func (v *segmentTableType) NewRecord() reform.Record {
+	return new(Segment)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *segmentTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// SegmentTable represents segments view or table in SQL database.
+var SegmentTable = &segmentTableType{
+	s: parse.StructInfo{
+		Type:      "Segment",
+		SQLSchema: "public",
+		SQLName:   "segments",
+		Fields: []parse.FieldInfo{
+			{Name: "SegmentName", Type: "string", Column: "segment_name"},
+			{Name: "Description", Type: "string", Column: "description"},
+			{Name: "IsDeleted", Type: "bool", Column: "is_deleted"},
+			{Name: "Included", Type: "StringList", Column: "included"},
+			{Name: "Excluded", Type: "StringList", Column: "excluded"},
+			{Name: "Rules", Type: "SegmentRules", Column: "rules"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+			{Name: "UpdatedAt", Type: "time.Time", Column: "updated_at"},
+			{Name: "Version", Type: "int64", Column: "version"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(Segment).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s Segment) String() string {
+	res := make([]string, 10)
+	res[0] = "SegmentName: " + reform.Inspect(s.SegmentName, true)
+	res[1] = "Description: " + reform.Inspect(s.Description, true)
+	res[2] = "IsDeleted: " + reform.Inspect(s.IsDeleted, true)
+	res[3] = "Included: " + reform.Inspect(s.Included, true)
+	res[4] = "Excluded: " + reform.Inspect(s.Excluded, true)
+	res[5] = "Rules: " + reform.Inspect(s.Rules, true)
+	res[6] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[7] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	res[8] = "UpdatedAt: " + reform.Inspect(s.UpdatedAt, true)
+	res[9] = "Version: " + reform.Inspect(s.Version, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *Segment) Values() []interface{} {
+	return []interface{}{
+		s.SegmentName,
+		s.Description,
+		s.IsDeleted,
+		s.Included,
+		s.Excluded,
+		s.Rules,
+		s.CreatedBy,
+		s.CreatedAt,
+		s.UpdatedAt,
+		s.Version,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *Segment) Pointers() []interface{} {
+	return []interface{}{
+		&s.SegmentName,
+		&s.Description,
+		&s.IsDeleted,
+		&s.Included,
+		&s.Excluded,
+		&s.Rules,
+		&s.CreatedBy,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+		&s.Version,
+	}
+}
+
+// View returns View object for that struct.
+func (s *Segment) View() reform.View {
+	return SegmentTable
+}
+
+// Table returns Table object for that record.
+func (s *Segment) Table() reform.Table {
+	return SegmentTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *Segment) PKValue() interface{} {
+	return s.SegmentName
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *Segment) PKPointer() interface{} {
+	return &s.SegmentName
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *Segment) HasPK() bool {
+	return s.SegmentName != SegmentTable.z[SegmentTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.SegmentName = pk.
+func (s *Segment) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = SegmentTable
+	_ reform.Struct = (*Segment)(nil)
+	_ reform.Table  = SegmentTable
+	_ reform.Record = (*Segment)(nil)
+	_ fmt.Stringer  = (*Segment)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&SegmentTable.s, new(Segment))
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+var ErrModelsStringListUnknownType = errors.New("models string list unknown type")
+
+// StringList - список строк, хранится в JSONB
+type StringList []string
+
+func (sl StringList) Value() (driver.Value, error) {
+	if sl == nil {
+		return []byte(`[]`), nil
+	}
+	return json.Marshal(sl)
+}
+
+func (sl *StringList) Scan(value any) error {
+	if value == nil {
+		*sl = nil
+		return nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsStringListUnknownType
+	}
+
+	return json.Unmarshal(data, sl)
+}
+
+// Contains проверяет наличие строки в списке
+func (sl StringList) Contains(s string) bool {
+	for _, item := range sl {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+var ErrModelsRulesUnknownType = errors.New("models rules unknown type")
+
+// Operator - оператор условия (clause) правила таргетинга
+type Operator string
+
+const (
+	OperatorIn         Operator = "in"
+	OperatorNotIn      Operator = "not_in"
+	OperatorEquals     Operator = "equals"
+	OperatorStartsWith Operator = "starts_with"
+	OperatorEndsWith   Operator = "ends_with"
+	OperatorContains   Operator = "contains"
+	OperatorRegex      Operator = "regex"
+	OperatorSemverGT   Operator = "semver_gt"
+	OperatorSemverLT   Operator = "semver_lt"
+	OperatorNumberGT   Operator = "number_gt"
+	OperatorNumberLT   Operator = "number_lt"
+	OperatorBefore     Operator = "before"
+	OperatorAfter      Operator = "after"
+
+	// OperatorInSegment - пользователь входит в один из сегментов Values, Attribute не нужен
+	OperatorInSegment Operator = "in_segment"
+	// OperatorNotInSegment - пользователь не входит ни в один из сегментов Values
+	OperatorNotInSegment Operator = "not_in_segment"
+)
+
+// Clause - условие над одним атрибутом контекста
+type Clause struct {
+	Attribute string   `json:"attribute,omitempty" example:"country"`
+	Operator  Operator `json:"operator" enum:"in,not_in,equals,starts_with,ends_with,contains,regex,semver_gt,semver_lt,number_gt,number_lt,before,after,in_segment,not_in_segment"`
+	Values    []any    `json:"values"`
+}
+
+// Rule - правило таргетинга: все условия должны выполниться, тогда отдаем вариацию Serve
+type Rule struct {
+	Description string   `json:"description,omitempty"`
+	Clauses     []Clause `json:"clauses"`
+	Serve       string   `json:"serve" example:"data"`
+}
+
+// Rules - упорядоченный список правил, хранится в JSONB
+type Rules []Rule
+
+func (r Rules) Value() (driver.Value, error) {
+	if r == nil {
+		return []byte(`[]`), nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *Rules) Scan(value any) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsRulesUnknownType
+	}
+
+	return json.Unmarshal(data, r)
+}
+
+// IsSegmentOperator - оператор ссылается на сегменты
+func (op Operator) IsSegmentOperator() bool {
+	return op == OperatorInSegment || op == OperatorNotInSegment
+}
+
+// SegmentNames - уникальные имена сегментов, на которые ссылаются правила
+func (r Rules) SegmentNames() []string {
+	uniq := make(map[string]struct{})
+	names := []string{}
+	for _, rule := range r {
+		for _, clause := range rule.Clauses {
+			if !clause.Operator.IsSegmentOperator() {
+				continue
+			}
+			for _, value := range clause.Values {
+				name, ok := value.(string)
+				if !ok {
+					continue
+				}
+				if _, ok := uniq[name]; !ok {
+					uniq[name] = struct{}{}
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
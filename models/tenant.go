@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Tenant - арендатор: бизнес-юнит, данные которого изолированы от остальных политиками RLS
+//
+//reform:public.tenants
+type Tenant struct {
+	ID        string    `json:"id" reform:"id,pk"`
+	Name      string    `json:"name" reform:"name"`
+	CreatedBy string    `json:"created_by" reform:"created_by"`
+	CreatedAt time.Time `json:"created_at" reform:"created_at"`
+}
+
+func (t Tenant) GetModelName() string {
+	return t.ID
+}
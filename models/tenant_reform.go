@@ -0,0 +1,147 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type tenantTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *tenantTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("tenants").
+func (v *tenantTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *tenantTableType) Columns() []string {
+	return []string{
+		"id",
+		"name",
+		"created_by",
+		"created_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *tenantTableType) NewStruct() reform.Struct {
+	return new(Tenant)
+}
+
+// NewRecord makes a new record for that table.
+func (v *tenantTableType) NewRecord() reform.Record {
+	return new(Tenant)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *tenantTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// TenantTable represents tenants view or table in SQL database.
+var TenantTable = &tenantTableType{
+	s: parse.StructInfo{
+		Type:      "Tenant",
+		SQLSchema: "public",
+		SQLName:   "tenants",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "string", Column: "id"},
+			{Name: "Name", Type: "string", Column: "name"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(Tenant).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s Tenant) String() string {
+	res := make([]string, 4)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "Name: " + reform.Inspect(s.Name, true)
+	res[2] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[3] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *Tenant) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.Name,
+		s.CreatedBy,
+		s.CreatedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *Tenant) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.Name,
+		&s.CreatedBy,
+		&s.CreatedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *Tenant) View() reform.View {
+	return TenantTable
+}
+
+// Table returns Table object for that record.
+func (s *Tenant) Table() reform.Table {
+	return TenantTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *Tenant) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *Tenant) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *Tenant) HasPK() bool {
+	return s.ID != TenantTable.z[TenantTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *Tenant) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = TenantTable
+	_ reform.Struct = (*Tenant)(nil)
+	_ reform.Table  = TenantTable
+	_ reform.Record = (*Tenant)(nil)
+	_ fmt.Stringer  = (*Tenant)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&TenantTable.s, new(Tenant))
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+//reform:public.users
+type User struct {
+	ID uuid.UUID `json:"id" reform:"id,pk"`
+	// TenantID - арендатор пользователя, subject уникален среди всех арендаторов
+	TenantID string `json:"tenant_id" reform:"tenant_id"`
+	// Subject - claim sub из JWT
+	Subject    string    `json:"subject" reform:"subject"`
+	Email      string    `json:"email" reform:"email"`
+	Name       string    `json:"name" reform:"name"`
+	IsDisabled bool      `json:"is_disabled" reform:"is_disabled"`
+	CreatedBy  string    `json:"created_by" reform:"created_by"`
+	CreatedAt  time.Time `json:"created_at" reform:"created_at"`
+}
+
+func (u User) GetModelName() string {
+	return u.Subject
+}
+
+// RoleBinding - роль пользователя, пустые Project и Environment - во всех проектах и окружениях
+//
+//reform:public.role_bindings
+type RoleBinding struct {
+	ID          uuid.UUID `json:"id" reform:"id,pk"`
+	UserID      uuid.UUID `json:"user_id" reform:"user_id"`
+	Role        string    `json:"role" reform:"role"`
+	Project     string    `json:"project" reform:"project"`
+	Environment string    `json:"environment" reform:"environment"`
+	CreatedBy   string    `json:"created_by" reform:"created_by"`
+	CreatedAt   time.Time `json:"created_at" reform:"created_at"`
+}
+
+func (b RoleBinding) GetModelName() string {
+	return b.Role
+}
+
+// UserAccess - пользователь с ролями
+type UserAccess struct {
+	User         User          `json:"user"`
+	RoleBindings []RoleBinding `json:"role_bindings"`
+}
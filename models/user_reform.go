@@ -0,0 +1,315 @@
+// Code generated by gopkg.in/reform.v1. DO NOT EDIT.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/parse"
+)
+
+type userTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *userTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("users").
+func (v *userTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *userTableType) Columns() []string {
+	return []string{
+		"id",
+		"tenant_id",
+		"subject",
+		"email",
+		"name",
+		"is_disabled",
+		"created_by",
+		"created_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *userTableType) NewStruct() reform.Struct {
+	return new(User)
+}
+
+// NewRecord makes a new record for that table.
+func (v *userTableType) NewRecord() reform.Record {
+	return new(User)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *userTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// UserTable represents users view or table in SQL database.
+var UserTable = &userTableType{
+	s: parse.StructInfo{
+		Type:      "User",
+		SQLSchema: "public",
+		SQLName:   "users",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "uuid.UUID", Column: "id"},
+			{Name: "TenantID", Type: "string", Column: "tenant_id"},
+			{Name: "Subject", Type: "string", Column: "subject"},
+			{Name: "Email", Type: "string", Column: "email"},
+			{Name: "Name", Type: "string", Column: "name"},
+			{Name: "IsDisabled", Type: "bool", Column: "is_disabled"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(User).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s User) String() string {
+	res := make([]string, 8)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "TenantID: " + reform.Inspect(s.TenantID, true)
+	res[2] = "Subject: " + reform.Inspect(s.Subject, true)
+	res[3] = "Email: " + reform.Inspect(s.Email, true)
+	res[4] = "Name: " + reform.Inspect(s.Name, true)
+	res[5] = "IsDisabled: " + reform.Inspect(s.IsDisabled, true)
+	res[6] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[7] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *User) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.TenantID,
+		s.Subject,
+		s.Email,
+		s.Name,
+		s.IsDisabled,
+		s.CreatedBy,
+		s.CreatedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *User) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.TenantID,
+		&s.Subject,
+		&s.Email,
+		&s.Name,
+		&s.IsDisabled,
+		&s.CreatedBy,
+		&s.CreatedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *User) View() reform.View {
+	return UserTable
+}
+
+// Table returns Table object for that record.
+func (s *User) Table() reform.Table {
+	return UserTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *User) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *User) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *User) HasPK() bool {
+	return s.ID != UserTable.z[UserTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *User) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = UserTable
+	_ reform.Struct = (*User)(nil)
+	_ reform.Table  = UserTable
+	_ reform.Record = (*User)(nil)
+	_ fmt.Stringer  = (*User)(nil)
+)
+
+type roleBindingTableType struct {
+	s parse.StructInfo
+	z []interface{}
+}
+
+// Schema returns a schema name in SQL database ("public").
+func (v *roleBindingTableType) Schema() string {
+	return v.s.SQLSchema
+}
+
+// Name returns a view or table name in SQL database ("role_bindings").
+func (v *roleBindingTableType) Name() string {
+	return v.s.SQLName
+}
+
+// Columns returns a new slice of column names for that view or table in SQL database.
+func (v *roleBindingTableType) Columns() []string {
+	return []string{
+		"id",
+		"user_id",
+		"role",
+		"project",
+		"environment",
+		"created_by",
+		"created_at",
+	}
+}
+
+// NewStruct makes a new struct for that view or table.
+func (v *roleBindingTableType) NewStruct() reform.Struct {
+	return new(RoleBinding)
+}
+
+// NewRecord makes a new record for that table.
+func (v *roleBindingTableType) NewRecord() reform.Record {
+	return new(RoleBinding)
+}
+
+// PKColumnIndex returns an index of primary key column for that table in SQL database.
+func (v *roleBindingTableType) PKColumnIndex() uint {
+	return uint(v.s.PKFieldIndex)
+}
+
+// RoleBindingTable represents role_bindings view or table in SQL database.
+var RoleBindingTable = &roleBindingTableType{
+	s: parse.StructInfo{
+		Type:      "RoleBinding",
+		SQLSchema: "public",
+		SQLName:   "role_bindings",
+		Fields: []parse.FieldInfo{
+			{Name: "ID", Type: "uuid.UUID", Column: "id"},
+			{Name: "UserID", Type: "uuid.UUID", Column: "user_id"},
+			{Name: "Role", Type: "string", Column: "role"},
+			{Name: "Project", Type: "string", Column: "project"},
+			{Name: "Environment", Type: "string", Column: "environment"},
+			{Name: "CreatedBy", Type: "string", Column: "created_by"},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at"},
+		},
+		PKFieldIndex: 0,
+	},
+	z: new(RoleBinding).Values(),
+}
+
+// String returns a string representation of this struct or record.
+func (s RoleBinding) String() string {
+	res := make([]string, 7)
+	res[0] = "ID: " + reform.Inspect(s.ID, true)
+	res[1] = "UserID: " + reform.Inspect(s.UserID, true)
+	res[2] = "Role: " + reform.Inspect(s.Role, true)
+	res[3] = "Project: " + reform.Inspect(s.Project, true)
+	res[4] = "Environment: " + reform.Inspect(s.Environment, true)
+	res[5] = "CreatedBy: " + reform.Inspect(s.CreatedBy, true)
+	res[6] = "CreatedAt: " + reform.Inspect(s.CreatedAt, true)
+	return strings.Join(res, ", ")
+}
+
+// Values returns a slice of struct or record field values.
+// Returned interface{} values are never untyped nils.
+func (s *RoleBinding) Values() []interface{} {
+	return []interface{}{
+		s.ID,
+		s.UserID,
+		s.Role,
+		s.Project,
+		s.Environment,
+		s.CreatedBy,
+		s.CreatedAt,
+	}
+}
+
+// Pointers returns a slice of pointers to struct or record fields.
+// Returned interface{} values are never untyped nils.
+func (s *RoleBinding) Pointers() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.UserID,
+		&s.Role,
+		&s.Project,
+		&s.Environment,
+		&s.CreatedBy,
+		&s.CreatedAt,
+	}
+}
+
+// View returns View object for that struct.
+func (s *RoleBinding) View() reform.View {
+	return RoleBindingTable
+}
+
+// Table returns Table object for that record.
+func (s *RoleBinding) Table() reform.Table {
+	return RoleBindingTable
+}
+
+// PKValue returns a value of primary key for that record.
+// Returned interface{} value is never untyped nil.
+func (s *RoleBinding) PKValue() interface{} {
+	return s.ID
+}
+
+// PKPointer returns a pointer to primary key field for that record.
+// Returned interface{} value is never untyped nil.
+func (s *RoleBinding) PKPointer() interface{} {
+	return &s.ID
+}
+
+// HasPK returns true if record has non-zero primary key set, false otherwise.
+func (s *RoleBinding) HasPK() bool {
+	return s.ID != RoleBindingTable.z[RoleBindingTable.s.PKFieldIndex]
+}
+
+// SetPK sets record primary key, if possible.
+//
+// Deprecated: prefer direct field assignment where possible: s.ID = pk.
+func (s *RoleBinding) SetPK(pk interface{}) {
+	reform.SetPK(s, pk)
+}
+
+// check interfaces
+var (
+	_ reform.View   = RoleBindingTable
+	_ reform.Struct = (*RoleBinding)(nil)
+	_ reform.Table  = RoleBindingTable
+	_ reform.Record = (*RoleBinding)(nil)
+	_ fmt.Stringer  = (*RoleBinding)(nil)
+)
+
+func init() {
+	parse.AssertUpToDate(&UserTable.s, new(User))
+	parse.AssertUpToDate(&RoleBindingTable.s, new(RoleBinding))
+}
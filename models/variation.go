@@ -0,0 +1,111 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrModelsVariationsUnknownType = errors.New("models variations unknown type")
+
+	ErrModelsServeUnknownType = errors.New("models serve unknown type")
+)
+
+// неявные вариации бинарного флага, доступны всегда, даже если заданы Variations
+const (
+	VariationData        = "data"
+	VariationDefaultData = "default_data"
+)
+
+// Variation - именованная вариация флага со своим payload
+type Variation struct {
+	Name        string  `json:"name" example:"control"`
+	Value       JSONmap `json:"value"`
+	Description string  `json:"description,omitempty"`
+}
+
+// Variations - список вариаций флага, хранится в JSONB
+type Variations []Variation
+
+func (v Variations) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte(`[]`), nil
+	}
+	return json.Marshal(v)
+}
+
+func (v *Variations) Scan(value any) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsVariationsUnknownType
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// WeightedVariation - доля (в процентах) пользователей, которым отдаем вариацию
+type WeightedVariation struct {
+	Variation string  `json:"variation" example:"control"`
+	Weight    float64 `json:"weight" minimum:"0" maximum:"100" example:"33"`
+}
+
+// Serve - что отдать: конкретную вариацию либо распределение по весам (сумма = 100)
+type Serve struct {
+	Variation string              `json:"variation,omitempty" example:"data"`
+	Split     []WeightedVariation `json:"split,omitempty"`
+}
+
+func (s Serve) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *Serve) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrModelsServeUnknownType
+	}
+
+	return json.Unmarshal(data, s)
+}
+
+// FindVariation ищет вариацию по имени среди Variations, затем среди неявных data/default_data
+func (f Flag) FindVariation(name string) (Variation, bool) {
+	for _, variation := range f.Variations {
+		if variation.Name == name {
+			return variation, true
+		}
+	}
+	switch name {
+	case VariationData:
+		return Variation{Name: VariationData, Value: f.Data}, true
+	case VariationDefaultData:
+		return Variation{Name: VariationDefaultData, Value: f.DefaultData}, true
+	}
+	return Variation{}, false
+}
+
+// OffVariationName - вариация для выключенного флага, по умолчанию default_data
+func (f Flag) OffVariationName() string {
+	if f.OffVariation != "" {
+		return f.OffVariation
+	}
+	return VariationDefaultData
+}
+
+// FallthroughServe - что отдаем, если ни одно правило не сработало, по умолчанию data
+func (f Flag) FallthroughServe() Serve {
+	if f.Fallthrough != nil && (f.Fallthrough.Variation != "" || len(f.Fallthrough.Split) > 0) {
+		return *f.Fallthrough
+	}
+	return Serve{Variation: VariationData}
+}
@@ -0,0 +1,276 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrPatchInvalidOperation = errors.New("invalid json patch operation")
+
+	ErrPatchPathNotFound = errors.New("path not found")
+
+	ErrPatchTestFailed = errors.New("test operation failed")
+)
+
+// Operation - операция JSON Patch, Value - nil, если поля value нет, и "null", если value: null
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch накладывает JSON Patch (RFC 6902) на документ: операции add, remove, replace,
+// move, copy и test выполняются по порядку, ошибка любой операции отменяет весь патч
+func JSONPatch(doc, jsonPatch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPatchInvalidDocument, err)
+	}
+	var operations []Operation
+	if err := json.Unmarshal(jsonPatch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPatchInvalidDocument, err)
+	}
+	for i, operation := range operations {
+		var err error
+		target, err = applyOperation(target, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func applyOperation(doc any, operation Operation) (any, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%w: value is required", ErrPatchInvalidOperation)
+		}
+		var value any
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPatchInvalidOperation, err)
+		}
+		switch operation.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrPatchTestFailed
+		}
+		return doc, nil
+	case "remove":
+		return remove(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "copy" {
+			// копия не должна делить вложенные map и срезы с исходным значением
+			value, err = deepCopy(value)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		}
+		if operation.Path == operation.From {
+			return doc, nil
+		}
+		if strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move into own child", ErrPatchInvalidOperation)
+		}
+		doc, err = remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+	return nil, fmt.Errorf("%w: unknown op {%s}", ErrPatchInvalidOperation, operation.Op)
+}
+
+// parsePointer разбирает JSON Pointer (RFC 6901), "" - весь документ
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path {%s} must start with /", ErrPatchInvalidOperation, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, token)
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, token)
+		}
+	}
+	return doc, nil
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent any, key string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[key] = value
+			return node, nil
+		case []any:
+			if key == "-" {
+				return append(node, value), nil
+			}
+			i, err := arrayIndex(key, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, key)
+	})
+}
+
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove whole document", ErrPatchInvalidOperation)
+	}
+	return update(doc, path, func(parent any, key string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[key]; !ok {
+				return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, key)
+			}
+			delete(node, key)
+			return node, nil
+		case []any:
+			i, err := arrayIndex(key, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, key)
+	})
+}
+
+func replace(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent any, key string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[key]; !ok {
+				return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, key)
+			}
+			node[key] = value
+			return node, nil
+		case []any:
+			i, err := arrayIndex(key, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, key)
+	})
+}
+
+// update спускается по path до родителя последнего токена и заменяет родителя результатом change,
+// срезы при вставке и удалении меняют длину, поэтому родителя перезаписываем на каждом уровне
+func update(doc any, path []string, change func(parent any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, path[0])
+		}
+		newChild, err := update(child, path[1:], change)
+		if err != nil {
+			return nil, err
+		}
+		node[path[0]] = newChild
+		return node, nil
+	case []any:
+		i, err := arrayIndex(path[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		newChild, err := update(node[i], path[1:], change)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = newChild
+		return node, nil
+	}
+	return nil, fmt.Errorf("%w: {%s}", ErrPatchPathNotFound, path[0])
+}
+
+// arrayIndex - индекс массива из токена, без ведущих нулей и не больше maxIndex
+func arrayIndex(token string, maxIndex int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: invalid array index {%s}", ErrPatchInvalidOperation, token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > maxIndex {
+		return 0, fmt.Errorf("%w: array index {%s}", ErrPatchPathNotFound, token)
+	}
+	return i, nil
+}
+
+func deepCopy(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var valueCopy any
+	if err := json.Unmarshal(data, &valueCopy); err != nil {
+		return nil, err
+	}
+	return valueCopy, nil
+}
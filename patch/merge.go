@@ -0,0 +1,44 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrPatchInvalidDocument = errors.New("invalid json document")
+
+// MergePatch накладывает JSON Merge Patch (RFC 7386) на документ:
+// объекты сливаются рекурсивно, null удаляет поле, остальное заменяется целиком
+func MergePatch(doc, mergePatch []byte) ([]byte, error) {
+	var target any
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &target); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPatchInvalidDocument, err)
+		}
+	}
+	var p any
+	if err := json.Unmarshal(mergePatch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPatchInvalidDocument, err)
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, p any) any {
+	patchObject, ok := p.(map[string]any)
+	if !ok {
+		return p
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any, len(patchObject))
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
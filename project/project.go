@@ -0,0 +1,48 @@
+// Package project передает через context проект и окружение запроса,
+// в них работают запросы к флагам и кэши флагов
+package project
+
+import "context"
+
+// проект и окружение старых путей без /projects/{project}/environments/{environment}
+const (
+	DefaultProject     = "default"
+	DefaultEnvironment = "default"
+)
+
+// Environment - окружение Name проекта Project
+type Environment struct {
+	Project string
+	Name    string
+}
+
+// Default - окружение старых путей
+func Default() Environment {
+	return Environment{Project: DefaultProject, Name: DefaultEnvironment}
+}
+
+// String - "<project>/<environment>", имена проектов и окружений без "/"
+func (e Environment) String() string {
+	return e.Project + "/" + e.Name
+}
+
+// Key - ключ кэша для имени name в окружении
+func (e Environment) Key(name string) string {
+	return e.String() + "/" + name
+}
+
+type environmentKey struct{}
+
+// WithEnvironment сохраняет окружение запроса в ctx
+func WithEnvironment(ctx context.Context, environment Environment) context.Context {
+	return context.WithValue(ctx, environmentKey{}, environment)
+}
+
+// EnvironmentFromContext возвращает окружение запроса, без окружения - Default
+func EnvironmentFromContext(ctx context.Context) Environment {
+	environment, ok := ctx.Value(environmentKey{}).(Environment)
+	if !ok {
+		return Default()
+	}
+	return environment
+}
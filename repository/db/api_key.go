@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"feature-flag-2/models"
+	"feature-flag-2/tenant"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"gopkg.in/reform.v1"
+	"time"
+)
+
+type RepoAPIKeyDB struct {
+	db *reform.DB
+	// cache - ключи по хэшу, проверка ключа идет на каждый запрос
+	cache *expirable.LRU[string, models.APIKey]
+}
+
+func NewRepoAPIKeyDB(
+	db *reform.DB,
+	cache *expirable.LRU[string, models.APIKey],
+) *RepoAPIKeyDB {
+	return &RepoAPIKeyDB{db: db, cache: cache}
+}
+
+// CreateAPIKey сохраняет новый ключ арендатора из ctx
+func (r *RepoAPIKeyDB) CreateAPIKey(ctx context.Context, apiKey models.APIKey) (models.APIKey, error) {
+	apiKey.TenantID = tenant.FromContext(ctx)
+	exec := func(tx *reform.TX) error {
+		return tx.WithContext(ctx).Insert(&apiKey)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return apiKey, err
+	}
+	return apiKey, nil
+}
+
+// EnsureAPIKey сохраняет ключ арендатора из ctx, если ключа с таким хэшем еще нет
+// (ключ из конфигурации при старте)
+func (r *RepoAPIKeyDB) EnsureAPIKey(ctx context.Context, apiKey models.APIKey) error {
+	exec := func(tx *reform.TX) error {
+		_, err := tx.WithContext(ctx).Exec(`INSERT INTO public.api_keys (
+	id,
+	name,
+	prefix,
+	key_hash,
+	scopes,
+	created_by,
+	created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (key_hash) DO NOTHING`,
+			apiKey.ID,
+			apiKey.Name,
+			apiKey.Prefix,
+			apiKey.KeyHash,
+			apiKey.Scopes,
+			apiKey.CreatedBy,
+			apiKey.CreatedAt,
+		)
+		return err
+	}
+	return inTenant(ctx, r.db, exec)
+}
+
+// GetAPIKeyByHash возвращает ключ любого арендатора по хэшу, отозванные ключи тоже:
+// по ключу определяется арендатор запроса
+func (r *RepoAPIKeyDB) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	apiKey, ok := r.cache.Get(keyHash)
+	if ok {
+		return apiKey, nil
+	}
+	exec := func(tx *reform.TX) error {
+		if err := resolveCredentials(ctx, tx); err != nil {
+			return err
+		}
+		return tx.WithContext(ctx).SelectOneTo(&apiKey, `WHERE key_hash = $1`, keyHash)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return apiKey, err
+	}
+	r.cache.Add(keyHash, apiKey)
+	return apiKey, nil
+}
+
+// ListOfAPIKeys возвращает все ключи арендатора из ctx, включая отозванные
+func (r *RepoAPIKeyDB) ListOfAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	var apiKeys []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		apiKeys, err = tx.WithContext(ctx).SelectAllFrom(models.APIKeyTable, `ORDER BY created_at`)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	return models.ConvertReformStructToModel[models.APIKey](apiKeys)
+}
+
+// RevokeAPIKey отзывает ключ, уже отозванный ключ - sql.ErrNoRows
+func (r *RepoAPIKeyDB) RevokeAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	var apiKey models.APIKey
+	exec := func(tx *reform.TX) error {
+		if err := tx.WithContext(ctx).SelectOneTo(
+			&apiKey,
+			`WHERE id = $1 AND revoked_at IS NULL FOR UPDATE`,
+			id,
+		); err != nil {
+			return err
+		}
+		revokedAt := time.Now().UTC()
+		apiKey.RevokedAt = &revokedAt
+		if err := tx.WithContext(ctx).Update(&apiKey); err != nil {
+			return err
+		}
+		return notifyChanges(ctx, tx.Querier, changeKindAPIKey, apiKey.KeyHash)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return apiKey, err
+	}
+	r.cache.Remove(apiKey.KeyHash)
+	return apiKey, nil
+}
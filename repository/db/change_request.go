@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"fmt"
+	"gopkg.in/reform.v1"
+	"time"
+)
+
+var ErrDBOutdated = errors.New("is outdated")
+
+// DecideFunc под блокировкой запроса на изменение меняет его статус и одобрения,
+// ошибка отменяет решение
+type DecideFunc func(changeRequest *models.ChangeRequest) error
+
+type RepoChangeRequestDB struct {
+	db    *reform.DB
+	flags *RepoFlagDB
+}
+
+func NewRepoChangeRequestDB(db *reform.DB, flags *RepoFlagDB) *RepoChangeRequestDB {
+	return &RepoChangeRequestDB{db: db, flags: flags}
+}
+
+// OpenChangeRequest сохраняет запрос на изменение флага окружения из ctx. Флаг блокируется,
+// precondition (может быть nil) проверяет его как при записи, запрос основан на его текущей версии.
+// У предложенного флага идентификатор, автор, дата создания и версия - от текущего флага,
+// запрос на удаление предлагает текущий флаг удаленным
+func (r *RepoChangeRequestDB) OpenChangeRequest(
+	ctx context.Context,
+	changeRequest models.ChangeRequest,
+	precondition PreconditionFunc,
+) (models.ChangeRequest, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	changeRequest.Project = environment.Project
+	changeRequest.Environment = environment.Name
+	exec := func(tx *reform.TX) error {
+		var flag models.Flag
+		if err := selectFlagForUpdate(ctx, tx, &flag, environment, changeRequest.FlagName); err != nil {
+			return err
+		}
+		if flag.IsDeleted {
+			if changeRequest.Kind == models.ChangeRequestKindDelete {
+				return ErrDBIsDeleted
+			}
+			return sql.ErrNoRows
+		}
+		if precondition != nil {
+			if err := precondition(flag); err != nil {
+				return err
+			}
+		}
+		changeRequest.BaseVersion = flag.Version
+		proposed := models.Flag(changeRequest.Proposed)
+		if changeRequest.Kind == models.ChangeRequestKindDelete {
+			proposed = flag
+			proposed.IsDeleted = true
+		}
+		proposed.ID = flag.ID
+		proposed.Project = flag.Project
+		proposed.Environment = flag.Environment
+		proposed.CreatedBy = flag.CreatedBy
+		proposed.CreatedAt = flag.CreatedAt
+		proposed.Version = flag.Version
+		changeRequest.Proposed = models.FlagSnapshot(proposed)
+		return tx.WithContext(ctx).Insert(&changeRequest)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return changeRequest, err
+	}
+	return changeRequest, nil
+}
+
+// GetChangeRequest возвращает запрос на изменение окружения из ctx по id
+func (r *RepoChangeRequestDB) GetChangeRequest(ctx context.Context, id string) (models.ChangeRequest, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	var changeRequest models.ChangeRequest
+	exec := func(tx *reform.TX) error {
+		return tx.WithContext(ctx).SelectOneTo(
+			&changeRequest,
+			`WHERE id = $1 AND project = $2 AND environment = $3`,
+			id,
+			environment.Project,
+			environment.Name,
+		)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return changeRequest, err
+	}
+	return changeRequest, nil
+}
+
+// ListOfChangeRequests возвращает запросы на изменение окружения из ctx от новых к старым,
+// если status не пустой - только запросы в этом статусе
+func (r *RepoChangeRequestDB) ListOfChangeRequests(
+	ctx context.Context,
+	status models.ChangeRequestStatus,
+) ([]models.ChangeRequest, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	tail := `WHERE project = $1 AND environment = $2 ORDER BY created_at DESC`
+	args := []any{environment.Project, environment.Name}
+	if status != "" {
+		tail = `WHERE project = $1 AND environment = $2 AND status = $3 ORDER BY created_at DESC`
+		args = append(args, status)
+	}
+	var changeRequests []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		changeRequests, err = tx.WithContext(ctx).SelectAllFrom(models.ChangeRequestTable, tail, args...)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	return models.ConvertReformStructToModel[models.ChangeRequest](changeRequests)
+}
+
+// DecideChangeRequest блокирует открытый запрос на изменение окружения из ctx и сохраняет решение decide,
+// закрытый запрос - ErrDBNotPending
+func (r *RepoChangeRequestDB) DecideChangeRequest(
+	ctx context.Context,
+	id string,
+	decide DecideFunc,
+) (models.ChangeRequest, error) {
+	var changeRequest models.ChangeRequest
+	exec := func(tx *reform.TX) error {
+		if err := selectOpenChangeRequestForUpdate(ctx, tx, &changeRequest, id); err != nil {
+			return err
+		}
+		if err := decide(&changeRequest); err != nil {
+			return err
+		}
+		return tx.WithContext(ctx).Update(&changeRequest)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return changeRequest, err
+	}
+	return changeRequest, nil
+}
+
+// ApplyChangeRequest применяет запрос на изменение окружения из ctx одной транзакцией: под блокировкой
+// запроса decide проверяет его и закрывает, флаг записывается или удаляется, если его версия все еще
+// BaseVersion - иначе ErrDBOutdated. Кэш флагов сбрасывается после commit
+func (r *RepoChangeRequestDB) ApplyChangeRequest(
+	ctx context.Context,
+	id string,
+	decide DecideFunc,
+) (models.ChangeRequest, error) {
+	var changeRequest models.ChangeRequest
+	var changedFlags []models.Flag
+	exec := func(tx *reform.TX) error {
+		if err := selectOpenChangeRequestForUpdate(ctx, tx, &changeRequest, id); err != nil {
+			return err
+		}
+		if err := decide(&changeRequest); err != nil {
+			return err
+		}
+		baseVersion := func(flag models.Flag) error {
+			if flag.Version != changeRequest.BaseVersion {
+				return fmt.Errorf("%w: flag version - {%d}, base version - {%d}",
+					ErrDBOutdated, flag.Version, changeRequest.BaseVersion)
+			}
+			return nil
+		}
+		change := audit.FromContext(ctx)
+		change.Comment = fmt.Sprintf("change request %s", changeRequest.ID)
+		flagCtx := audit.WithChange(ctx, change)
+		var err error
+		switch changeRequest.Kind {
+		case models.ChangeRequestKindDelete:
+			changedFlags, err = r.flags.deleteFlag(flagCtx, tx, changeRequest.FlagName, baseVersion)
+		default:
+			proposed := models.Flag(changeRequest.Proposed)
+			proposed.UpdatedAt = time.Now().UTC()
+			changedFlags, err = r.flags.updateFlag(flagCtx, tx, proposed, baseVersion)
+		}
+		if err != nil {
+			return err
+		}
+		// запись флага закрыла запрос как устаревший вместе с остальными, здесь он применен
+		return tx.WithContext(ctx).Update(&changeRequest)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return changeRequest, err
+	}
+	r.flags.flagsChanged(changedFlags...)
+	return changeRequest, nil
+}
+
+func selectOpenChangeRequestForUpdate(
+	ctx context.Context,
+	tx *reform.TX,
+	changeRequest *models.ChangeRequest,
+	id string,
+) error {
+	environment := project.EnvironmentFromContext(ctx)
+	if err := tx.WithContext(ctx).SelectOneTo(
+		changeRequest,
+		`WHERE id = $1 AND project = $2 AND environment = $3 FOR UPDATE`,
+		id,
+		environment.Project,
+		environment.Name,
+	); err != nil {
+		return err
+	}
+	if !changeRequest.Status.IsOpen() {
+		return fmt.Errorf("%w: status - {%s}", ErrDBNotPending, changeRequest.Status)
+	}
+	return nil
+}
+
+// invalidateChangeRequests закрывает открытые запросы на изменение флага, основанные не на его
+// новой версии: предложение сделано для состояния флага, которого уже нет
+func invalidateChangeRequests(ctx context.Context, q *reform.Querier, flag models.Flag) error {
+	_, err := q.WithContext(ctx).Exec(
+		`UPDATE public.change_requests SET status = $1, resolved_at = $2, reason = $3
+WHERE project = $4 AND environment = $5 AND flag_name = $6 AND status IN ($7, $8) AND base_version <> $9`,
+		models.ChangeRequestStatusInvalidated,
+		time.Now().UTC(),
+		fmt.Sprintf("flag changed to version %d", flag.Version),
+		flag.Project,
+		flag.Environment,
+		flag.FlagName,
+		models.ChangeRequestStatusPending,
+		models.ChangeRequestStatusApproved,
+		flag.Version,
+	)
+	return err
+}
@@ -3,10 +3,19 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/tenant"
+	"fmt"
+	"github.com/google/uuid"
 	"github.com/hashicorp/golang-lru/v2/expirable"
 	"gopkg.in/reform.v1"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -15,147 +24,547 @@ var (
 	ErrDBNotFound = errors.New("not found")
 
 	ErrDBIsDeleted = errors.New("is deleted")
+
+	ErrDBHasDependents = errors.New("has dependents")
 )
 
+// PreconditionFunc проверяет текущее состояние флага под блокировкой строки перед изменением,
+// ошибка отменяет изменение (например, If-Match не совпал с версией флага)
+type PreconditionFunc func(flag models.Flag) error
+
 type RepoFlagDB struct {
-	db    *reform.DB
-	cache *expirable.LRU[string, models.Flag]
+	db      *reform.DB
+	cache   *expirable.LRU[string, models.Flag]
+	onEvict []func(flagNames ...string)
+	// onChange - подписчики на изменения флагов после commit
+	onChange []func(flag models.Flag)
+
+	// snapshots - все флаги окружения для массового вычисления, сбрасываются в EvictFlags и по snapshotTTL
+	snapshotMu         sync.RWMutex
+	snapshots          map[string]flagsSnapshot
+	snapshotGeneration uint64
+	snapshotTTL        time.Duration
+}
+
+// flagsSnapshot - флаги одного окружения на момент at
+type flagsSnapshot struct {
+	flags []models.Flag
+	at    time.Time
 }
 
 func NewRepoFlagDB(
 	db *reform.DB,
 	cache *expirable.LRU[string, models.Flag],
+	snapshotTTL time.Duration,
 ) *RepoFlagDB {
-	return &RepoFlagDB{db: db, cache: cache}
+	return &RepoFlagDB{db: db, cache: cache, snapshotTTL: snapshotTTL}
+}
+
+// OnEvict регистрирует функцию, которую вызываем после удаления флагов из кэша
+// (например, для сброса fiber cache), регистрировать до старта сервера
+func (r *RepoFlagDB) OnEvict(fn func(flagNames ...string)) {
+	r.onEvict = append(r.onEvict, fn)
+}
+
+// OnChange регистрирует функцию, которую вызываем после commit создания, изменения или удаления флага
+// (удаленный флаг приходит с IsDeleted = true), регистрировать до старта сервера
+func (r *RepoFlagDB) OnChange(fn func(flag models.Flag)) {
+	r.onChange = append(r.onChange, fn)
+}
+
+func (r *RepoFlagDB) notifyChange(flags ...models.Flag) {
+	for _, flag := range flags {
+		for _, fn := range r.onChange {
+			fn(flag)
+		}
+	}
+}
+
+// EvictFlags удаляет флаги окружения арендатора из LRU и уведомляет подписчиков OnEvict
+func (r *RepoFlagDB) EvictFlags(tenantID string, environment project.Environment, flagNames ...string) {
+	if len(flagNames) == 0 {
+		return
+	}
+	for _, flagName := range flagNames {
+		r.cache.Remove(flagKey(tenantID, environment, flagName))
+	}
+	r.evicted(flagNames...)
+}
+
+// EvictFlagsInAllEnvironments удаляет флаги арендатора с такими именами из LRU во всех окружениях
+// (сегменты общие для всех окружений) и уведомляет подписчиков OnEvict
+func (r *RepoFlagDB) EvictFlagsInAllEnvironments(tenantID string, flagNames ...string) {
+	if len(flagNames) == 0 {
+		return
+	}
+	for _, key := range r.cache.Keys() {
+		if !strings.HasPrefix(key, tenant.Key(tenantID, "")) {
+			continue
+		}
+		for _, flagName := range flagNames {
+			if strings.HasSuffix(key, "/"+flagName) {
+				r.cache.Remove(key)
+			}
+		}
+	}
+	r.evicted(flagNames...)
+}
+
+// EvictAllFlags очищает LRU и снимки целиком и уведомляет подписчиков OnEvict
+func (r *RepoFlagDB) EvictAllFlags() {
+	flagNames := r.cache.Keys()
+	r.cache.Purge()
+	r.evicted(flagNames...)
+}
+
+func (r *RepoFlagDB) evicted(flagNames ...string) {
+	r.snapshotMu.Lock()
+	r.snapshots = nil
+	r.snapshotGeneration++
+	r.snapshotMu.Unlock()
+	for _, fn := range r.onEvict {
+		fn(flagNames...)
+	}
 }
 
-// Create создает новый флаг
-func (r *RepoFlagDB) CreateFlag(ctx context.Context, newFlag models.Flag) error {
+// flagsChanged после commit сбрасывает кэш измененных флагов, флаги могут быть из разных окружений,
+// и уведомляет подписчиков OnChange
+func (r *RepoFlagDB) flagsChanged(flags ...models.Flag) {
+	for _, flag := range flags {
+		r.EvictFlags(flag.TenantID, environmentOf(flag), flag.FlagName)
+	}
+	r.notifyChange(flags...)
+}
+
+// flagKey - ключ LRU флага окружения арендатора
+func flagKey(tenantID string, environment project.Environment, flagName string) string {
+	return tenant.Key(tenantID, environment.Key(flagName))
+}
+
+// environmentOf - окружение, которому принадлежит флаг
+func environmentOf(flag models.Flag) project.Environment {
+	return project.Environment{Project: flag.Project, Name: flag.Environment}
+}
+
+// Create создает новый флаг в окружении из ctx, удаленный флаг с тем же именем создается заново
+// со следующей версией. В остальных окружениях проекта флаг появляется выключенным
+func (r *RepoFlagDB) CreateFlag(ctx context.Context, newFlag models.Flag) (models.Flag, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	newFlag.ID = uuid.New()
+	newFlag.TenantID = tenant.FromContext(ctx)
+	newFlag.Project = environment.Project
+	newFlag.Environment = environment.Name
+	newFlag.Version = 1
+	withRolloutSalt(&newFlag, nil)
+	var sharedFlags []models.Flag
 	exec := func(tx *reform.TX) error {
 		var oldFlag models.Flag
-		if err := tx.WithContext(ctx).SelectOneTo(
-			&oldFlag,
-			`WHERE flag_name = $1 FOR UPDATE`,
-			newFlag.FlagName,
-		); err != nil {
+		err := selectFlagForUpdate(ctx, tx, &oldFlag, environment, newFlag.FlagName)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if err := tx.WithContext(ctx).Insert(&newFlag); err != nil {
+				return err
+			}
+		case err != nil:
 			return err
-		}
-		if !oldFlag.IsDeleted {
+		case !oldFlag.IsDeleted:
 			return ErrDBAlreadyExists
+		default:
+			newFlag.ID = oldFlag.ID
+			newFlag.Version = oldFlag.Version + 1
+			if err := tx.WithContext(ctx).Update(&newFlag); err != nil {
+				return err
+			}
 		}
-		if err := tx.WithContext(ctx).Update(&newFlag); err != nil {
+		if err := insertFlagVersion(ctx, tx.Querier, newFlag); err != nil {
 			return err
 		}
-		r.cache.Remove(newFlag.FlagName)
-		return nil
-	}
-	if err := r.db.InTransactionContext(ctx, nil, exec); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return r.db.WithContext(ctx).Insert(&newFlag)
+		if err := notifyChanges(ctx, tx.Querier, changeKindFlag, newFlag.FlagName); err != nil {
+			return err
 		}
+		sharedFlags, err = shareDefinition(ctx, tx, newFlag)
 		return err
 	}
-	return nil
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return newFlag, err
+	}
+	r.flagsChanged(append(sharedFlags, newFlag)...)
+	return newFlag, nil
 }
 
-// GetByFlagName возвращает флаг по имени
+// GetByFlagName возвращает флаг по имени в окружении из ctx
 func (r *RepoFlagDB) GetFlagByName(ctx context.Context, flagName string) (models.Flag, error) {
-	flag, ok := r.cache.Get(flagName)
+	environment := project.EnvironmentFromContext(ctx)
+	key := flagKey(tenant.FromContext(ctx), environment, flagName)
+	flag, ok := r.cache.Get(key)
 	if ok {
 		return flag, nil
 	}
-	flag.FlagName = flagName
-	if err := r.db.WithContext(ctx).FindByPrimaryKeyTo(&flag, flagName); err != nil {
+	exec := func(tx *reform.TX) error {
+		return tx.WithContext(ctx).SelectOneTo(
+			&flag,
+			`WHERE project = $1 AND environment = $2 AND flag_name = $3`,
+			environment.Project,
+			environment.Name,
+			flagName,
+		)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
 		return flag, err
 	}
-	r.cache.Add(flagName, flag)
+	r.cache.Add(key, flag)
 
 	return flag, nil
 }
 
-// Update обновляет флаг
+// selectFlagForUpdate читает и блокирует флаг окружения до конца транзакции
+func selectFlagForUpdate(
+	ctx context.Context,
+	tx *reform.TX,
+	flag *models.Flag,
+	environment project.Environment,
+	flagName string,
+) error {
+	return tx.WithContext(ctx).SelectOneTo(
+		flag,
+		`WHERE project = $1 AND environment = $2 AND flag_name = $3 FOR UPDATE`,
+		environment.Project,
+		environment.Name,
+		flagName,
+	)
+}
+
+// Update обновляет флаг, precondition может быть nil
 func (r *RepoFlagDB) UpdateFlag(
 	ctx context.Context,
 	newFlag models.Flag,
+	precondition PreconditionFunc,
 ) (models.Flag, error) {
+	var changedFlags []models.Flag
 	exec := func(tx *reform.TX) error {
-		var oldFlag models.Flag
-		if err := tx.WithContext(ctx).SelectOneTo(
-			&oldFlag,
-			`WHERE is_deleted = false AND flag_name = $1 FOR UPDATE`,
-			newFlag.FlagName,
-		); err != nil {
-			return err
-		}
-		return tx.WithContext(ctx).Update(&newFlag)
+		var err error
+		changedFlags, err = r.updateFlag(ctx, tx, newFlag, precondition)
+		return err
 	}
-	if err := r.db.InTransactionContext(ctx, nil, exec); err != nil {
+	if err := inTenant(ctx, r.db, exec); err != nil {
 		return newFlag, err
 	}
-	r.cache.Remove(newFlag.FlagName)
-	return newFlag, nil
+	r.flagsChanged(changedFlags...)
+	return changedFlags[0], nil
 }
 
-// Delete удаляет флаг
-func (r *RepoFlagDB) DeleteFlag(ctx context.Context, flagName string) error {
-	exec := func(tx *reform.TX) error {
-		var flagFromDB models.Flag
-		if err := tx.WithContext(ctx).SelectOneTo(
-			&flagFromDB,
-			`WHERE flag_name = $1 FOR UPDATE`,
-			flagName,
-		); err != nil {
-			return err
-		}
-		if flagFromDB.IsDeleted {
-			return ErrDBIsDeleted
+// updateFlag обновляет флаг окружения из ctx внутри транзакции tx. Первый из измененных флагов -
+// сам флаг, за ним - флаги других окружений проекта, если изменились теги.
+// Кэш сбрасывает вызывающий после commit через flagsChanged
+func (r *RepoFlagDB) updateFlag(
+	ctx context.Context,
+	tx *reform.TX,
+	newFlag models.Flag,
+	precondition PreconditionFunc,
+) ([]models.Flag, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	var oldFlag models.Flag
+	if err := selectFlagForUpdate(ctx, tx, &oldFlag, environment, newFlag.FlagName); err != nil {
+		return nil, err
+	}
+	if oldFlag.IsDeleted {
+		return nil, sql.ErrNoRows
+	}
+	if precondition != nil {
+		if err := precondition(oldFlag); err != nil {
+			return nil, err
 		}
-		flagFromDB.IsDeleted = true
-		return tx.WithContext(ctx).Update(&flagFromDB)
 	}
-	if err := r.db.InTransactionContext(ctx, nil, exec); err != nil {
+	// автора и дату создания меняет только создание флага заново
+	newFlag.ID = oldFlag.ID
+	newFlag.TenantID = oldFlag.TenantID
+	newFlag.Project = oldFlag.Project
+	newFlag.Environment = oldFlag.Environment
+	newFlag.CreatedBy = oldFlag.CreatedBy
+	newFlag.CreatedAt = oldFlag.CreatedAt
+	newFlag.Version = oldFlag.Version + 1
+	withRolloutSalt(&newFlag, oldFlag.Rollout)
+	if err := tx.WithContext(ctx).Update(&newFlag); err != nil {
+		return nil, err
+	}
+	if err := insertFlagVersion(ctx, tx.Querier, newFlag); err != nil {
+		return nil, err
+	}
+	if err := notifyChanges(ctx, tx.Querier, changeKindFlag, newFlag.FlagName); err != nil {
+		return nil, err
+	}
+	changedFlags := []models.Flag{newFlag}
+	if slices.Equal(oldFlag.Tags, newFlag.Tags) {
+		return changedFlags, nil
+	}
+	sharedFlags, err := shareDefinition(ctx, tx, newFlag)
+	if err != nil {
+		return nil, err
+	}
+	return append(changedFlags, sharedFlags...), nil
+}
+
+// Delete удаляет флаг во всех окружениях проекта - определение флага общее, precondition может быть nil
+// и проверяет флаг окружения из ctx
+func (r *RepoFlagDB) DeleteFlag(ctx context.Context, flagName string, precondition PreconditionFunc) error {
+	var deletedFlags []models.Flag
+	exec := func(tx *reform.TX) error {
+		var err error
+		deletedFlags, err = r.deleteFlag(ctx, tx, flagName, precondition)
 		return err
 	}
-	r.cache.Remove(flagName)
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return err
+	}
+	r.flagsChanged(deletedFlags...)
 	return nil
 }
 
-// ListOfAllFkags возвращает список всех флагов
-func (r *RepoFlagDB) ListOfAllFlags(ctx context.Context) ([]models.Flag, error) {
-	flags, err := r.db.WithContext(ctx).SelectAllFrom(models.FlagTable, "")
+// deleteFlag удаляет флаг во всех окружениях проекта внутри транзакции tx и возвращает удаленные флаги.
+// Кэш сбрасывает вызывающий после commit через flagsChanged
+func (r *RepoFlagDB) deleteFlag(
+	ctx context.Context,
+	tx *reform.TX,
+	flagName string,
+	precondition PreconditionFunc,
+) ([]models.Flag, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	var flagFromDB models.Flag
+	if err := selectFlagForUpdate(ctx, tx, &flagFromDB, environment, flagName); err != nil {
+		return nil, err
+	}
+	if flagFromDB.IsDeleted {
+		return nil, ErrDBIsDeleted
+	}
+	if precondition != nil {
+		if err := precondition(flagFromDB); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := lockProjectEnvironments(ctx, tx, environment.Project); err != nil {
+		return nil, err
+	}
+	dependentFlags, err := flagsDependingOn(ctx, tx.Querier, environment.Project, flagName)
+	if err != nil {
+		return nil, err
+	}
+	if len(dependentFlags) > 0 {
+		return nil, fmt.Errorf("%w: flags - {%s}", ErrDBHasDependents, strings.Join(dependentFlags, ", "))
+	}
+	flags, err := tx.WithContext(ctx).SelectAllFrom(
+		models.FlagTable,
+		`WHERE project = $1 AND flag_name = $2 AND is_deleted = false ORDER BY environment FOR UPDATE`,
+		environment.Project,
+		flagName,
+	)
 	if err != nil {
 		return nil, err
 	}
+	deletedFlags, err := models.ConvertReformStructToModel[models.Flag](flags)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deletedFlags {
+		deletedFlags[i].IsDeleted = true
+		deletedFlags[i].Version++
+		if err := tx.WithContext(ctx).Update(&deletedFlags[i]); err != nil {
+			return nil, err
+		}
+		flagCtx := project.WithEnvironment(ctx, environmentOf(deletedFlags[i]))
+		if err := insertFlagVersion(flagCtx, tx.Querier, deletedFlags[i]); err != nil {
+			return nil, err
+		}
+		if err := notifyChanges(flagCtx, tx.Querier, changeKindFlag, flagName); err != nil {
+			return nil, err
+		}
+	}
+	return deletedFlags, nil
+}
+
+// shareDefinition переносит определение флага - имя и теги - в остальные окружения проекта:
+// там, где флага нет или он удален, флаг появляется выключенным с тем же состоянием,
+// у живых флагов меняются только теги. Включение, правила, вариации и payload у окружений свои
+func shareDefinition(ctx context.Context, tx *reform.TX, flag models.Flag) ([]models.Flag, error) {
+	environmentNames, err := lockProjectEnvironments(ctx, tx, flag.Project)
+	if err != nil {
+		return nil, err
+	}
+	sharedFlags := make([]models.Flag, 0, len(environmentNames))
+	for _, environmentName := range environmentNames {
+		if environmentName == flag.Environment {
+			continue
+		}
+		environment := project.Environment{Project: flag.Project, Name: environmentName}
+		var envFlag models.Flag
+		err := selectFlagForUpdate(ctx, tx, &envFlag, environment, flag.FlagName)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			envFlag = disabledCopy(flag, environment)
+			envFlag.ID = uuid.New()
+			envFlag.Version = 1
+			if err := tx.WithContext(ctx).Insert(&envFlag); err != nil {
+				return nil, err
+			}
+		case err != nil:
+			return nil, err
+		case envFlag.IsDeleted:
+			id, version := envFlag.ID, envFlag.Version
+			envFlag = disabledCopy(flag, environment)
+			envFlag.ID = id
+			envFlag.Version = version + 1
+			if err := tx.WithContext(ctx).Update(&envFlag); err != nil {
+				return nil, err
+			}
+		case slices.Equal(envFlag.Tags, flag.Tags):
+			continue
+		default:
+			envFlag.Tags = flag.Tags
+			envFlag.UpdatedAt = flag.UpdatedAt
+			envFlag.Version++
+			if err := tx.WithContext(ctx).Update(&envFlag); err != nil {
+				return nil, err
+			}
+		}
+		envCtx := project.WithEnvironment(ctx, environment)
+		if err := insertFlagVersion(envCtx, tx.Querier, envFlag); err != nil {
+			return nil, err
+		}
+		if err := notifyChanges(envCtx, tx.Querier, changeKindFlag, envFlag.FlagName); err != nil {
+			return nil, err
+		}
+		sharedFlags = append(sharedFlags, envFlag)
+	}
+	return sharedFlags, nil
+}
+
+// disabledCopy - выключенная копия флага для окружения environment, id и версию задает вызывающий
+func disabledCopy(flag models.Flag, environment project.Environment) models.Flag {
+	flag.Project = environment.Project
+	flag.Environment = environment.Name
+	flag.IsEnabled = false
+	flag.IsDeleted = false
+	return flag
+}
+
+// withRolloutSalt - соль раскатки не меняется при обновлении без соли,
+// иначе пользователи переедут в другие бакеты
+func withRolloutSalt(newFlag *models.Flag, oldRollout *models.Rollout) {
+	if newFlag.Rollout == nil || newFlag.Rollout.Salt != "" {
+		return
+	}
+	if oldRollout != nil && oldRollout.Salt != "" {
+		newFlag.Rollout.Salt = oldRollout.Salt
+		return
+	}
+	newFlag.Rollout.Salt = uuid.NewString()
+}
+
+// flagsDependingOn ищет живые флаги проекта в любом окружении, у которых flagName в пререквизитах
+func flagsDependingOn(ctx context.Context, q *reform.Querier, projectName string, flagName string) ([]string, error) {
+	containment, err := json.Marshal([]map[string]string{{"flag": flagName}})
+	if err != nil {
+		return nil, err
+	}
+	return selectStrings(
+		ctx,
+		q,
+		`SELECT DISTINCT flag_name FROM public.flags
+WHERE project = $1 AND is_deleted = false AND prerequisites @> $2::JSONB
+ORDER BY flag_name`,
+		projectName,
+		string(containment),
+	)
+}
+
+// ListOfAllFkags возвращает список всех флагов окружения из ctx
+func (r *RepoFlagDB) ListOfAllFlags(ctx context.Context) ([]models.Flag, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	var flags []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		flags, err = tx.WithContext(ctx).SelectAllFrom(
+			models.FlagTable,
+			`WHERE project = $1 AND environment = $2`,
+			environment.Project,
+			environment.Name,
+		)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
 	listOfFlags, err := models.ConvertReformStructToModel[models.Flag](flags)
 	if err != nil {
 		return nil, err
 	}
 	for _, flag := range listOfFlags {
-		r.cache.Add(flag.FlagName, flag)
+		r.cache.Add(flagKey(flag.TenantID, environment, flag.FlagName), flag)
+	}
+	return listOfFlags, nil
+}
+
+// SnapshotOfAllFlags возвращает все флаги окружения из ctx из памяти, при промахе читает их
+// через ListOfAllFlags. Срез общий для всех вызывающих - менять его нельзя
+func (r *RepoFlagDB) SnapshotOfAllFlags(ctx context.Context) ([]models.Flag, error) {
+	key := tenant.Key(tenant.FromContext(ctx), project.EnvironmentFromContext(ctx).String())
+	r.snapshotMu.RLock()
+	snapshot, generation := r.snapshots[key], r.snapshotGeneration
+	r.snapshotMu.RUnlock()
+	if snapshot.flags != nil && time.Since(snapshot.at) < r.snapshotTTL {
+		return snapshot.flags, nil
 	}
+	listOfFlags, err := r.ListOfAllFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.snapshotMu.Lock()
+	// пока читали из БД, флаги могли измениться - такой снимок не сохраняем
+	if r.snapshotGeneration == generation {
+		if r.snapshots == nil {
+			r.snapshots = make(map[string]flagsSnapshot)
+		}
+		r.snapshots[key] = flagsSnapshot{flags: listOfFlags, at: time.Now()}
+	}
+	r.snapshotMu.Unlock()
 	return listOfFlags, nil
 }
 
+// ListOfFlagByNames возвращает флаги окружения из ctx по именам
 func (r *RepoFlagDB) ListOfFlagByNames(
 	ctx context.Context,
 	flagNames []string,
 ) ([]models.Flag, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	tenantID := tenant.FromContext(ctx)
 	listOfFlags := make([]models.Flag, 0, len(flagNames))
 	findFlagsByNamesFromDB := make([]string, 0, len(flagNames))
 	for _, nameOfFlag := range flagNames {
-		if flag, ok := r.cache.Get(nameOfFlag); ok {
+		if flag, ok := r.cache.Get(flagKey(tenantID, environment, nameOfFlag)); ok {
 			listOfFlags = append(listOfFlags, flag)
 			continue
 		}
 		findFlagsByNamesFromDB = append(findFlagsByNamesFromDB, nameOfFlag)
 	}
 	if len(findFlagsByNamesFromDB) > 0 {
-		args := make([]any, 0, len(findFlagsByNamesFromDB))
+		args := make([]any, 0, len(findFlagsByNamesFromDB)+2)
+		args = append(args, environment.Project, environment.Name)
 		for _, name := range findFlagsByNamesFromDB {
 			args = append(args, name)
 		}
-		flags, err := r.db.WithContext(ctx).FindAllFrom(models.FlagTable, "flag_name", args...)
-		if err != nil {
+		var flags []reform.Struct
+		exec := func(tx *reform.TX) error {
+			var err error
+			flags, err = tx.WithContext(ctx).SelectAllFrom(
+				models.FlagTable,
+				fmt.Sprintf(
+					`WHERE project = $1 AND environment = $2 AND flag_name IN (%s)`,
+					strings.Join(r.db.Placeholders(3, len(findFlagsByNamesFromDB)), ", "),
+				),
+				args...,
+			)
+			return err
+		}
+		if err := inTenant(ctx, r.db, exec); err != nil {
 			return nil, err
 		}
 		listOfFlagsFromDB, err := models.ConvertReformStructToModel[models.Flag](flags)
@@ -171,7 +580,7 @@ func (r *RepoFlagDB) ListOfFlagByNames(
 		return nil, models.ErrorWithUnknownModelNames[models.Flag](flagNames, listOfFlags)
 	}
 	for _, flag := range listOfFlags {
-		r.cache.Add(flag.FlagName, flag)
+		r.cache.Add(flagKey(tenantID, environment, flag.FlagName), flag)
 	}
 	return listOfFlags, nil
 }
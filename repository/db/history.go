@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"feature-flag-2/audit"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"fmt"
+	"gopkg.in/reform.v1"
+	"slices"
+	"time"
+)
+
+// insertFlagVersion записывает состояние флага в историю, вызывать в транзакции изменения флага.
+// Открытые запросы на изменение флага, основанные на прежних версиях, устаревают здесь же
+func insertFlagVersion(ctx context.Context, q *reform.Querier, flag models.Flag) error {
+	change := audit.FromContext(ctx)
+	if err := invalidateChangeRequests(ctx, q, flag); err != nil {
+		return err
+	}
+	return q.WithContext(ctx).Insert(&models.FlagVersion{
+		Project:     flag.Project,
+		Environment: flag.Environment,
+		FlagName:    flag.FlagName,
+		Version:     flag.Version,
+		Snapshot:    models.FlagSnapshot(flag),
+		Actor:       change.Actor,
+		Comment:     change.Comment,
+		CreatedAt:   time.Now().UTC(),
+	})
+}
+
+// ListOfFlagVersions возвращает страницу истории флага в окружении из ctx от новых версий к старым
+// и число всех версий
+func (r *RepoFlagDB) ListOfFlagVersions(
+	ctx context.Context,
+	flagName string,
+	limit int,
+	offset int,
+) ([]models.FlagVersion, int64, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	var total int64
+	var versions []reform.Struct
+	exec := func(tx *reform.TX) error {
+		if err := tx.WithContext(ctx).QueryRow(
+			`SELECT count(*) FROM public.flag_versions WHERE project = $1 AND environment = $2 AND flag_name = $3`,
+			environment.Project,
+			environment.Name,
+			flagName,
+		).Scan(&total); err != nil {
+			return err
+		}
+		var err error
+		versions, err = tx.WithContext(ctx).SelectAllFrom(
+			models.FlagVersionTable,
+			`WHERE project = $1 AND environment = $2 AND flag_name = $3 ORDER BY version DESC LIMIT $4 OFFSET $5`,
+			environment.Project,
+			environment.Name,
+			flagName,
+			limit,
+			offset,
+		)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, 0, err
+	}
+	listOfVersions, err := models.ConvertReformStructToModel[models.FlagVersion](versions)
+	if err != nil {
+		return nil, 0, err
+	}
+	return listOfVersions, total, nil
+}
+
+// GetFlagVersion возвращает версию флага окружения из ctx из истории
+func (r *RepoFlagDB) GetFlagVersion(
+	ctx context.Context,
+	flagName string,
+	version int64,
+) (models.FlagVersion, error) {
+	var flagVersion models.FlagVersion
+	exec := func(tx *reform.TX) error {
+		return selectFlagVersion(ctx, tx.Querier, &flagVersion, flagName, version)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return flagVersion, err
+	}
+	return flagVersion, nil
+}
+
+func selectFlagVersion(
+	ctx context.Context,
+	q *reform.Querier,
+	flagVersion *models.FlagVersion,
+	flagName string,
+	version int64,
+) error {
+	environment := project.EnvironmentFromContext(ctx)
+	return q.WithContext(ctx).SelectOneTo(
+		flagVersion,
+		`WHERE project = $1 AND environment = $2 AND flag_name = $3 AND version = $4`,
+		environment.Project,
+		environment.Name,
+		flagName,
+		version,
+	)
+}
+
+// RollbackFlag восстанавливает флаг из версии version и записывает его следующей версией,
+// история не переписывается. Удаленный флаг тоже восстанавливается.
+// check проверяет восстановленный флаг до записи: сегменты и пререквизиты могли измениться.
+// check читает БД своими транзакциями, поэтому вызывается до блокировки строки флага,
+// а под блокировкой сверяется версия: флаг, измененный за время проверки, - ErrDBOutdated
+func (r *RepoFlagDB) RollbackFlag(
+	ctx context.Context,
+	flagName string,
+	version int64,
+	check func(ctx context.Context, flag models.Flag) error,
+) (models.Flag, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	var currentFlag, restoredFlag models.Flag
+	restore := func(tx *reform.TX) error {
+		if err := tx.WithContext(ctx).SelectOneTo(
+			&currentFlag,
+			`WHERE project = $1 AND environment = $2 AND flag_name = $3`,
+			environment.Project,
+			environment.Name,
+			flagName,
+		); err != nil {
+			return err
+		}
+		var flagVersion models.FlagVersion
+		if err := selectFlagVersion(ctx, tx.Querier, &flagVersion, flagName, version); err != nil {
+			return err
+		}
+		restoredFlag = models.Flag(flagVersion.Snapshot)
+		if restoredFlag.IsDeleted {
+			return fmt.Errorf("%w: version - {%d}", ErrDBIsDeleted, version)
+		}
+		// версии до появления окружений хранят снимок без id, проекта и окружения
+		restoredFlag.ID = currentFlag.ID
+		restoredFlag.TenantID = currentFlag.TenantID
+		restoredFlag.Project = currentFlag.Project
+		restoredFlag.Environment = currentFlag.Environment
+		restoredFlag.CreatedBy = currentFlag.CreatedBy
+		restoredFlag.CreatedAt = currentFlag.CreatedAt
+		restoredFlag.Version = currentFlag.Version + 1
+		return nil
+	}
+	if err := inTenant(ctx, r.db, restore); err != nil {
+		return restoredFlag, err
+	}
+	if err := check(ctx, restoredFlag); err != nil {
+		return restoredFlag, err
+	}
+	var sharedFlags []models.Flag
+	exec := func(tx *reform.TX) error {
+		var lockedFlag models.Flag
+		if err := selectFlagForUpdate(ctx, tx, &lockedFlag, environment, flagName); err != nil {
+			return err
+		}
+		if lockedFlag.Version != currentFlag.Version {
+			return fmt.Errorf(
+				"%w: version of flag - {%d}, rollback was checked on version {%d}",
+				ErrDBOutdated,
+				lockedFlag.Version,
+				currentFlag.Version,
+			)
+		}
+		restoredFlag.UpdatedAt = time.Now().UTC()
+		if err := tx.WithContext(ctx).Update(&restoredFlag); err != nil {
+			return err
+		}
+		if err := insertFlagVersion(ctx, tx.Querier, restoredFlag); err != nil {
+			return err
+		}
+		if err := notifyChanges(ctx, tx.Querier, changeKindFlag, flagName); err != nil {
+			return err
+		}
+		// восстановленный флаг и его теги появляются во всех окружениях проекта
+		if currentFlag.IsDeleted || !slices.Equal(currentFlag.Tags, restoredFlag.Tags) {
+			var err error
+			sharedFlags, err = shareDefinition(ctx, tx, restoredFlag)
+			return err
+		}
+		return nil
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return restoredFlag, err
+	}
+	r.flagsChanged(append(sharedFlags, restoredFlag)...)
+	return restoredFlag, nil
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/tenant"
+	"gopkg.in/reform.v1"
+	"log"
+	"time"
+)
+
+// RecordLifecycleTransitions записывает переходы флагов всех арендаторов в active (active_from)
+// и expired (active_until), наступившие в (since, now]; повторная запись того же перехода игнорируется,
+// поэтому безопасно для нескольких реплик. Нулевой since - у каждого арендатора с его последнего перехода
+func (r *RepoFlagDB) RecordLifecycleTransitions(
+	ctx context.Context,
+	since time.Time,
+	now time.Time,
+) ([]models.FlagLifecycleEvent, error) {
+	tenantIDs, err := selectTenantIDs(ctx, r.db.Querier)
+	if err != nil {
+		return nil, err
+	}
+	events := []models.FlagLifecycleEvent{}
+	for _, tenantID := range tenantIDs {
+		tenantCtx := tenant.WithTenant(ctx, tenantID)
+		tenantEvents, err := r.recordLifecycleTransitions(tenantCtx, since, now)
+		if err != nil {
+			return nil, err
+		}
+		// состояние флагов поменялось - списки с фильтром по state устарели,
+		// переход записывает одна реплика, остальным сообщаем через NOTIFY
+		for _, event := range tenantEvents {
+			environment := project.Environment{Project: event.Project, Name: event.Environment}
+			r.EvictFlags(tenantID, environment, event.FlagName)
+			envCtx := project.WithEnvironment(tenantCtx, environment)
+			if err := notifyChanges(envCtx, r.db.Querier, changeKindFlag, event.FlagName); err != nil {
+				log.Printf("db: notifyChanges error - {%v}", err)
+			}
+		}
+		events = append(events, tenantEvents...)
+	}
+	return events, nil
+}
+
+// recordLifecycleTransitions записывает переходы флагов арендатора из ctx. Нулевой since - с последнего
+// записанного перехода арендатора, без записанных переходов - с начала времен
+func (r *RepoFlagDB) recordLifecycleTransitions(
+	ctx context.Context,
+	since time.Time,
+	now time.Time,
+) ([]models.FlagLifecycleEvent, error) {
+	events := []models.FlagLifecycleEvent{}
+	exec := func(tx *reform.TX) error {
+		if since.IsZero() {
+			var lastOccurredAt *time.Time
+			if err := tx.WithContext(ctx).QueryRow(
+				`SELECT max(occurred_at) FROM public.flag_lifecycle_events`,
+			).Scan(&lastOccurredAt); err != nil {
+				return err
+			}
+			if lastOccurredAt != nil {
+				// в ту же микросекунду могли наступить переходы других флагов, повтор игнорирует ON CONFLICT
+				since = lastOccurredAt.Add(-time.Microsecond)
+			}
+		}
+		rows, err := tx.WithContext(ctx).Query(`INSERT INTO public.flag_lifecycle_events (
+	project,
+	environment,
+	flag_name,
+	state,
+	occurred_at,
+	recorded_at
+)
+SELECT project, environment, flag_name, $3::TEXT, active_from, $2::TIMESTAMPTZ FROM public.flags
+WHERE is_deleted = false AND active_from > $1 AND active_from <= $2
+UNION ALL
+SELECT project, environment, flag_name, $4::TEXT, active_until, $2::TIMESTAMPTZ FROM public.flags
+WHERE is_deleted = false AND active_until > $1 AND active_until <= $2
+ON CONFLICT (tenant_id, project, environment, flag_name, state, occurred_at) DO NOTHING
+RETURNING id, project, environment, flag_name, state, occurred_at, recorded_at`,
+			since,
+			now,
+			models.FlagStateActive,
+			models.FlagStateExpired,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var event models.FlagLifecycleEvent
+			if err := rows.Scan(event.Pointers()...); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return rows.Err()
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListOfLifecycleEvents возвращает историю переходов флага окружения из ctx по расписанию
+func (r *RepoFlagDB) ListOfLifecycleEvents(
+	ctx context.Context,
+	flagName string,
+) ([]models.FlagLifecycleEvent, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	var events []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		events, err = tx.WithContext(ctx).SelectAllFrom(
+			models.FlagLifecycleEventTable,
+			`WHERE project = $1 AND environment = $2 AND flag_name = $3 ORDER BY occurred_at, id`,
+			environment.Project,
+			environment.Name,
+			flagName,
+		)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	return models.ConvertReformStructToModel[models.FlagLifecycleEvent](events)
+}
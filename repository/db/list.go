@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"fmt"
+	"gopkg.in/reform.v1"
+	"strings"
+	"time"
+)
+
+// ErrDBUnknownSort - колонки нет среди колонок сортировки страницы флагов
+var ErrDBUnknownSort = errors.New("unknown sort")
+
+// колонки, по которым сортируется страница флагов
+const (
+	FlagSortByName      = "flag_name"
+	FlagSortByCreatedAt = "created_at"
+	FlagSortByUpdatedAt = "updated_at"
+)
+
+// FlagPosition - последний флаг страницы: имя и значение колонки сортировки, если это дата
+type FlagPosition struct {
+	FlagName string
+	At       time.Time
+}
+
+// FlagListQuery - условия страницы флагов окружения из ctx, пустые и nil поля не фильтруют.
+// Поиск по имени без учета регистра: NamePrefix - с начала имени, NameContains - в любом месте
+type FlagListQuery struct {
+	State        models.FlagState
+	Enabled      *bool
+	Deleted      *bool
+	CreatedBy    string
+	Tag          string
+	UpdatedSince *time.Time
+	NamePrefix   string
+	NameContains string
+	SortBy       string
+	Descending   bool
+	// After - позиция, после которой начинается страница, nil - первая страница
+	After *FlagPosition
+	Limit int
+	Now   time.Time
+}
+
+// likePattern экранирует в s спецсимволы LIKE, чтобы %, _ и \ в имени искались как есть
+func likePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(s))
+}
+
+// PageOfFlags возвращает до query.Limit флагов окружения из ctx, отфильтрованных и упорядоченных в БД,
+// more - за страницей есть еще флаги. Порядок по дате продолжается именем флага, поэтому позиция однозначна
+func (r *RepoFlagDB) PageOfFlags(ctx context.Context, query FlagListQuery) ([]models.Flag, bool, error) {
+	if query.SortBy != FlagSortByName && query.SortBy != FlagSortByCreatedAt && query.SortBy != FlagSortByUpdatedAt {
+		return nil, false, fmt.Errorf("%w {%s}", ErrDBUnknownSort, query.SortBy)
+	}
+	environment := project.EnvironmentFromContext(ctx)
+	args := []any{environment.Project, environment.Name}
+	arg := func(value any) string {
+		args = append(args, value)
+		return r.db.Placeholder(len(args))
+	}
+	conditions := []string{"project = $1", "environment = $2"}
+	switch query.State {
+	case models.FlagStateScheduled:
+		conditions = append(conditions, "active_from > "+arg(query.Now))
+	case models.FlagStateActive:
+		now := arg(query.Now)
+		conditions = append(conditions, "active_from <= "+now+" AND (active_until IS NULL OR active_until > "+now+")")
+	case models.FlagStateExpired:
+		now := arg(query.Now)
+		conditions = append(conditions, "active_from <= "+now+" AND active_until <= "+now)
+	}
+	if query.Enabled != nil {
+		conditions = append(conditions, "is_enabled = "+arg(*query.Enabled))
+	}
+	if query.Deleted != nil {
+		conditions = append(conditions, "is_deleted = "+arg(*query.Deleted))
+	}
+	if query.CreatedBy != "" {
+		conditions = append(conditions, "created_by = "+arg(query.CreatedBy))
+	}
+	if query.Tag != "" {
+		// @> идет по idx_flags_tags
+		tags, err := json.Marshal([]string{query.Tag})
+		if err != nil {
+			return nil, false, err
+		}
+		conditions = append(conditions, "tags @> "+arg(string(tags))+"::JSONB")
+	}
+	if query.UpdatedSince != nil {
+		conditions = append(conditions, "updated_at >= "+arg(*query.UpdatedSince))
+	}
+	if query.NamePrefix != "" {
+		conditions = append(conditions, "lower(flag_name) LIKE "+arg(likePattern(query.NamePrefix)+"%"))
+	}
+	if query.NameContains != "" {
+		conditions = append(conditions, "lower(flag_name) LIKE "+arg("%"+likePattern(query.NameContains)+"%"))
+	}
+	direction, after := "ASC", ">"
+	if query.Descending {
+		direction, after = "DESC", "<"
+	}
+	order := "flag_name " + direction
+	if query.SortBy != FlagSortByName {
+		order = query.SortBy + " " + direction + ", " + order
+	}
+	if query.After != nil {
+		if query.SortBy == FlagSortByName {
+			conditions = append(conditions, "flag_name "+after+" "+arg(query.After.FlagName))
+		} else {
+			conditions = append(conditions, fmt.Sprintf(
+				"(%s, flag_name) %s (%s, %s)",
+				query.SortBy,
+				after,
+				arg(query.After.At),
+				arg(query.After.FlagName),
+			))
+		}
+	}
+	tail := fmt.Sprintf(
+		"WHERE %s ORDER BY %s LIMIT %s",
+		strings.Join(conditions, " AND "),
+		order,
+		arg(query.Limit+1),
+	)
+	var flags []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		flags, err = tx.WithContext(ctx).SelectAllFrom(models.FlagTable, tail, args...)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, false, err
+	}
+	listOfFlags, err := models.ConvertReformStructToModel[models.Flag](flags)
+	if err != nil {
+		return nil, false, err
+	}
+	more := len(listOfFlags) > query.Limit
+	if more {
+		listOfFlags = listOfFlags[:query.Limit]
+	}
+	for _, flag := range listOfFlags {
+		r.cache.Add(flagKey(flag.TenantID, environment, flag.FlagName), flag)
+	}
+	return listOfFlags, more, nil
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"feature-flag-2/project"
+	"feature-flag-2/tenant"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx"
+	"gopkg.in/reform.v1"
+)
+
+// flagChangesChannel - канал NOTIFY, по которому реплики сообщают друг другу об изменениях
+const flagChangesChannel = "flag_changes"
+
+// виды изменений в уведомлении
+const (
+	changeKindFlag    = "flag"
+	changeKindSegment = "segment"
+	changeKindAPIKey  = "api_key"
+	changeKindUser    = "user"
+	// changeKindEnvironment - настройки окружения, имя - "<project>/<environment>"
+	changeKindEnvironment = "environment"
+)
+
+// instanceID - реплика, отправившая уведомление, свои уведомления слушатель пропускает
+var instanceID = uuid.NewString()
+
+// changeNotification - payload NOTIFY flag_changes, у флага еще проект и окружение.
+// Tenant - арендатор изменения, кэши флагов, сегментов и окружений у арендаторов свои
+type changeNotification struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	Tenant      string `json:"tenant"`
+	Project     string `json:"project,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Origin      string `json:"origin"`
+}
+
+// notifyChanges отправляет NOTIFY по каждому имени, внутри транзакции уведомления уходят при commit,
+// арендатор, проект и окружение берутся из ctx
+func notifyChanges(ctx context.Context, q *reform.Querier, kind string, names ...string) error {
+	environment := project.EnvironmentFromContext(ctx)
+	for _, name := range names {
+		payload, err := json.Marshal(changeNotification{
+			Kind:        kind,
+			Name:        name,
+			Tenant:      tenant.FromContext(ctx),
+			Project:     environment.Project,
+			Environment: environment.Name,
+			Origin:      instanceID,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := q.WithContext(ctx).Exec(`SELECT pg_notify($1, $2)`, flagChangesChannel, string(payload)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Listener слушает изменения других реплик и сбрасывает у себя кэши флагов и сегментов
+type Listener struct {
+	connConfig pgx.ConnConfig
+	flags      *RepoFlagDB
+	segments   *RepoSegmentDB
+	apiKeys    *RepoAPIKeyDB
+	users      *RepoUserDB
+	projects   *RepoProjectDB
+}
+
+func NewListener(
+	dbURL string,
+	flags *RepoFlagDB,
+	segments *RepoSegmentDB,
+	apiKeys *RepoAPIKeyDB,
+	users *RepoUserDB,
+	projects *RepoProjectDB,
+) (*Listener, error) {
+	connConfig, err := pgx.ParseURI(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{
+		connConfig: connConfig,
+		flags:      flags,
+		segments:   segments,
+		apiKeys:    apiKeys,
+		users:      users,
+		projects:   projects,
+	}, nil
+}
+
+// Run держит отдельное соединение с LISTEN flag_changes до отмены ctx, при обрыве переподключается
+// с паузой от секунды до maxBackoff и сбрасывает кэши целиком - уведомления за время обрыва потеряны
+func (l *Listener) Run(ctx context.Context, maxBackoff time.Duration) {
+	backoff := time.Second
+	for {
+		listened, err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if listened {
+			backoff = time.Second
+		}
+		log.Printf("db: LISTEN %s error, reconnect in %s - {%v}", flagChangesChannel, backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// listen - одно соединение, listened - LISTEN выполнен и кэши сброшены
+func (l *Listener) listen(ctx context.Context) (bool, error) {
+	conn, err := pgx.Connect(l.connConfig)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if err := conn.Listen(flagChangesChannel); err != nil {
+		return false, err
+	}
+	l.flags.EvictAllFlags()
+	l.segments.cache.Purge()
+	l.apiKeys.cache.Purge()
+	l.users.cache.Purge()
+	l.projects.cache.Purge()
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		var change changeNotification
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			log.Printf("db: flag_changes payload error - {%v}", err)
+			continue
+		}
+		if change.Origin == instanceID {
+			continue
+		}
+		switch change.Kind {
+		case changeKindFlag:
+			environment := project.Environment{Project: change.Project, Name: change.Environment}
+			l.flags.EvictFlags(change.Tenant, environment, change.Name)
+			// подписчики OnChange (SSE) этой реплики тоже должны узнать об изменении
+			flagCtx := project.WithEnvironment(tenant.WithTenant(ctx, change.Tenant), environment)
+			flag, err := l.flags.GetFlagByName(flagCtx, change.Name)
+			if err != nil {
+				log.Printf("db: GetFlagByName {%s} error - {%v}", change.Name, err)
+				continue
+			}
+			l.flags.notifyChange(flag)
+		case changeKindSegment:
+			l.segments.cache.Remove(tenant.Key(change.Tenant, change.Name))
+		case changeKindAPIKey:
+			// отозванный ключ перестает работать на всех репликах сразу, а не через TTL кэша
+			l.apiKeys.cache.Remove(change.Name)
+		case changeKindUser:
+			// отключенный пользователь и отозванная роль тоже действуют сразу
+			l.users.cache.Remove(change.Name)
+		case changeKindEnvironment:
+			// включенное одобрение изменений действует сразу на всех репликах
+			l.projects.cache.Remove(tenant.Key(change.Tenant, change.Name))
+		}
+	}
+}
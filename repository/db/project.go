@@ -0,0 +1,251 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/tenant"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"gopkg.in/reform.v1"
+	"time"
+)
+
+type RepoProjectDB struct {
+	db *reform.DB
+	// cache - окружения по "<tenant>/<project>/<environment>", окружение проверяется на каждый запрос
+	// по пути с проектом, окружения не удаляются, настройки других реплик сбрасывает Listener
+	cache *expirable.LRU[string, models.Environment]
+}
+
+func NewRepoProjectDB(
+	db *reform.DB,
+	cache *expirable.LRU[string, models.Environment],
+) *RepoProjectDB {
+	return &RepoProjectDB{db: db, cache: cache}
+}
+
+// CreateProject сохраняет проект вместе с окружениями, имя занято - ErrDBAlreadyExists
+func (r *RepoProjectDB) CreateProject(
+	ctx context.Context,
+	newProject models.Project,
+	environments []models.Environment,
+) (models.Project, error) {
+	exec := func(tx *reform.TX) error {
+		var oldProject models.Project
+		err := tx.WithContext(ctx).FindByPrimaryKeyTo(&oldProject, newProject.Name)
+		switch {
+		case err == nil:
+			return ErrDBAlreadyExists
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+		if err := tx.WithContext(ctx).Insert(&newProject); err != nil {
+			return err
+		}
+		for i := range environments {
+			if err := tx.WithContext(ctx).Insert(&environments[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return newProject, err
+	}
+	return newProject, nil
+}
+
+// ListOfProjects возвращает все проекты арендатора из ctx
+func (r *RepoProjectDB) ListOfProjects(ctx context.Context) ([]models.Project, error) {
+	var projects []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		projects, err = tx.WithContext(ctx).SelectAllFrom(models.ProjectTable, `ORDER BY name`)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	return models.ConvertReformStructToModel[models.Project](projects)
+}
+
+// ListOfEnvironments возвращает окружения проекта, проекта нет - sql.ErrNoRows
+func (r *RepoProjectDB) ListOfEnvironments(ctx context.Context, projectName string) ([]models.Environment, error) {
+	var environments []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var existingProject models.Project
+		if err := tx.WithContext(ctx).FindByPrimaryKeyTo(&existingProject, projectName); err != nil {
+			return err
+		}
+		var err error
+		environments, err = tx.WithContext(ctx).SelectAllFrom(
+			models.EnvironmentTable,
+			`WHERE project = $1 ORDER BY created_at, name`,
+			projectName,
+		)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	return models.ConvertReformStructToModel[models.Environment](environments)
+}
+
+// GetEnvironment возвращает окружение проекта, окружения нет - sql.ErrNoRows
+func (r *RepoProjectDB) GetEnvironment(
+	ctx context.Context,
+	environment project.Environment,
+) (models.Environment, error) {
+	key := tenant.Key(tenant.FromContext(ctx), environment.String())
+	env, ok := r.cache.Get(key)
+	if ok {
+		return env, nil
+	}
+	exec := func(tx *reform.TX) error {
+		return tx.WithContext(ctx).SelectOneTo(
+			&env,
+			`WHERE project = $1 AND name = $2`,
+			environment.Project,
+			environment.Name,
+		)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return env, err
+	}
+	r.cache.Add(key, env)
+	return env, nil
+}
+
+// UpdateEnvironment меняет настройки окружения, окружения нет - sql.ErrNoRows
+func (r *RepoProjectDB) UpdateEnvironment(
+	ctx context.Context,
+	environment project.Environment,
+	requiredApprovals int,
+) (models.Environment, error) {
+	var env models.Environment
+	exec := func(tx *reform.TX) error {
+		if err := tx.WithContext(ctx).SelectOneTo(
+			&env,
+			`WHERE project = $1 AND name = $2 FOR UPDATE`,
+			environment.Project,
+			environment.Name,
+		); err != nil {
+			return err
+		}
+		env.RequiredApprovals = requiredApprovals
+		if err := tx.WithContext(ctx).Update(&env); err != nil {
+			return err
+		}
+		return notifyChanges(ctx, tx.Querier, changeKindEnvironment, environment.String())
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return env, err
+	}
+	r.cache.Remove(tenant.Key(tenant.FromContext(ctx), environment.String()))
+	return env, nil
+}
+
+// CreateEnvironment добавляет окружение в проект. Определения флагов общие для проекта, поэтому
+// живые флаги окружения source (пустое - первого окружения проекта) копируются в новое окружение
+// выключенными, с записью в истории
+func (r *RepoProjectDB) CreateEnvironment(
+	ctx context.Context,
+	newEnvironment models.Environment,
+	source string,
+) (models.Environment, error) {
+	exec := func(tx *reform.TX) error {
+		// блокировка проекта не дает создать или удалить флаг, пока копируются флаги
+		if err := tx.WithContext(ctx).SelectOneTo(
+			&models.Project{},
+			`WHERE name = $1 FOR UPDATE`,
+			newEnvironment.Project,
+		); err != nil {
+			return err
+		}
+		var oldEnvironment models.Environment
+		err := tx.WithContext(ctx).SelectOneTo(
+			&oldEnvironment,
+			`WHERE project = $1 AND name = $2`,
+			newEnvironment.Project,
+			newEnvironment.Name,
+		)
+		switch {
+		case err == nil:
+			return ErrDBAlreadyExists
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+		var sourceEnvironment models.Environment
+		tail, args := `WHERE project = $1 ORDER BY created_at, name LIMIT 1`, []any{newEnvironment.Project}
+		if source != "" {
+			tail, args = `WHERE project = $1 AND name = $2`, append(args, source)
+		}
+		err = tx.WithContext(ctx).SelectOneTo(&sourceEnvironment, tail, args...)
+		switch {
+		case errors.Is(err, sql.ErrNoRows) && source != "":
+			return fmt.Errorf("source environment {%s}: %w", source, err)
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+		if err := tx.WithContext(ctx).Insert(&newEnvironment); err != nil {
+			return err
+		}
+		if sourceEnvironment.Name == "" {
+			return nil
+		}
+		flags, err := tx.WithContext(ctx).SelectAllFrom(
+			models.FlagTable,
+			`WHERE project = $1 AND environment = $2 AND is_deleted = false ORDER BY flag_name`,
+			sourceEnvironment.Project,
+			sourceEnvironment.Name,
+		)
+		if err != nil {
+			return err
+		}
+		sourceFlags, err := models.ConvertReformStructToModel[models.Flag](flags)
+		if err != nil {
+			return err
+		}
+		environment := project.Environment{Project: newEnvironment.Project, Name: newEnvironment.Name}
+		envCtx := project.WithEnvironment(ctx, environment)
+		for _, sourceFlag := range sourceFlags {
+			flag := disabledCopy(sourceFlag, environment)
+			flag.ID = uuid.New()
+			flag.UpdatedAt = time.Now().UTC()
+			flag.Version = 1
+			if err := tx.WithContext(ctx).Insert(&flag); err != nil {
+				return err
+			}
+			if err := insertFlagVersion(envCtx, tx.Querier, flag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return newEnvironment, err
+	}
+	return newEnvironment, nil
+}
+
+// lockProjectEnvironments блокирует проект до конца транзакции от создания окружений
+// и возвращает имена его окружений
+func lockProjectEnvironments(ctx context.Context, tx *reform.TX, projectName string) ([]string, error) {
+	if err := tx.WithContext(ctx).SelectOneTo(
+		&models.Project{},
+		`WHERE name = $1 FOR SHARE`,
+		projectName,
+	); err != nil {
+		return nil, err
+	}
+	return selectStrings(
+		ctx,
+		tx.Querier,
+		`SELECT name FROM public.environments WHERE project = $1 ORDER BY name`,
+		projectName,
+	)
+}
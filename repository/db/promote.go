@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"fmt"
+	"gopkg.in/reform.v1"
+	"strings"
+)
+
+// PromoteFunc под блокировкой строит по флагам source и target (в порядке имен) новые состояния
+// флагов target, которые нужно записать. Ошибка отменяет продвижение целиком
+type PromoteFunc func(ctx context.Context, sourceFlags []models.Flag, targetFlags []models.Flag) ([]models.Flag, error)
+
+// PromoteFlags переносит состояние флагов из окружения source в окружение target одной транзакцией:
+// флаги target блокируются до записи, флаги source - до commit, чтобы promote видел то же,
+// что будет записано. Флаг, которого нет в одном из окружений или он удален, - ErrDBNotFound
+func (r *RepoFlagDB) PromoteFlags(
+	ctx context.Context,
+	source project.Environment,
+	target project.Environment,
+	flagNames []string,
+	promote PromoteFunc,
+) ([]models.Flag, error) {
+	var promotedFlags []models.Flag
+	targetCtx := project.WithEnvironment(ctx, target)
+	exec := func(tx *reform.TX) error {
+		targetFlags, err := selectLiveFlags(ctx, tx, target, flagNames, "FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		sourceFlags, err := selectLiveFlags(ctx, tx, source, flagNames, "FOR SHARE")
+		if err != nil {
+			return err
+		}
+		newFlags, err := promote(targetCtx, sourceFlags, targetFlags)
+		if err != nil {
+			return err
+		}
+		for _, newFlag := range newFlags {
+			changedFlags, err := r.updateFlag(targetCtx, tx, newFlag, nil)
+			if err != nil {
+				return err
+			}
+			promotedFlags = append(promotedFlags, changedFlags...)
+		}
+		return nil
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	r.flagsChanged(promotedFlags...)
+	return promotedFlags, nil
+}
+
+// selectLiveFlags читает живые флаги окружения по именам в порядке имен с блокировкой lock
+func selectLiveFlags(
+	ctx context.Context,
+	tx *reform.TX,
+	environment project.Environment,
+	flagNames []string,
+	lock string,
+) ([]models.Flag, error) {
+	args := make([]any, 0, len(flagNames)+2)
+	args = append(args, environment.Project, environment.Name)
+	for _, flagName := range flagNames {
+		args = append(args, flagName)
+	}
+	flags, err := tx.WithContext(ctx).SelectAllFrom(
+		models.FlagTable,
+		fmt.Sprintf(
+			`WHERE project = $1 AND environment = $2 AND is_deleted = false AND flag_name IN (%s)
+ORDER BY flag_name %s`,
+			strings.Join(tx.Placeholders(3, len(flagNames)), ", "),
+			lock,
+		),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	listOfFlags, err := models.ConvertReformStructToModel[models.Flag](flags)
+	if err != nil {
+		return nil, err
+	}
+	if len(listOfFlags) != len(flagNames) {
+		return nil, fmt.Errorf(
+			"%w: environment - {%s}, %v",
+			ErrDBNotFound,
+			environment,
+			models.ErrorWithUnknownModelNames(flagNames, listOfFlags),
+		)
+	}
+	return listOfFlags, nil
+}
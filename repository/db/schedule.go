@@ -0,0 +1,275 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/tenant"
+	"fmt"
+	"gopkg.in/reform.v1"
+	"time"
+)
+
+var ErrDBNotPending = errors.New("is not pending")
+
+// ApplyChangeFunc строит новое состояние флага из текущего и отложенного изменения
+type ApplyChangeFunc func(ctx context.Context, flag models.Flag, change models.ScheduledChange) (models.Flag, error)
+
+type RepoScheduleDB struct {
+	db    *reform.DB
+	flags *RepoFlagDB
+}
+
+func NewRepoScheduleDB(db *reform.DB, flags *RepoFlagDB) *RepoScheduleDB {
+	return &RepoScheduleDB{db: db, flags: flags}
+}
+
+// CreateScheduledChange сохраняет новое отложенное изменение флага окружения из ctx
+func (r *RepoScheduleDB) CreateScheduledChange(
+	ctx context.Context,
+	change models.ScheduledChange,
+) (models.ScheduledChange, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	change.Project = environment.Project
+	change.Environment = environment.Name
+	exec := func(tx *reform.TX) error {
+		return tx.WithContext(ctx).Insert(&change)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return change, err
+	}
+	return change, nil
+}
+
+// GetScheduledChange возвращает отложенное изменение флага окружения из ctx по id
+func (r *RepoScheduleDB) GetScheduledChange(
+	ctx context.Context,
+	flagName string,
+	id string,
+) (models.ScheduledChange, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	var change models.ScheduledChange
+	exec := func(tx *reform.TX) error {
+		return tx.WithContext(ctx).SelectOneTo(
+			&change,
+			`WHERE id = $1 AND project = $2 AND environment = $3 AND flag_name = $4`,
+			id,
+			environment.Project,
+			environment.Name,
+			flagName,
+		)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return change, err
+	}
+	return change, nil
+}
+
+// ListOfScheduledChanges возвращает изменения флага окружения из ctx по времени применения,
+// если status не пустой - только изменения в этом статусе
+func (r *RepoScheduleDB) ListOfScheduledChanges(
+	ctx context.Context,
+	flagName string,
+	status models.ScheduleStatus,
+) ([]models.ScheduledChange, error) {
+	environment := project.EnvironmentFromContext(ctx)
+	tail := `WHERE project = $1 AND environment = $2 AND flag_name = $3 ORDER BY apply_at, created_at`
+	args := []any{environment.Project, environment.Name, flagName}
+	if status != "" {
+		tail = `WHERE project = $1 AND environment = $2 AND flag_name = $3 AND status = $4
+ORDER BY apply_at, created_at`
+		args = append(args, status)
+	}
+	var changes []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		changes, err = tx.WithContext(ctx).SelectAllFrom(models.ScheduledChangeTable, tail, args...)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	return models.ConvertReformStructToModel[models.ScheduledChange](changes)
+}
+
+// UpdateScheduledChange меняет время, изменения и комментарий, пока изменение не применено
+func (r *RepoScheduleDB) UpdateScheduledChange(
+	ctx context.Context,
+	newChange models.ScheduledChange,
+) (models.ScheduledChange, error) {
+	var change models.ScheduledChange
+	exec := func(tx *reform.TX) error {
+		if err := r.selectPendingForUpdate(ctx, tx, &change, newChange.FlagName, newChange.ID.String()); err != nil {
+			return err
+		}
+		change.ApplyAt = newChange.ApplyAt
+		change.Changes = newChange.Changes
+		change.Comment = newChange.Comment
+		return tx.WithContext(ctx).Update(&change)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return change, err
+	}
+	return change, nil
+}
+
+// CancelScheduledChange отменяет изменение, пока оно не применено
+func (r *RepoScheduleDB) CancelScheduledChange(
+	ctx context.Context,
+	flagName string,
+	id string,
+) (models.ScheduledChange, error) {
+	var change models.ScheduledChange
+	exec := func(tx *reform.TX) error {
+		if err := r.selectPendingForUpdate(ctx, tx, &change, flagName, id); err != nil {
+			return err
+		}
+		change.Status = models.ScheduleStatusCancelled
+		return tx.WithContext(ctx).Update(&change)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return change, err
+	}
+	return change, nil
+}
+
+func (r *RepoScheduleDB) selectPendingForUpdate(
+	ctx context.Context,
+	tx *reform.TX,
+	change *models.ScheduledChange,
+	flagName string,
+	id string,
+) error {
+	environment := project.EnvironmentFromContext(ctx)
+	if err := tx.WithContext(ctx).SelectOneTo(
+		change,
+		`WHERE id = $1 AND project = $2 AND environment = $3 AND flag_name = $4 FOR UPDATE`,
+		id,
+		environment.Project,
+		environment.Name,
+		flagName,
+	); err != nil {
+		return err
+	}
+	if change.Status != models.ScheduleStatusPending {
+		return fmt.Errorf("%w: status - {%s}", ErrDBNotPending, change.Status)
+	}
+	return nil
+}
+
+// ApplyDueChanges применяет до limit изменений с наступившим apply_at, изменения каждого арендатора -
+// в транзакции арендатора
+func (r *RepoScheduleDB) ApplyDueChanges(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+	apply ApplyChangeFunc,
+) ([]models.ScheduledChange, error) {
+	tenantIDs, err := selectTenantIDs(ctx, r.db.Querier)
+	if err != nil {
+		return nil, err
+	}
+	var processed []models.ScheduledChange
+	for _, tenantID := range tenantIDs {
+		if len(processed) >= limit {
+			break
+		}
+		tenantProcessed, err := r.applyDueChanges(tenant.WithTenant(ctx, tenantID), now, limit-len(processed), apply)
+		if err != nil {
+			return nil, err
+		}
+		processed = append(processed, tenantProcessed...)
+	}
+	return processed, nil
+}
+
+// applyDueChanges применяет до limit изменений арендатора из ctx в одной транзакции.
+// Строки блокируются через FOR UPDATE SKIP LOCKED, поэтому реплики не применят одно изменение дважды.
+// Каждое изменение применяется под своим savepoint: ошибка помечает изменение failed
+// и не откатывает остальные
+func (r *RepoScheduleDB) applyDueChanges(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+	apply ApplyChangeFunc,
+) ([]models.ScheduledChange, error) {
+	var processed []models.ScheduledChange
+	var updatedFlags []models.Flag
+	exec := func(tx *reform.TX) error {
+		changes, err := tx.WithContext(ctx).SelectAllFrom(
+			models.ScheduledChangeTable,
+			`WHERE status = $1 AND apply_at <= $2 ORDER BY apply_at, created_at LIMIT $3 FOR UPDATE SKIP LOCKED`,
+			models.ScheduleStatusPending,
+			now,
+			limit,
+		)
+		if err != nil {
+			return err
+		}
+		dueChanges, err := models.ConvertReformStructToModel[models.ScheduledChange](changes)
+		if err != nil {
+			return err
+		}
+		for _, change := range dueChanges {
+			if _, err := tx.WithContext(ctx).Exec(`SAVEPOINT scheduled_change`); err != nil {
+				return err
+			}
+			changeCtx := project.WithEnvironment(
+				ctx,
+				project.Environment{Project: change.Project, Name: change.Environment},
+			)
+			changedFlags, err := r.applyChange(changeCtx, tx, change, apply)
+			if err != nil {
+				if _, err := tx.WithContext(ctx).Exec(`ROLLBACK TO SAVEPOINT scheduled_change`); err != nil {
+					return err
+				}
+				change.Status = models.ScheduleStatusFailed
+				change.Error = err.Error()
+			} else {
+				change.Status = models.ScheduleStatusApplied
+				updatedFlags = append(updatedFlags, changedFlags...)
+			}
+			appliedAt := now
+			change.AppliedAt = &appliedAt
+			if err := tx.WithContext(ctx).Update(&change); err != nil {
+				return err
+			}
+			if _, err := tx.WithContext(ctx).Exec(`RELEASE SAVEPOINT scheduled_change`); err != nil {
+				return err
+			}
+			processed = append(processed, change)
+		}
+		return nil
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	r.flags.flagsChanged(updatedFlags...)
+	return processed, nil
+}
+
+func (r *RepoScheduleDB) applyChange(
+	ctx context.Context,
+	tx *reform.TX,
+	change models.ScheduledChange,
+	apply ApplyChangeFunc,
+) ([]models.Flag, error) {
+	var flag models.Flag
+	err := selectFlagForUpdate(ctx, tx, &flag, project.EnvironmentFromContext(ctx), change.FlagName)
+	if err == nil && flag.IsDeleted {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, fmt.Errorf("flag {%s}: %w", change.FlagName, err)
+	}
+	newFlag, err := apply(ctx, flag, change)
+	if err != nil {
+		return nil, err
+	}
+	// в истории флага изменение записано от имени автора отложенного изменения
+	ctx = audit.WithChange(ctx, audit.Change{Actor: change.CreatedBy, Comment: change.Comment})
+	return r.flags.updateFlag(ctx, tx, newFlag, nil)
+}
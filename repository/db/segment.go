@@ -0,0 +1,278 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"feature-flag-2/models"
+	"feature-flag-2/tenant"
+	"fmt"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"gopkg.in/reform.v1"
+	"strings"
+)
+
+type RepoSegmentDB struct {
+	db    *reform.DB
+	cache *expirable.LRU[string, models.Segment]
+}
+
+func NewRepoSegmentDB(
+	db *reform.DB,
+	cache *expirable.LRU[string, models.Segment],
+) *RepoSegmentDB {
+	return &RepoSegmentDB{db: db, cache: cache}
+}
+
+// CreateSegment создает новый сегмент, либо восстанавливает удаленный
+func (r *RepoSegmentDB) CreateSegment(
+	ctx context.Context,
+	newSegment models.Segment,
+) (models.Segment, error) {
+	newSegment.Version = 1
+	exec := func(tx *reform.TX) error {
+		var oldSegment models.Segment
+		err := tx.WithContext(ctx).SelectOneTo(
+			&oldSegment,
+			`WHERE segment_name = $1 FOR UPDATE`,
+			newSegment.SegmentName,
+		)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if err := tx.WithContext(ctx).Insert(&newSegment); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !oldSegment.IsDeleted:
+			return ErrDBAlreadyExists
+		default:
+			newSegment.Version = oldSegment.Version + 1
+			if err := tx.WithContext(ctx).Update(&newSegment); err != nil {
+				return err
+			}
+		}
+		return notifyChanges(ctx, tx.Querier, changeKindSegment, newSegment.SegmentName)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return newSegment, err
+	}
+	r.cache.Remove(segmentKey(ctx, newSegment.SegmentName))
+	return newSegment, nil
+}
+
+// GetSegmentByName возвращает сегмент по имени
+func (r *RepoSegmentDB) GetSegmentByName(
+	ctx context.Context,
+	segmentName string,
+) (models.Segment, error) {
+	segment, ok := r.cache.Get(segmentKey(ctx, segmentName))
+	if ok {
+		return segment, nil
+	}
+	segment.SegmentName = segmentName
+	exec := func(tx *reform.TX) error {
+		return tx.WithContext(ctx).FindByPrimaryKeyTo(&segment, segmentName)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return segment, err
+	}
+	r.cache.Add(segmentKey(ctx, segmentName), segment)
+
+	return segment, nil
+}
+
+// UpdateSegment обновляет живой сегмент и возвращает имена флагов, которые на него ссылаются,
+// удаление, автор и дата создания берутся из БД
+func (r *RepoSegmentDB) UpdateSegment(
+	ctx context.Context,
+	newSegment models.Segment,
+) (models.Segment, []string, error) {
+	var dependentFlags []string
+	exec := func(tx *reform.TX) error {
+		var oldSegment models.Segment
+		if err := tx.WithContext(ctx).SelectOneTo(
+			&oldSegment,
+			`WHERE is_deleted = false AND segment_name = $1 FOR UPDATE`,
+			newSegment.SegmentName,
+		); err != nil {
+			return err
+		}
+		newSegment.IsDeleted = oldSegment.IsDeleted
+		newSegment.CreatedBy = oldSegment.CreatedBy
+		newSegment.CreatedAt = oldSegment.CreatedAt
+		newSegment.Version = oldSegment.Version + 1
+		if err := tx.WithContext(ctx).Update(&newSegment); err != nil {
+			return err
+		}
+		if err := notifyChanges(ctx, tx.Querier, changeKindSegment, newSegment.SegmentName); err != nil {
+			return err
+		}
+		flagNames, err := flagsUsingSegment(ctx, tx.Querier, newSegment.SegmentName, false)
+		if err != nil {
+			return err
+		}
+		dependentFlags = flagNames
+		return nil
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return newSegment, nil, err
+	}
+	r.cache.Remove(segmentKey(ctx, newSegment.SegmentName))
+	return newSegment, dependentFlags, nil
+}
+
+// DeleteSegment удаляет сегмент (soft delete), если на него не ссылаются живые флаги
+func (r *RepoSegmentDB) DeleteSegment(ctx context.Context, segmentName string) error {
+	exec := func(tx *reform.TX) error {
+		var segmentFromDB models.Segment
+		if err := tx.WithContext(ctx).SelectOneTo(
+			&segmentFromDB,
+			`WHERE segment_name = $1 FOR UPDATE`,
+			segmentName,
+		); err != nil {
+			return err
+		}
+		if segmentFromDB.IsDeleted {
+			return ErrDBIsDeleted
+		}
+		flagNames, err := flagsUsingSegment(ctx, tx.Querier, segmentName, true)
+		if err != nil {
+			return err
+		}
+		if len(flagNames) > 0 {
+			return fmt.Errorf("%w: flags - {%s}", ErrDBHasDependents, strings.Join(flagNames, ", "))
+		}
+		segmentFromDB.IsDeleted = true
+		segmentFromDB.Version++
+		if err := tx.WithContext(ctx).Update(&segmentFromDB); err != nil {
+			return err
+		}
+		return notifyChanges(ctx, tx.Querier, changeKindSegment, segmentName)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return err
+	}
+	r.cache.Remove(segmentKey(ctx, segmentName))
+	return nil
+}
+
+// ListOfAllSegments возвращает список всех сегментов арендатора из ctx
+func (r *RepoSegmentDB) ListOfAllSegments(ctx context.Context) ([]models.Segment, error) {
+	var segments []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		segments, err = tx.WithContext(ctx).SelectAllFrom(models.SegmentTable, "")
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	listOfSegments, err := models.ConvertReformStructToModel[models.Segment](segments)
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range listOfSegments {
+		r.cache.Add(segmentKey(ctx, segment.SegmentName), segment)
+	}
+	return listOfSegments, nil
+}
+
+// ListOfSegmentsByNames возвращает сегменты по именам, все имена должны существовать
+func (r *RepoSegmentDB) ListOfSegmentsByNames(
+	ctx context.Context,
+	segmentNames []string,
+) ([]models.Segment, error) {
+	listOfSegments := make([]models.Segment, 0, len(segmentNames))
+	findSegmentsByNamesFromDB := make([]any, 0, len(segmentNames))
+	for _, segmentName := range segmentNames {
+		if segment, ok := r.cache.Get(segmentKey(ctx, segmentName)); ok {
+			listOfSegments = append(listOfSegments, segment)
+			continue
+		}
+		findSegmentsByNamesFromDB = append(findSegmentsByNamesFromDB, segmentName)
+	}
+	if len(findSegmentsByNamesFromDB) > 0 {
+		var segments []reform.Struct
+		exec := func(tx *reform.TX) error {
+			var err error
+			segments, err = tx.WithContext(ctx).FindAllFrom(
+				models.SegmentTable,
+				"segment_name",
+				findSegmentsByNamesFromDB...,
+			)
+			return err
+		}
+		if err := inTenant(ctx, r.db, exec); err != nil {
+			return nil, err
+		}
+		listOfSegmentsFromDB, err := models.ConvertReformStructToModel[models.Segment](segments)
+		if err != nil {
+			return nil, err
+		}
+		listOfSegments = append(listOfSegments, listOfSegmentsFromDB...)
+	}
+	if len(listOfSegments) != len(segmentNames) {
+		return nil, models.ErrorWithUnknownModelNames[models.Segment](segmentNames, listOfSegments)
+	}
+	for _, segment := range listOfSegments {
+		r.cache.Add(segmentKey(ctx, segment.SegmentName), segment)
+	}
+	return listOfSegments, nil
+}
+
+// segmentKey - ключ LRU сегмента арендатора из ctx
+func segmentKey(ctx context.Context, segmentName string) string {
+	return tenant.Key(tenant.FromContext(ctx), segmentName)
+}
+
+// flagsUsingSegment ищет флаги, в правилах которых есть in_segment/not_in_segment с сегментом
+func flagsUsingSegment(
+	ctx context.Context,
+	q *reform.Querier,
+	segmentName string,
+	onlyLive bool,
+) ([]string, error) {
+	containment := func(op models.Operator) (string, error) {
+		data, err := json.Marshal([]map[string]any{{
+			"clauses": []map[string]any{{
+				"operator": op,
+				"values":   []string{segmentName},
+			}},
+		}})
+		return string(data), err
+	}
+	inSegment, err := containment(models.OperatorInSegment)
+	if err != nil {
+		return nil, err
+	}
+	notInSegment, err := containment(models.OperatorNotInSegment)
+	if err != nil {
+		return nil, err
+	}
+	// сегменты общие для всех окружений, флаг с одним именем в нескольких окружениях - одно имя
+	query := `SELECT DISTINCT flag_name FROM public.flags WHERE (rules @> $1::JSONB OR rules @> $2::JSONB)`
+	if onlyLive {
+		query += ` AND is_deleted = false`
+	}
+	return selectStrings(ctx, q, query+` ORDER BY flag_name`, inSegment, notInSegment)
+}
+
+// selectStrings выполняет запрос, возвращающий одну текстовую колонку
+func selectStrings(ctx context.Context, q *reform.Querier, query string, args ...any) ([]string, error) {
+	rows, err := q.WithContext(ctx).Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"feature-flag-2/models"
+	"feature-flag-2/tenant"
+	"gopkg.in/reform.v1"
+)
+
+// inTenant выполняет exec в транзакции арендатора из ctx. app.tenant_id действует до конца транзакции,
+// и политики RLS показывают и дают менять только строки арендатора. Соединения пула общие,
+// поэтому вне такой транзакции таблицы с данными арендаторов пусты
+func inTenant(ctx context.Context, db *reform.DB, exec func(tx *reform.TX) error) error {
+	return db.InTransactionContext(ctx, nil, func(tx *reform.TX) error {
+		if _, err := tx.WithContext(ctx).Exec(
+			`SELECT set_config('app.tenant_id', $1, true)`,
+			tenant.FromContext(ctx),
+		); err != nil {
+			return err
+		}
+		return exec(tx)
+	})
+}
+
+// resolveCredentials открывает до конца транзакции tx чтение API ключей, пользователей и ролей
+// всех арендаторов: по учетным данным арендатор только определяется
+func resolveCredentials(ctx context.Context, tx *reform.TX) error {
+	_, err := tx.WithContext(ctx).Exec(`SELECT set_config('app.resolve_credentials', 'on', true)`)
+	return err
+}
+
+// selectTenantIDs - все арендаторы для фоновых задач, которые обходят данные каждого арендатора
+func selectTenantIDs(ctx context.Context, q *reform.Querier) ([]string, error) {
+	return selectStrings(ctx, q, `SELECT id FROM public.tenants ORDER BY id`)
+}
+
+type RepoTenantDB struct {
+	db *reform.DB
+}
+
+func NewRepoTenantDB(db *reform.DB) *RepoTenantDB {
+	return &RepoTenantDB{db: db}
+}
+
+// CreateTenant сохраняет арендатора вместе с его первым проектом, окружениями и ключом admin
+// одной транзакцией нового арендатора, id занят - ErrDBAlreadyExists
+func (r *RepoTenantDB) CreateTenant(
+	ctx context.Context,
+	newTenant models.Tenant,
+	newProject models.Project,
+	environments []models.Environment,
+	apiKey models.APIKey,
+) (models.Tenant, error) {
+	exec := func(tx *reform.TX) error {
+		var oldTenant models.Tenant
+		err := tx.WithContext(ctx).FindByPrimaryKeyTo(&oldTenant, newTenant.ID)
+		switch {
+		case err == nil:
+			return ErrDBAlreadyExists
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+		if err := tx.WithContext(ctx).Insert(&newTenant); err != nil {
+			return err
+		}
+		if err := tx.WithContext(ctx).Insert(&newProject); err != nil {
+			return err
+		}
+		for i := range environments {
+			if err := tx.WithContext(ctx).Insert(&environments[i]); err != nil {
+				return err
+			}
+		}
+		return tx.WithContext(ctx).Insert(&apiKey)
+	}
+	if err := inTenant(tenant.WithTenant(ctx, newTenant.ID), r.db, exec); err != nil {
+		return newTenant, err
+	}
+	return newTenant, nil
+}
+
+// ListOfTenants возвращает всех арендаторов
+func (r *RepoTenantDB) ListOfTenants(ctx context.Context) ([]models.Tenant, error) {
+	tenants, err := r.db.WithContext(ctx).SelectAllFrom(models.TenantTable, `ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	return models.ConvertReformStructToModel[models.Tenant](tenants)
+}
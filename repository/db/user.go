@@ -0,0 +1,256 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"feature-flag-2/models"
+	"feature-flag-2/tenant"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"gopkg.in/reform.v1"
+)
+
+type RepoUserDB struct {
+	db *reform.DB
+	// cache - пользователи с ролями по subject, проверка JWT идет на каждый запрос
+	cache *expirable.LRU[string, models.UserAccess]
+}
+
+func NewRepoUserDB(
+	db *reform.DB,
+	cache *expirable.LRU[string, models.UserAccess],
+) *RepoUserDB {
+	return &RepoUserDB{db: db, cache: cache}
+}
+
+// CreateUser сохраняет нового пользователя арендатора из ctx, subject уже занят
+// у любого арендатора - ErrDBAlreadyExists
+func (r *RepoUserDB) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	user.TenantID = tenant.FromContext(ctx)
+	exec := func(tx *reform.TX) error {
+		// по subject из JWT определяется арендатор, поэтому subject один на всех арендаторов
+		if err := resolveCredentials(ctx, tx); err != nil {
+			return err
+		}
+		var oldUser models.User
+		err := tx.WithContext(ctx).SelectOneTo(&oldUser, `WHERE subject = $1`, user.Subject)
+		switch {
+		case err == nil:
+			return ErrDBAlreadyExists
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+		if err := tx.WithContext(ctx).Insert(&user); err != nil {
+			return err
+		}
+		// пользователь без ролей мог попасть в кэш на других репликах после ответа user_not_found
+		return notifyChanges(ctx, tx.Querier, changeKindUser, user.Subject)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return user, err
+	}
+	r.cache.Remove(user.Subject)
+	return user, nil
+}
+
+// GetUser возвращает пользователя с ролями по id
+func (r *RepoUserDB) GetUser(ctx context.Context, id string) (models.UserAccess, error) {
+	var access models.UserAccess
+	exec := func(tx *reform.TX) error {
+		var user models.User
+		if err := tx.WithContext(ctx).SelectOneTo(&user, `WHERE id = $1`, id); err != nil {
+			return err
+		}
+		var err error
+		access, err = r.userAccess(ctx, tx.Querier, user)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return models.UserAccess{}, err
+	}
+	return access, nil
+}
+
+// GetUserAccessBySubject возвращает пользователя любого арендатора с ролями по subject из JWT:
+// по пользователю определяется арендатор запроса
+func (r *RepoUserDB) GetUserAccessBySubject(ctx context.Context, subject string) (models.UserAccess, error) {
+	access, ok := r.cache.Get(subject)
+	if ok {
+		return access, nil
+	}
+	exec := func(tx *reform.TX) error {
+		if err := resolveCredentials(ctx, tx); err != nil {
+			return err
+		}
+		var user models.User
+		if err := tx.WithContext(ctx).SelectOneTo(&user, `WHERE subject = $1`, subject); err != nil {
+			return err
+		}
+		var err error
+		access, err = r.userAccess(ctx, tx.Querier, user)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return access, err
+	}
+	r.cache.Add(subject, access)
+	return access, nil
+}
+
+// ListOfUsers возвращает всех пользователей арендатора из ctx без ролей
+func (r *RepoUserDB) ListOfUsers(ctx context.Context) ([]models.User, error) {
+	var users []reform.Struct
+	exec := func(tx *reform.TX) error {
+		var err error
+		users, err = tx.WithContext(ctx).SelectAllFrom(models.UserTable, `ORDER BY created_at`)
+		return err
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return nil, err
+	}
+	return models.ConvertReformStructToModel[models.User](users)
+}
+
+// UpdateUser меняет email, имя и отключение пользователя, subject и автор создания не меняются
+func (r *RepoUserDB) UpdateUser(ctx context.Context, user models.User) (models.UserAccess, error) {
+	var access models.UserAccess
+	exec := func(tx *reform.TX) error {
+		var oldUser models.User
+		if err := tx.WithContext(ctx).SelectOneTo(&oldUser, `WHERE id = $1 FOR UPDATE`, user.ID); err != nil {
+			return err
+		}
+		oldUser.Email = user.Email
+		oldUser.Name = user.Name
+		oldUser.IsDisabled = user.IsDisabled
+		if err := tx.WithContext(ctx).Update(&oldUser); err != nil {
+			return err
+		}
+		var err error
+		if access, err = r.userAccess(ctx, tx.Querier, oldUser); err != nil {
+			return err
+		}
+		return notifyChanges(ctx, tx.Querier, changeKindUser, oldUser.Subject)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return access, err
+	}
+	r.cache.Remove(access.User.Subject)
+	return access, nil
+}
+
+// DeleteUser удаляет пользователя вместе с ролями
+func (r *RepoUserDB) DeleteUser(ctx context.Context, id string) error {
+	var user models.User
+	exec := func(tx *reform.TX) error {
+		if err := tx.WithContext(ctx).SelectOneTo(&user, `WHERE id = $1 FOR UPDATE`, id); err != nil {
+			return err
+		}
+		if err := tx.WithContext(ctx).Delete(&user); err != nil {
+			return err
+		}
+		return notifyChanges(ctx, tx.Querier, changeKindUser, user.Subject)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return err
+	}
+	r.cache.Remove(user.Subject)
+	return nil
+}
+
+// CreateRoleBinding выдает пользователю роль, такая же роль на тот же ресурс - ErrDBAlreadyExists
+func (r *RepoUserDB) CreateRoleBinding(
+	ctx context.Context,
+	binding models.RoleBinding,
+) (models.UserAccess, error) {
+	var access models.UserAccess
+	exec := func(tx *reform.TX) error {
+		var user models.User
+		if err := tx.WithContext(ctx).SelectOneTo(&user, `WHERE id = $1 FOR UPDATE`, binding.UserID); err != nil {
+			return err
+		}
+		var oldBinding models.RoleBinding
+		err := tx.WithContext(ctx).SelectOneTo(
+			&oldBinding,
+			`WHERE user_id = $1 AND role = $2 AND project = $3 AND environment = $4`,
+			binding.UserID,
+			binding.Role,
+			binding.Project,
+			binding.Environment,
+		)
+		switch {
+		case err == nil:
+			return ErrDBAlreadyExists
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+		if err := tx.WithContext(ctx).Insert(&binding); err != nil {
+			return err
+		}
+		if access, err = r.userAccess(ctx, tx.Querier, user); err != nil {
+			return err
+		}
+		return notifyChanges(ctx, tx.Querier, changeKindUser, user.Subject)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return access, err
+	}
+	r.cache.Remove(access.User.Subject)
+	return access, nil
+}
+
+// DeleteRoleBinding отзывает роль пользователя
+func (r *RepoUserDB) DeleteRoleBinding(
+	ctx context.Context,
+	userID string,
+	bindingID string,
+) (models.UserAccess, error) {
+	var access models.UserAccess
+	exec := func(tx *reform.TX) error {
+		var user models.User
+		if err := tx.WithContext(ctx).SelectOneTo(&user, `WHERE id = $1 FOR UPDATE`, userID); err != nil {
+			return err
+		}
+		var binding models.RoleBinding
+		if err := tx.WithContext(ctx).SelectOneTo(
+			&binding,
+			`WHERE id = $1 AND user_id = $2`,
+			bindingID,
+			userID,
+		); err != nil {
+			return err
+		}
+		if err := tx.WithContext(ctx).Delete(&binding); err != nil {
+			return err
+		}
+		var err error
+		if access, err = r.userAccess(ctx, tx.Querier, user); err != nil {
+			return err
+		}
+		return notifyChanges(ctx, tx.Querier, changeKindUser, user.Subject)
+	}
+	if err := inTenant(ctx, r.db, exec); err != nil {
+		return access, err
+	}
+	r.cache.Remove(access.User.Subject)
+	return access, nil
+}
+
+func (r *RepoUserDB) userAccess(
+	ctx context.Context,
+	q *reform.Querier,
+	user models.User,
+) (models.UserAccess, error) {
+	bindings, err := q.WithContext(ctx).SelectAllFrom(
+		models.RoleBindingTable,
+		`WHERE user_id = $1 ORDER BY created_at`,
+		user.ID,
+	)
+	if err != nil {
+		return models.UserAccess{}, err
+	}
+	roleBindings, err := models.ConvertReformStructToModel[models.RoleBinding](bindings)
+	if err != nil {
+		return models.UserAccess{}, err
+	}
+	return models.UserAccess{User: user, RoleBindings: roleBindings}, nil
+}
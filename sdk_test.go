@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"feature-flag-2/client"
+	"feature-flag-2/evaluator"
+	"feature-flag-2/models"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+)
+
+// sdkEnvironment - окружение, флаги которого читает клиент в тестах SDK
+const sdkEnvironment = "staging"
+
+// sdkProxy - обратный прокси перед сервером из newServer: клиент ходит в настоящий API по HTTP,
+// прокси запоминает запросы и Last-Event-ID подключений к потоку
+type sdkProxy struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	paths     []string
+	flagPages int
+	streamIDs []string
+}
+
+func newSDKProxy(t *testing.T, srv *server) *sdkProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = srv.app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true})
+	}()
+	t.Cleanup(func() { _ = srv.app.ShutdownWithTimeout(5 * time.Second) })
+	target := &url.URL{Scheme: "http", Host: ln.Addr().String()}
+	proxy := &sdkProxy{}
+	proxy.Server = httptest.NewServer(&httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			proxy.record(r.In)
+		},
+		// события потока доходят до клиента сразу, без буфера прокси
+		FlushInterval: -1,
+	})
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func (p *sdkProxy) record(r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paths = append(p.paths, r.URL.Path)
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/flags"):
+		p.flagPages++
+	case strings.HasSuffix(r.URL.Path, "/stream"):
+		p.streamIDs = append(p.streamIDs, r.Header.Get("Last-Event-ID"))
+	}
+}
+
+// requests возвращает копии записанных путей, страниц GET /flags и Last-Event-ID потока
+func (p *sdkProxy) requests() ([]string, int, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.paths...), p.flagPages, append([]string(nil), p.streamIDs...)
+}
+
+// createProject создает проект арендатора с окружением sdkEnvironment и возвращает префикс его путей
+func createProject(t *testing.T, app *fiber.App, key, projectName string) string {
+	t.Helper()
+	body := map[string]any{"name": projectName, "environments": []string{sdkEnvironment}}
+	if status := call(t, app, http.MethodPost, "/projects", key, body, nil); status != http.StatusCreated {
+		t.Fatalf("POST /projects {%s} - %d", projectName, status)
+	}
+	return "/projects/" + projectName + "/environments/" + sdkEnvironment
+}
+
+// boolFlagBody - включенный флаг, data отдает {"value": true}, default_data - {"value": false}
+func boolFlagBody(flagName string, enabled bool) map[string]any {
+	return map[string]any{
+		"flag_name":    flagName,
+		"is_enabled":   enabled,
+		"active_from":  time.Now().Add(-time.Hour),
+		"data":         map[string]any{"value": true},
+		"default_data": map[string]any{"value": false},
+	}
+}
+
+// insertFlags пишет флаги окружения прямо в БД в транзакции арендатора:
+// тысячи флагов через API создавались бы слишком долго
+func insertFlags(t *testing.T, db *sql.DB, tenantID, projectName string, flagNames []string) {
+	t.Helper()
+	sqlTX, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sqlTX.Rollback() }()
+	if _, err := sqlTX.Exec(`SELECT set_config('app.tenant_id', $1, true)`, tenantID); err != nil {
+		t.Fatal(err)
+	}
+	tx := reform.NewTX(sqlTX, postgresql.Dialect, nil)
+	now := time.Now().UTC()
+	for _, flagName := range flagNames {
+		if err := tx.Insert(&models.Flag{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			Project:     projectName,
+			Environment: sdkEnvironment,
+			FlagName:    flagName,
+			IsEnabled:   true,
+			ActiveFrom:  now.Add(-time.Hour),
+			Data:        models.JSONmap{"value": true},
+			DefaultData: models.JSONmap{"value": false},
+			CreatedBy:   "sdk_test",
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Version:     1,
+		}); err != nil {
+			t.Fatalf("insert flag {%s} - %v", flagName, err)
+		}
+	}
+	if err := sqlTX.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newSDKClient(t *testing.T, proxy *sdkProxy, key, projectName string, stream bool) *client.Client {
+	t.Helper()
+	c, err := client.New(context.Background(), client.Config{
+		BaseURL:      proxy.URL,
+		APIKey:       key,
+		PollInterval: time.Hour,
+		Stream:       stream,
+		Project:      projectName,
+		Environment:  sdkEnvironment,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// eventually ждет condition до 10 секунд: поток применяет события в своей горутине,
+// а после разрыва клиент переподключается через секунду
+func eventually(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientLoadsAllPagesOfEnvironment(t *testing.T) {
+	srv, db, operatorKey := testServer(t)
+	suffix := randomHex(t)
+	tenantID := "sdk-" + suffix
+	key := createTenant(t, srv.app, operatorKey, tenantID)
+	projectName := "shop-" + suffix
+	environmentPath := createProject(t, srv.app, key, projectName)
+	// страница GET /flags - до 1000 флагов, последний флаг на третьей странице
+	flagNames := make([]string, 2001)
+	for i := range flagNames {
+		flagNames[i] = fmt.Sprintf("page_%s_%04d", suffix, i)
+	}
+	insertFlags(t, db, tenantID, projectName, flagNames)
+	proxy := newSDKProxy(t, srv)
+	c := newSDKClient(t, proxy, key, projectName, false)
+
+	paths, flagPages, _ := proxy.requests()
+	if flagPages != 3 {
+		t.Fatalf("pages of GET %s/flags - %d, want 3", environmentPath, flagPages)
+	}
+	for _, path := range paths {
+		if path != "/segments" && !strings.HasPrefix(path, environmentPath+"/") {
+			t.Fatalf("client requested {%s}, want paths of environment {%s}", path, environmentPath)
+		}
+	}
+	evalCtx := evaluator.Context{Key: "user"}
+	for _, flagName := range flagNames {
+		if !c.BoolVariation(flagName, evalCtx, false) {
+			t.Fatalf("flag {%s} is not loaded", flagName)
+		}
+	}
+	if result := c.Evaluate("unknown", evalCtx); result.ErrorCode != client.ErrorCodeFlagNotFound {
+		t.Fatalf("unknown flag - %+v, want error %s", result, client.ErrorCodeFlagNotFound)
+	}
+
+	// сервер недоступен - клиент отдает последние полученные значения
+	proxy.Close()
+	if err := c.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh of closed server without error")
+	}
+	if !c.BoolVariation(flagNames[len(flagNames)-1], evalCtx, false) {
+		t.Fatal("flags are lost after failed Refresh")
+	}
+}
+
+func TestClientStreamAppliesChangesOfEnvironment(t *testing.T) {
+	srv, _, operatorKey := testServer(t)
+	suffix := randomHex(t)
+	key := createTenant(t, srv.app, operatorKey, "sdk-"+suffix)
+	projectName := "shop-" + suffix
+	environmentPath := createProject(t, srv.app, key, projectName)
+	for _, flagName := range []string{"flag_a", "flag_b"} {
+		if status := call(t, srv.app, http.MethodPost, environmentPath+"/flag", key, boolFlagBody(flagName, true), nil); status != http.StatusCreated {
+			t.Fatalf("POST %s/flag {%s} - %d", environmentPath, flagName, status)
+		}
+	}
+	proxy := newSDKProxy(t, srv)
+	c := newSDKClient(t, proxy, key, projectName, true)
+	evalCtx := evaluator.Context{Key: "user"}
+	eventually(t, func() bool {
+		_, _, streamIDs := proxy.requests()
+		return len(streamIDs) == 1
+	}, "client did not connect to stream")
+
+	// каждое изменение через API приходит событием потока, без опроса
+	if status := call(t, srv.app, http.MethodPut, environmentPath+"/flag/flag_a", key, boolFlagBody("flag_a", false), nil); status != http.StatusOK {
+		t.Fatalf("PUT %s/flag/flag_a - %d", environmentPath, status)
+	}
+	if status := call(t, srv.app, http.MethodDelete, environmentPath+"/flag/flag_b", key, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("DELETE %s/flag/flag_b - %d", environmentPath, status)
+	}
+	if status := call(t, srv.app, http.MethodPost, environmentPath+"/flag", key, boolFlagBody("flag_c", true), nil); status != http.StatusCreated {
+		t.Fatalf("POST %s/flag {flag_c} - %d", environmentPath, status)
+	}
+	eventually(t, func() bool {
+		return c.Evaluate("flag_a", evalCtx).Reason == evaluator.ReasonDisabled &&
+			c.Evaluate("flag_b", evalCtx).Reason == evaluator.ReasonDeleted &&
+			c.BoolVariation("flag_c", evalCtx, false)
+	}, "put, delete and create of flags are not applied from stream")
+
+	// после разрыва клиент переподключается с Last-Event-ID и получает пропущенные события
+	proxy.CloseClientConnections()
+	if status := call(t, srv.app, http.MethodPut, environmentPath+"/flag/flag_a", key, boolFlagBody("flag_a", true), nil); status != http.StatusOK {
+		t.Fatalf("PUT %s/flag/flag_a - %d", environmentPath, status)
+	}
+	eventually(t, func() bool {
+		return c.BoolVariation("flag_a", evalCtx, false)
+	}, "change after reconnect is not applied")
+	_, _, streamIDs := proxy.requests()
+	if len(streamIDs) < 2 || streamIDs[0] != "" || streamIDs[1] == "" {
+		t.Fatalf("Last-Event-ID of stream connections - %q, want empty and then the last received id", streamIDs)
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/repository/db"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrServiceInvalidAPIKey = errors.New("invalid api key")
+
+const (
+	// apiKeyPrefix - по префиксу ключ легко найти в логах и конфигурации
+	apiKeyPrefix = "ffk_"
+	// apiKeyShownPrefix - сколько первых символов ключа хранится открыто, чтобы узнать ключ в списке
+	apiKeyShownPrefix = 12
+	// apiKeySubjectPrefix - автор изменений, сделанных по ключу: "api-key:<name>"
+	apiKeySubjectPrefix = "api-key:"
+	// bootstrapAPIKeyName - имя ключа из конфигурации
+	bootstrapAPIKeyName = "bootstrap"
+)
+
+type ServiceAPIKey struct {
+	repoAPIKey *db.RepoAPIKeyDB
+}
+
+func NewServiceAPIKey(repoAPIKey *db.RepoAPIKeyDB) *ServiceAPIKey {
+	return &ServiceAPIKey{repoAPIKey: repoAPIKey}
+}
+
+// CreateAPIKey выпускает ключ, сам ключ возвращается только в ответе на создание, в БД - его хэш
+func (sk *ServiceAPIKey) CreateAPIKey(
+	ctx context.Context,
+	apiKeyDecode entity.APIKeyDecode,
+) (*entity.APIKeyResponse, error) {
+	for _, scope := range apiKeyDecode.Scopes {
+		if !slices.Contains(auth.Scopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope {%s}", ErrServiceInvalidAPIKey, scope)
+		}
+	}
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := sk.repoAPIKey.CreateAPIKey(ctx, newAPIKey(
+		apiKeyDecode.Name,
+		key,
+		apiKeyDecode.Scopes,
+		audit.FromContext(ctx).Actor,
+	))
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewAPIKeyResponse(apiKey, key), nil
+}
+
+// EnsureBootstrapAPIKey сохраняет ключ из конфигурации с правом admin, если его еще нет,
+// без него на закрытом сервере нельзя выпустить первый ключ
+func (sk *ServiceAPIKey) EnsureBootstrapAPIKey(ctx context.Context, key string) error {
+	return sk.repoAPIKey.EnsureAPIKey(ctx, newAPIKey(
+		bootstrapAPIKeyName,
+		key,
+		[]string{auth.ScopeAdmin},
+		audit.ActorUnknown,
+	))
+}
+
+func (sk *ServiceAPIKey) RetrieveListOfAPIKeys(ctx context.Context) (*entity.ListOfAPIKeyResponse, error) {
+	apiKeys, err := sk.repoAPIKey.ListOfAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfAPIKeyResponse(apiKeys), nil
+}
+
+func (sk *ServiceAPIKey) RevokeAPIKey(ctx context.Context, id string) (*entity.APIKeyResponse, error) {
+	apiKey, err := sk.repoAPIKey.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewAPIKeyResponse(apiKey, ""), nil
+}
+
+// Authenticate - auth.AuthenticateFunc для API ключей, отозванный ключ не действует
+func (sk *ServiceAPIKey) Authenticate(ctx context.Context, token string) (auth.Principal, error) {
+	if token == "" {
+		return auth.Principal{}, fmt.Errorf("%w: api key is missing", auth.ErrAuthUnauthorized)
+	}
+	apiKey, err := sk.repoAPIKey.GetAPIKeyByHash(ctx, hashAPIKey(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.Principal{}, fmt.Errorf("%w: unknown api key", auth.ErrAuthUnauthorized)
+		}
+		return auth.Principal{}, err
+	}
+	if apiKey.RevokedAt != nil {
+		return auth.Principal{}, fmt.Errorf("%w: api key is revoked", auth.ErrAuthUnauthorized)
+	}
+	// права ключа глобальные
+	return auth.Principal{
+		Subject:  apiKeySubjectPrefix + apiKey.Name,
+		Tenant:   apiKey.TenantID,
+		Bindings: []auth.Binding{{Scopes: apiKey.Scopes}},
+	}, nil
+}
+
+func newAPIKey(name, key string, scopes []string, createdBy string) models.APIKey {
+	return models.APIKey{
+		ID:        uuid.New(),
+		Name:      name,
+		Prefix:    key[:min(apiKeyShownPrefix, len(key))],
+		KeyHash:   hashAPIKey(key),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func generateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashAPIKey - ключ случайный и длинный, поэтому достаточно SHA-256 без соли
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/repository/db"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrServiceApprovalRequired = errors.New("environment requires approval")
+
+	ErrServiceSelfApproval = errors.New("self-approval is not allowed")
+
+	ErrServiceInvalidDecision = errors.New("invalid change request decision")
+
+	ErrServiceUnauthenticatedApprover = errors.New("approver is not authenticated")
+)
+
+// changeRequestFields - поля флага в разнице запроса на изменение: состояние окружения, теги и удаление
+var changeRequestFields = append(slices.Clone(promotedFields), "tags", "is_deleted")
+
+// requiredApprovals - сколько одобрений нужно изменению флага в окружении из ctx, 0 - изменения сразу
+func (sf *ServiceFlag) requiredApprovals(ctx context.Context) (int, error) {
+	environment, err := sf.repoProject.GetEnvironment(ctx, project.EnvironmentFromContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	return environment.RequiredApprovals, nil
+}
+
+// projectRequiredApprovals - сколько одобрений нужно изменению, которое затрагивает все окружения
+// проекта из ctx: наибольшее из окружений
+func (sf *ServiceFlag) projectRequiredApprovals(ctx context.Context) (int, error) {
+	environments, err := sf.repoProject.ListOfEnvironments(ctx, project.EnvironmentFromContext(ctx).Project)
+	if err != nil {
+		return 0, err
+	}
+	requiredApprovals := 0
+	for _, environment := range environments {
+		requiredApprovals = max(requiredApprovals, environment.RequiredApprovals)
+	}
+	return requiredApprovals, nil
+}
+
+// checkWithoutApproval - отказ, если окружение из ctx требует одобрения: изменение мимо
+// запроса на изменение (откат, перенос, расписание) обошло бы одобрение
+func (sf *ServiceFlag) checkWithoutApproval(ctx context.Context) error {
+	requiredApprovals, err := sf.requiredApprovals(ctx)
+	if err != nil {
+		return err
+	}
+	if requiredApprovals > 0 {
+		return fmt.Errorf(
+			"%w: environment - {%s}, use change requests",
+			ErrServiceApprovalRequired,
+			project.EnvironmentFromContext(ctx),
+		)
+	}
+	return nil
+}
+
+// openChangeRequest открывает запрос на изменение флага окружения из ctx, комментарий изменения -
+// комментарий запроса
+func (sf *ServiceFlag) openChangeRequest(
+	ctx context.Context,
+	kind models.ChangeRequestKind,
+	proposed models.Flag,
+	requiredApprovals int,
+	precondition db.PreconditionFunc,
+) (models.ChangeRequest, error) {
+	change := audit.FromContext(ctx)
+	return sf.repoChangeRequest.OpenChangeRequest(ctx, models.ChangeRequest{
+		ID:                uuid.New(),
+		FlagName:          proposed.FlagName,
+		Kind:              kind,
+		Proposed:          models.FlagSnapshot(proposed),
+		Status:            models.ChangeRequestStatusPending,
+		RequiredApprovals: requiredApprovals,
+		Approvals:         models.Approvals{},
+		Comment:           change.Comment,
+		CreatedBy:         change.Actor,
+		CreatedAt:         time.Now().UTC(),
+	}, precondition)
+}
+
+// RetrieveListOfChangeRequests - запросы на изменение окружения, если status не пустой - только в этом статусе
+func (sf *ServiceFlag) RetrieveListOfChangeRequests(
+	ctx context.Context,
+	status models.ChangeRequestStatus,
+) (*entity.ListOfChangeRequestResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	changeRequests, err := sf.repoChangeRequest.ListOfChangeRequests(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfChangeRequestResponse(changeRequests), nil
+}
+
+// RetrieveChangeRequest - запрос на изменение и разница с флагом, на котором он основан
+func (sf *ServiceFlag) RetrieveChangeRequest(
+	ctx context.Context,
+	id string,
+) (*entity.ChangeRequestResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	changeRequest, err := sf.repoChangeRequest.GetChangeRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return sf.changeRequestResponse(ctx, changeRequest)
+}
+
+// ApproveChangeRequest добавляет одобрение субъекта запроса. Автор запроса свой запрос не одобряет,
+// один субъект одобряет запрос один раз. Набралось required_approvals - запрос одобрен.
+// Без проверки ключей автора задает X-Actor и одобрить свой запрос можно под чужим именем,
+// поэтому одобряет только субъект с ключом или JWT
+func (sf *ServiceFlag) ApproveChangeRequest(
+	ctx context.Context,
+	id string,
+	decision entity.ChangeRequestDecisionDecode,
+) (*entity.ChangeRequestResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsApprove); err != nil {
+		return nil, err
+	}
+	if _, ok := auth.PrincipalFromContext(ctx); !ok {
+		return nil, ErrServiceUnauthenticatedApprover
+	}
+	actor := audit.FromContext(ctx).Actor
+	if actor == "" {
+		return nil, fmt.Errorf("%w: approver is unknown", ErrServiceInvalidDecision)
+	}
+	changeRequest, err := sf.repoChangeRequest.DecideChangeRequest(
+		ctx,
+		id,
+		func(changeRequest *models.ChangeRequest) error {
+			if changeRequest.CreatedBy == actor {
+				return fmt.Errorf("%w: actor - {%s}", ErrServiceSelfApproval, actor)
+			}
+			if changeRequest.Approvals.Contains(actor) {
+				return fmt.Errorf("%w: already approved by {%s}", ErrServiceInvalidDecision, actor)
+			}
+			changeRequest.Approvals = append(changeRequest.Approvals, models.Approval{
+				Actor:     actor,
+				Comment:   decision.Comment,
+				CreatedAt: time.Now().UTC(),
+			})
+			if len(changeRequest.Approvals) >= changeRequest.RequiredApprovals {
+				changeRequest.Status = models.ChangeRequestStatusApproved
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sf.changeRequestResponse(ctx, changeRequest)
+}
+
+// RejectChangeRequest закрывает запрос без применения, комментарий - причина
+func (sf *ServiceFlag) RejectChangeRequest(
+	ctx context.Context,
+	id string,
+	decision entity.ChangeRequestDecisionDecode,
+) (*entity.ChangeRequestResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsApprove); err != nil {
+		return nil, err
+	}
+	changeRequest, err := sf.repoChangeRequest.DecideChangeRequest(
+		ctx,
+		id,
+		func(changeRequest *models.ChangeRequest) error {
+			resolvedAt := time.Now().UTC()
+			changeRequest.Status = models.ChangeRequestStatusRejected
+			changeRequest.ResolvedBy = audit.FromContext(ctx).Actor
+			changeRequest.ResolvedAt = &resolvedAt
+			changeRequest.Reason = decision.Comment
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sf.changeRequestResponse(ctx, changeRequest)
+}
+
+// ApplyChangeRequest применяет одобренный запрос: предложенный флаг проверяется заново,
+// сегменты и пререквизиты могли измениться с открытия запроса
+func (sf *ServiceFlag) ApplyChangeRequest(
+	ctx context.Context,
+	id string,
+) (*entity.ChangeRequestResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	changeRequest, err := sf.repoChangeRequest.ApplyChangeRequest(
+		ctx,
+		id,
+		func(changeRequest *models.ChangeRequest) error {
+			if changeRequest.Status != models.ChangeRequestStatusApproved {
+				return fmt.Errorf(
+					"%w: %d of %d approvals",
+					ErrServiceInvalidDecision,
+					len(changeRequest.Approvals),
+					changeRequest.RequiredApprovals,
+				)
+			}
+			if changeRequest.Kind == models.ChangeRequestKindUpdate {
+				if err := sf.validateFlag(ctx, models.Flag(changeRequest.Proposed)); err != nil {
+					return err
+				}
+			}
+			resolvedAt := time.Now().UTC()
+			changeRequest.Status = models.ChangeRequestStatusApplied
+			changeRequest.ResolvedBy = audit.FromContext(ctx).Actor
+			changeRequest.ResolvedAt = &resolvedAt
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sf.changeRequestResponse(ctx, changeRequest)
+}
+
+// changeRequestResponse - запрос на изменение с разницей между версией base_version из истории
+// и предложенным флагом
+func (sf *ServiceFlag) changeRequestResponse(
+	ctx context.Context,
+	changeRequest models.ChangeRequest,
+) (*entity.ChangeRequestResponse, error) {
+	baseVersion, err := sf.repoDB.GetFlagVersion(ctx, changeRequest.FlagName, changeRequest.BaseVersion)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := fieldChanges(
+		models.Flag(baseVersion.Snapshot),
+		models.Flag(changeRequest.Proposed),
+		changeRequestFields,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewChangeRequestResponse(changeRequest, changes), nil
+}
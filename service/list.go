@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/repository/db"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrServiceInvalidCursor = errors.New("invalid cursor")
+
+// flagCursor - содержимое next_cursor: порядок страницы и последний флаг в нем.
+// Курсор другого порядка не продолжает страницу
+type flagCursor struct {
+	Sort     string    `json:"sort"`
+	FlagName string    `json:"flag_name"`
+	At       time.Time `json:"at,omitzero"`
+}
+
+func encodeFlagCursor(sort string, flag models.Flag) (string, error) {
+	cursor := flagCursor{Sort: sort, FlagName: flag.FlagName}
+	switch strings.TrimPrefix(sort, "-") {
+	case db.FlagSortByCreatedAt:
+		cursor.At = flag.CreatedAt
+	case db.FlagSortByUpdatedAt:
+		cursor.At = flag.UpdatedAt
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeFlagCursor(sort string, value string) (*db.FlagPosition, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrServiceInvalidCursor, err)
+	}
+	var cursor flagCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrServiceInvalidCursor, err)
+	}
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor of sort {%s}, but sort is {%s}", ErrServiceInvalidCursor, cursor.Sort, sort)
+	}
+	return &db.FlagPosition{FlagName: cursor.FlagName, At: cursor.At}, nil
+}
+
+// flagListQuery переводит параметры GET /flags в условия запроса к БД
+func flagListQuery(params entity.FlagListParams, now time.Time) (db.FlagListQuery, error) {
+	query := db.FlagListQuery{
+		State:        models.FlagState(params.State),
+		CreatedBy:    params.CreatedBy,
+		Tag:          params.Tag,
+		NamePrefix:   params.Prefix,
+		NameContains: params.Search,
+		SortBy:       strings.TrimPrefix(params.Sort, "-"),
+		Descending:   strings.HasPrefix(params.Sort, "-"),
+		Limit:        params.Limit,
+		Now:          now,
+	}
+	if params.Enabled != "" {
+		enabled := params.Enabled == "true"
+		query.Enabled = &enabled
+	}
+	if params.Deleted != "all" {
+		deleted := params.Deleted == "true"
+		query.Deleted = &deleted
+	}
+	if !params.UpdatedSince.IsZero() {
+		query.UpdatedSince = &params.UpdatedSince
+	}
+	if params.Cursor != "" {
+		after, err := decodeFlagCursor(params.Sort, params.Cursor)
+		if err != nil {
+			return query, err
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+// RetrieveListOfFlags - страница флагов окружения: фильтры, поиск и порядок выполняет БД,
+// next_cursor продолжает список с того же места, даже если флаги до него менялись
+func (sf *ServiceFlag) RetrieveListOfFlags(
+	ctx context.Context,
+	params entity.FlagListParams,
+) (*entity.ListOfFlagResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	query, err := flagListQuery(params, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	listOfFlags, more, err := sf.repoDB.PageOfFlags(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var nextCursor string
+	if more && len(listOfFlags) > 0 {
+		nextCursor, err = encodeFlagCursor(params.Sort, listOfFlags[len(listOfFlags)-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entity.NewPageOfFlagResponse(listOfFlags, nextCursor), nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"sort"
+	"strings"
+)
+
+// ErrServiceNotModified - результат массового вычисления совпал с ETag клиента
+var ErrServiceNotModified = errors.New("not modified")
+
+// EvaluateFlagOFREP - вычисление одного флага по OpenFeature Remote Evaluation Protocol
+func (sf *ServiceFlag) EvaluateFlagOFREP(
+	ctx context.Context,
+	flagKey string,
+	request entity.OFREPEvaluationRequest,
+) (*entity.OFREPEvaluationResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsEvaluate); err != nil {
+		return nil, err
+	}
+	evalCtx, err := request.EvaluationContext()
+	if err != nil {
+		return entity.NewOFREPEvaluationResponse(
+			entity.NewOFREPError(flagKey, entity.OFREPErrorCodeInvalidContext, err.Error()),
+		), nil
+	}
+	respEvaluation, err := sf.EvaluateFlag(ctx, flagKey, evalCtx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.NewOFREPEvaluationResponse(
+				entity.NewOFREPError(flagKey, entity.OFREPErrorCodeFlagNotFound, "flag not found"),
+			), nil
+		}
+		return nil, err
+	}
+	return entity.NewOFREPEvaluationResponse(
+		entity.NewOFREPEvaluation(respEvaluation.Body.Evaluation, evalCtx.Key),
+	), nil
+}
+
+// EvaluateAllFlagsOFREP - вычисление всех живых флагов по OFREP, ETag - хэш результата,
+// если он совпал с ifNoneMatch, возвращаем ErrServiceNotModified и ETag
+func (sf *ServiceFlag) EvaluateAllFlagsOFREP(
+	ctx context.Context,
+	request entity.OFREPEvaluationRequest,
+	ifNoneMatch string,
+) (*entity.OFREPBulkEvaluationResponse, string, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsEvaluate); err != nil {
+		return nil, "", err
+	}
+	evalCtx, err := request.EvaluationContext()
+	if err != nil {
+		return entity.NewOFREPBulkErrorResponse(entity.OFREPErrorCodeInvalidContext, err.Error()), "", nil
+	}
+	respEvaluations, err := sf.EvaluateAllFlags(ctx, entity.BulkEvaluationDecode{
+		Key:        evalCtx.Key,
+		Attributes: evalCtx.Attributes,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	evaluations := make([]entity.OFREPEvaluation, 0, len(respEvaluations.Body.Evaluations))
+	for _, result := range respEvaluations.Body.Evaluations {
+		evaluations = append(evaluations, entity.NewOFREPEvaluation(result, evalCtx.Key))
+	}
+	// порядок флагов фиксирован, иначе ETag менялся бы от порядка обхода map
+	sort.Slice(evaluations, func(i, j int) bool {
+		return evaluations[i].Key < evaluations[j].Key
+	})
+	etag, err := ofrepETag(evaluations)
+	if err != nil {
+		return nil, "", err
+	}
+	if matchETag(ifNoneMatch, etag) {
+		return nil, etag, ErrServiceNotModified
+	}
+	return entity.NewOFREPBulkEvaluationResponse(evaluations, etag), etag, nil
+}
+
+func ofrepETag(evaluations []entity.OFREPEvaluation) (string, error) {
+	data, err := json.Marshal(evaluations)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// matchETag - If-None-Match может содержать несколько ETag через запятую, слабые сравниваем как сильные
+func matchETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/patch"
+	"feature-flag-2/project"
+	"feature-flag-2/repository/db"
+	"feature-flag-2/tenant"
+	"fmt"
+	"mime"
+	"time"
+)
+
+var (
+	ErrServiceUnsupportedPatch = errors.New("unsupported patch content type")
+
+	ErrServiceInvalidPatch = errors.New("invalid patch")
+
+	ErrServiceConflict = errors.New("flag is changed concurrently")
+)
+
+// типы тела PATCH
+const (
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// patchAttempts - сколько раз PATCH перечитывает флаг, если его изменили между чтением и записью
+const patchAttempts = 3
+
+// errFlagChanged - флаг изменился после чтения, патч нужно наложить заново
+var errFlagChanged = errors.New("flag changed")
+
+// PatchFlag - частичное обновление флага JSON Merge Patch (RFC 7386) или JSON Patch (RFC 6902)
+// в зависимости от contentType. Патч накладывается на текущую версию флага, если флаг успели
+// изменить до записи - патч накладывается заново на новую версию. Если окружение требует
+// одобрения - пропатченный флаг уходит в запрос на изменение
+func (sf *ServiceFlag) PatchFlag(
+	ctx context.Context,
+	flagName string,
+	contentType string,
+	patchDoc []byte,
+	precondition db.PreconditionFunc,
+) (*entity.FlagChangeResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: {%s}", ErrServiceUnsupportedPatch, contentType)
+	}
+	var apply func(doc, patchDoc []byte) ([]byte, error)
+	switch mediaType {
+	case ContentTypeMergePatch:
+		apply = patch.MergePatch
+	case ContentTypeJSONPatch:
+		apply = patch.JSONPatch
+	default:
+		return nil, fmt.Errorf("%w: {%s}", ErrServiceUnsupportedPatch, mediaType)
+	}
+	for range patchAttempts {
+		flag, err := sf.repoDB.GetFlagByName(ctx, flagName)
+		if err != nil {
+			return nil, err
+		}
+		if flag.IsDeleted {
+			return nil, sql.ErrNoRows
+		}
+		newFlag, err := patchFlag(flag, patchDoc, apply)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrServiceInvalidPatch, err)
+		}
+		newFlag.UpdatedAt = time.Now().UTC()
+		respFlag, err := sf.updateFlag(ctx, newFlag, func(currentFlag models.Flag) error {
+			if precondition != nil {
+				if err := precondition(currentFlag); err != nil {
+					return err
+				}
+			}
+			if currentFlag.Version != flag.Version {
+				return errFlagChanged
+			}
+			return nil
+		})
+		if errors.Is(err, errFlagChanged) {
+			// в LRU могла остаться версия, которую другая реплика уже изменила
+			sf.repoDB.EvictFlags(tenant.FromContext(ctx), project.EnvironmentFromContext(ctx), flagName)
+			continue
+		}
+		return respFlag, err
+	}
+	return nil, ErrServiceConflict
+}
+
+// patchFlag накладывает патч на JSON флага, имя, проект, окружение, удаление, автор,
+// даты создания и изменения и версия патчем не меняются
+func patchFlag(
+	flag models.Flag,
+	patchDoc []byte,
+	apply func(doc, patchDoc []byte) ([]byte, error),
+) (models.Flag, error) {
+	flagDoc, err := json.Marshal(flag)
+	if err != nil {
+		return flag, err
+	}
+	patchedDoc, err := apply(flagDoc, patchDoc)
+	if err != nil {
+		return flag, err
+	}
+	var newFlag models.Flag
+	if err := json.Unmarshal(patchedDoc, &newFlag); err != nil {
+		return flag, err
+	}
+	newFlag.ID = flag.ID
+	newFlag.Project = flag.Project
+	newFlag.Environment = flag.Environment
+	newFlag.FlagName = flag.FlagName
+	newFlag.IsDeleted = flag.IsDeleted
+	newFlag.CreatedBy = flag.CreatedBy
+	newFlag.CreatedAt = flag.CreatedAt
+	newFlag.UpdatedAt = flag.UpdatedAt
+	newFlag.Version = flag.Version
+	return newFlag, nil
+}
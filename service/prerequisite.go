@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"feature-flag-2/models"
+	"feature-flag-2/utils"
+	"fmt"
+	"strings"
+)
+
+var ErrServicePrerequisiteCycle = errors.New("prerequisites cycle")
+
+// prerequisiteFlags загружает все флаги, от которых транзитивно зависят flags
+func (sf *ServiceFlag) prerequisiteFlags(
+	ctx context.Context,
+	flags ...models.Flag,
+) ([]models.Flag, error) {
+	visited := make(map[string]struct{}, len(flags))
+	for _, flag := range flags {
+		visited[flag.FlagName] = struct{}{}
+	}
+	enqueue := func(queue []string, flag models.Flag) []string {
+		for _, flagName := range flag.Prerequisites.FlagNames() {
+			if _, ok := visited[flagName]; !ok {
+				visited[flagName] = struct{}{}
+				queue = append(queue, flagName)
+			}
+		}
+		return queue
+	}
+	queue := []string{}
+	for _, flag := range flags {
+		queue = enqueue(queue, flag)
+	}
+	prerequisiteFlags := []models.Flag{}
+	for len(queue) > 0 {
+		listOfFlags, err := sf.repoDB.ListOfFlagByNames(ctx, utils.UniqueWords(queue))
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[:0]
+		for _, flag := range listOfFlags {
+			prerequisiteFlags = append(prerequisiteFlags, flag)
+			queue = enqueue(queue, flag)
+		}
+	}
+	return prerequisiteFlags, nil
+}
+
+// validatePrerequisites - пререквизиты существуют, не удалены, имеют нужную вариацию
+// и вместе с флагом не образуют цикл
+func (sf *ServiceFlag) validatePrerequisites(ctx context.Context, flag models.Flag) error {
+	if len(flag.Prerequisites) == 0 {
+		return nil
+	}
+	prerequisiteFlags, err := sf.prerequisiteFlags(ctx, flag)
+	if err != nil {
+		return err
+	}
+	graph := make(map[string]models.Flag, len(prerequisiteFlags)+1)
+	for _, prerequisiteFlag := range prerequisiteFlags {
+		graph[prerequisiteFlag.FlagName] = prerequisiteFlag
+	}
+	for _, prerequisite := range flag.Prerequisites {
+		prerequisiteFlag, ok := graph[prerequisite.Flag]
+		if prerequisite.Flag == flag.FlagName {
+			return fmt.Errorf("%w: flag {%s} depends on itself", ErrServicePrerequisiteCycle, flag.FlagName)
+		}
+		if !ok {
+			return fmt.Errorf("prerequisite flag {%s} - not found", prerequisite.Flag)
+		}
+		if prerequisiteFlag.IsDeleted {
+			return fmt.Errorf("prerequisite flag {%s} is deleted", prerequisite.Flag)
+		}
+		if _, ok := prerequisiteFlag.FindVariation(prerequisite.Variation); !ok {
+			return fmt.Errorf("prerequisite flag {%s} has no variation {%s}", prerequisite.Flag, prerequisite.Variation)
+		}
+	}
+	// новая версия флага заменяет сохраненную
+	graph[flag.FlagName] = flag
+	if path := findCycle(graph, flag.FlagName); path != nil {
+		return fmt.Errorf("%w: {%s}", ErrServicePrerequisiteCycle, strings.Join(path, " -> "))
+	}
+	return nil
+}
+
+// findCycle ищет путь по пререквизитам из start обратно в start, nil - цикла нет
+func findCycle(graph map[string]models.Flag, start string) []string {
+	visited := make(map[string]struct{}, len(graph))
+	var walk func(flagName string, path []string) []string
+	walk = func(flagName string, path []string) []string {
+		for _, next := range graph[flagName].Prerequisites.FlagNames() {
+			if next == start {
+				return append(path, next)
+			}
+			if _, ok := visited[next]; ok {
+				continue
+			}
+			visited[next] = struct{}{}
+			if cycle := walk(next, append(path, next)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk(start, []string{start})
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/repository/db"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrServiceInvalidEnvironment = errors.New("invalid environment")
+
+// environmentNamePattern - имя окружения, как и проекта, попадает в путь и ключи кэша
+var environmentNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type ServiceProject struct {
+	repoProject *db.RepoProjectDB
+}
+
+func NewServiceProject(repoProject *db.RepoProjectDB) *ServiceProject {
+	return &ServiceProject{repoProject: repoProject}
+}
+
+// CreateProject создает проект с окружениями, без окружений - с окружением default
+func (sp *ServiceProject) CreateProject(
+	ctx context.Context,
+	projectDecode entity.ProjectDecode,
+) (*entity.ProjectResponse, error) {
+	environmentNames := projectDecode.Environments
+	if len(environmentNames) == 0 {
+		environmentNames = []string{project.DefaultEnvironment}
+	}
+	actor := audit.FromContext(ctx).Actor
+	now := time.Now().UTC()
+	environments := make([]models.Environment, 0, len(environmentNames))
+	for _, environmentName := range environmentNames {
+		if !environmentNamePattern.MatchString(environmentName) || len(environmentName) > 100 {
+			return nil, fmt.Errorf("%w: name - {%s}", ErrServiceInvalidEnvironment, environmentName)
+		}
+		environments = append(environments, models.Environment{
+			ID:        uuid.New(),
+			Project:   projectDecode.Name,
+			Name:      environmentName,
+			CreatedBy: actor,
+			CreatedAt: now,
+		})
+	}
+	newProject, err := sp.repoProject.CreateProject(ctx, models.Project{
+		Name:        projectDecode.Name,
+		Description: projectDecode.Description,
+		CreatedBy:   actor,
+		CreatedAt:   now,
+	}, environments)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewProjectResponse(newProject), nil
+}
+
+func (sp *ServiceProject) RetrieveListOfProjects(ctx context.Context) (*entity.ListOfProjectResponse, error) {
+	projects, err := sp.repoProject.ListOfProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfProjectResponse(projects), nil
+}
+
+func (sp *ServiceProject) RetrieveListOfEnvironments(
+	ctx context.Context,
+	projectName string,
+) (*entity.ListOfEnvironmentResponse, error) {
+	environments, err := sp.repoProject.ListOfEnvironments(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfEnvironmentResponse(environments), nil
+}
+
+// CreateEnvironment добавляет окружение в проект, флаги проекта появляются в нем выключенными
+func (sp *ServiceProject) CreateEnvironment(
+	ctx context.Context,
+	projectName string,
+	environmentDecode entity.EnvironmentDecode,
+) (*entity.EnvironmentResponse, error) {
+	environment, err := sp.repoProject.CreateEnvironment(ctx, models.Environment{
+		ID:        uuid.New(),
+		Project:   projectName,
+		Name:      environmentDecode.Name,
+		CreatedBy: audit.FromContext(ctx).Actor,
+		CreatedAt: time.Now().UTC(),
+	}, environmentDecode.Source)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewEnvironmentResponse(environment), nil
+}
+
+// UpdateEnvironment меняет настройки окружения
+func (sp *ServiceProject) UpdateEnvironment(
+	ctx context.Context,
+	environment project.Environment,
+	settingsDecode entity.EnvironmentSettingsDecode,
+) (*entity.EnvironmentResponse, error) {
+	env, err := sp.repoProject.UpdateEnvironment(ctx, environment, settingsDecode.RequiredApprovals)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewEnvironmentResponse(env), nil
+}
+
+// EnvironmentExists проверяет окружение из пути запроса
+func (sp *ServiceProject) EnvironmentExists(ctx context.Context, environment project.Environment) error {
+	_, err := sp.repoProject.GetEnvironment(ctx, environment)
+	return err
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/repository/db"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrServiceInvalidPromotion = errors.New("invalid promotion")
+
+// promotedFields - состояние флага, свое у каждого окружения, его и переносит promote.
+// Имя и теги общие для проекта, автор, даты и версия - свои у флага в окружении
+var promotedFields = []string{
+	"is_enabled",
+	"active_from",
+	"active_until",
+	"data",
+	"default_data",
+	"rules",
+	"rollout",
+	"variations",
+	"off_variation",
+	"fallthrough",
+	"prerequisites",
+}
+
+// PromoteFlags переносит состояние флагов из окружения source в окружение target проекта projectName.
+// Без confirm возвращает разницу и ее ETag, с confirm пересчитывает разницу под блокировкой,
+// проверяет ее ETag через precondition (разница устарела - отказ) и записывает одной транзакцией
+func (sf *ServiceFlag) PromoteFlags(
+	ctx context.Context,
+	projectName string,
+	promoteDecode entity.PromoteDecode,
+	precondition func(etag string) error,
+) (*entity.PromoteResponse, error) {
+	if promoteDecode.Source == promoteDecode.Target {
+		return nil, fmt.Errorf("%w: source and target are the same environment", ErrServiceInvalidPromotion)
+	}
+	source := project.Environment{Project: projectName, Name: promoteDecode.Source}
+	target := project.Environment{Project: projectName, Name: promoteDecode.Target}
+	if err := auth.CheckResource(
+		ctx,
+		auth.ScopeFlagsRead,
+		auth.Resource{Project: source.Project, Environment: source.Name},
+	); err != nil {
+		return nil, err
+	}
+	if err := auth.CheckResource(
+		ctx,
+		auth.ScopeFlagsWrite,
+		auth.Resource{Project: target.Project, Environment: target.Name},
+	); err != nil {
+		return nil, err
+	}
+	flagNames := slices.Compact(slices.Sorted(slices.Values(promoteDecode.FlagNames)))
+	targetCtx := project.WithEnvironment(ctx, target)
+	if !promoteDecode.Confirm {
+		sourceFlags, err := sf.liveFlagsByNames(project.WithEnvironment(ctx, source), flagNames)
+		if err != nil {
+			return nil, err
+		}
+		targetFlags, err := sf.liveFlagsByNames(targetCtx, flagNames)
+		if err != nil {
+			return nil, err
+		}
+		promotions, _, etag, err := sf.promotion(targetCtx, sourceFlags, targetFlags)
+		if err != nil {
+			return nil, err
+		}
+		return entity.NewPromoteResponse(source.Name, target.Name, promotions, etag, false), nil
+	}
+	if err := sf.checkWithoutApproval(targetCtx); err != nil {
+		return nil, err
+	}
+	change := audit.FromContext(ctx)
+	change.Comment = promoteDecode.Comment
+	if change.Comment == "" {
+		change.Comment = fmt.Sprintf("promote from %s", source.Name)
+	}
+	var promotions []entity.FlagPromotion
+	var etag string
+	_, err := sf.repoDB.PromoteFlags(
+		audit.WithChange(ctx, change),
+		source,
+		target,
+		flagNames,
+		func(ctx context.Context, sourceFlags []models.Flag, targetFlags []models.Flag) ([]models.Flag, error) {
+			var newFlags []models.Flag
+			var err error
+			promotions, newFlags, etag, err = sf.promotion(ctx, sourceFlags, targetFlags)
+			if err != nil {
+				return nil, err
+			}
+			if err := precondition(etag); err != nil {
+				return nil, err
+			}
+			return newFlags, nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewPromoteResponse(source.Name, target.Name, promotions, etag, true), nil
+}
+
+// liveFlagsByNames - живые флаги окружения из ctx в порядке имен, удаленный флаг - db.ErrDBIsDeleted
+func (sf *ServiceFlag) liveFlagsByNames(ctx context.Context, flagNames []string) ([]models.Flag, error) {
+	flags, err := sf.repoDB.ListOfFlagByNames(ctx, flagNames)
+	if err != nil {
+		return nil, err
+	}
+	for _, flag := range flags {
+		if flag.IsDeleted {
+			return nil, fmt.Errorf("%w: flag - {%s}", db.ErrDBIsDeleted, flag.FlagName)
+		}
+	}
+	slices.SortFunc(flags, func(a, b models.Flag) int {
+		return strings.Compare(a.FlagName, b.FlagName)
+	})
+	return flags, nil
+}
+
+// promotion сравнивает флаги source и target в порядке имен: разница по флагам, новые состояния
+// измененных флагов target, проверенные в окружении из ctx, и ETag разницы - по версиям флагов обоих окружений
+func (sf *ServiceFlag) promotion(
+	ctx context.Context,
+	sourceFlags []models.Flag,
+	targetFlags []models.Flag,
+) ([]entity.FlagPromotion, []models.Flag, string, error) {
+	promotions := make([]entity.FlagPromotion, 0, len(targetFlags))
+	newFlags := []models.Flag{}
+	hash := sha256.New()
+	for i, targetFlag := range targetFlags {
+		sourceFlag := sourceFlags[i]
+		fmt.Fprintf(hash, "%s:%d:%d\n", targetFlag.FlagName, sourceFlag.Version, targetFlag.Version)
+		newFlag := promotedFlag(sourceFlag, targetFlag)
+		changes, err := fieldChanges(targetFlag, newFlag, promotedFields)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		promotions = append(promotions, entity.FlagPromotion{
+			FlagName:      targetFlag.FlagName,
+			SourceVersion: sourceFlag.Version,
+			TargetVersion: targetFlag.Version,
+			Changes:       changes,
+		})
+		if len(changes) == 0 {
+			continue
+		}
+		if err := sf.validateFlag(ctx, newFlag); err != nil {
+			return nil, nil, "", err
+		}
+		newFlags = append(newFlags, newFlag)
+	}
+	return promotions, newFlags, hex.EncodeToString(hash.Sum(nil)[:16]), nil
+}
+
+// promotedFlag - флаг target с состоянием флага source
+func promotedFlag(sourceFlag models.Flag, targetFlag models.Flag) models.Flag {
+	flag := targetFlag
+	flag.IsEnabled = sourceFlag.IsEnabled
+	flag.ActiveFrom = sourceFlag.ActiveFrom
+	flag.ActiveUntil = sourceFlag.ActiveUntil
+	flag.Data = sourceFlag.Data
+	flag.DefaultData = sourceFlag.DefaultData
+	flag.Rules = sourceFlag.Rules
+	flag.Rollout = sourceFlag.Rollout
+	flag.Variations = sourceFlag.Variations
+	flag.OffVariation = sourceFlag.OffVariation
+	flag.Fallthrough = sourceFlag.Fallthrough
+	flag.Prerequisites = sourceFlag.Prerequisites
+	flag.UpdatedAt = time.Now().UTC()
+	return flag
+}
+
+// fieldChanges - различающиеся поля fields флагов в JSON
+func fieldChanges(oldFlag models.Flag, newFlag models.Flag, fields []string) ([]entity.FieldChange, error) {
+	oldFields, err := flagFields(oldFlag)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flagFields(newFlag)
+	if err != nil {
+		return nil, err
+	}
+	changes := []entity.FieldChange{}
+	for _, field := range fields {
+		if bytes.Equal(oldFields[field], newFields[field]) {
+			continue
+		}
+		changes = append(changes, entity.FieldChange{Field: field, From: oldFields[field], To: newFields[field]})
+	}
+	return changes, nil
+}
+
+func flagFields(flag models.Flag) (map[string]json.RawMessage, error) {
+	doc, err := json.Marshal(flag)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
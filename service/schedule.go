@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/patch"
+	"feature-flag-2/repository/db"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+var ErrServiceInvalidScheduledChange = errors.New("invalid scheduled change")
+
+type ServiceSchedule struct {
+	repoSchedule *db.RepoScheduleDB
+	serviceFlag  *ServiceFlag
+}
+
+func NewServiceSchedule(repoSchedule *db.RepoScheduleDB, serviceFlag *ServiceFlag) *ServiceSchedule {
+	return &ServiceSchedule{repoSchedule: repoSchedule, serviceFlag: serviceFlag}
+}
+
+// CreateScheduledChange ставит изменение флага в очередь, изменение сразу проверяется
+// на текущем состоянии флага, чтобы не узнать об ошибке только в момент применения.
+// В окружении, которое требует одобрения, расписание обошло бы его - отказ
+func (ss *ServiceSchedule) CreateScheduledChange(
+	ctx context.Context,
+	flagName string,
+	changeDecode entity.ScheduledChangeDecode,
+) (*entity.ScheduledChangeResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	if err := ss.serviceFlag.checkWithoutApproval(ctx); err != nil {
+		return nil, err
+	}
+	if err := ss.validateChanges(ctx, flagName, changeDecode.Changes); err != nil {
+		return nil, err
+	}
+	change, err := ss.repoSchedule.CreateScheduledChange(ctx, models.ScheduledChange{
+		ID:        uuid.New(),
+		FlagName:  flagName,
+		ApplyAt:   changeDecode.ApplyAt,
+		Changes:   changeDecode.Changes,
+		Comment:   changeDecode.Comment,
+		Status:    models.ScheduleStatusPending,
+		CreatedBy: audit.FromContext(ctx).Actor,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewScheduledChangeResponse(change), nil
+}
+
+func (ss *ServiceSchedule) GetScheduledChange(
+	ctx context.Context,
+	flagName string,
+	id string,
+) (*entity.ScheduledChangeResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	change, err := ss.repoSchedule.GetScheduledChange(ctx, flagName, id)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewScheduledChangeResponse(change), nil
+}
+
+// RetrieveListOfScheduledChanges - очередь изменений флага, если status не пустой - только в этом статусе
+func (ss *ServiceSchedule) RetrieveListOfScheduledChanges(
+	ctx context.Context,
+	flagName string,
+	status models.ScheduleStatus,
+) (*entity.ListOfScheduledChangeResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	if _, err := ss.serviceFlag.repoDB.GetFlagByName(ctx, flagName); err != nil {
+		return nil, err
+	}
+	changes, err := ss.repoSchedule.ListOfScheduledChanges(ctx, flagName, status)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfScheduledChangeResponse(changes), nil
+}
+
+// UpdateScheduledChange меняет изменение, которое еще ждет применения
+func (ss *ServiceSchedule) UpdateScheduledChange(
+	ctx context.Context,
+	flagName string,
+	id string,
+	changeDecode entity.ScheduledChangeDecode,
+) (*entity.ScheduledChangeResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	changeID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrServiceInvalidScheduledChange, err)
+	}
+	if err := ss.validateChanges(ctx, flagName, changeDecode.Changes); err != nil {
+		return nil, err
+	}
+	change, err := ss.repoSchedule.UpdateScheduledChange(ctx, models.ScheduledChange{
+		ID:       changeID,
+		FlagName: flagName,
+		ApplyAt:  changeDecode.ApplyAt,
+		Changes:  changeDecode.Changes,
+		Comment:  changeDecode.Comment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewScheduledChangeResponse(change), nil
+}
+
+// CancelScheduledChange отменяет изменение, которое еще ждет применения
+func (ss *ServiceSchedule) CancelScheduledChange(
+	ctx context.Context,
+	flagName string,
+	id string,
+) (*entity.ScheduledChangeResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	change, err := ss.repoSchedule.CancelScheduledChange(ctx, flagName, id)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewScheduledChangeResponse(change), nil
+}
+
+// RunScheduler - раз в interval применяет наступившие изменения пачками по batchSize,
+// работает до отмены ctx
+func (ss *ServiceSchedule) RunScheduler(ctx context.Context, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			changes, err := ss.repoSchedule.ApplyDueChanges(ctx, time.Now(), batchSize, ss.applyScheduledChange)
+			if err != nil {
+				log.Printf("service: ApplyDueChanges error - {%v}", err)
+				break
+			}
+			for _, change := range changes {
+				if change.Status == models.ScheduleStatusFailed {
+					log.Printf("service: scheduled change {%s} of flag {%s} failed - {%s}", change.ID, change.FlagName, change.Error)
+					continue
+				}
+				log.Printf("service: scheduled change {%s} of flag {%s} applied", change.ID, change.FlagName)
+			}
+			// неполная пачка - наступивших изменений больше нет
+			if len(changes) < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyScheduledChange накладывает изменение на флаг и проверяет результат как обычное обновление,
+// окружение с тех пор стало требовать одобрения - изменение не применяется
+func (ss *ServiceSchedule) applyScheduledChange(
+	ctx context.Context,
+	flag models.Flag,
+	change models.ScheduledChange,
+) (models.Flag, error) {
+	if err := ss.serviceFlag.checkWithoutApproval(ctx); err != nil {
+		return flag, err
+	}
+	newFlag, err := mergeFlag(flag, change.Changes)
+	if err != nil {
+		return flag, err
+	}
+	newFlag.UpdatedAt = time.Now().UTC()
+	if err := ss.serviceFlag.validateFlag(ctx, newFlag); err != nil {
+		return flag, err
+	}
+	return newFlag, nil
+}
+
+func (ss *ServiceSchedule) validateChanges(ctx context.Context, flagName string, changes models.JSONmap) error {
+	flag, err := ss.serviceFlag.repoDB.GetFlagByName(ctx, flagName)
+	if err != nil {
+		return err
+	}
+	if flag.IsDeleted {
+		return db.ErrDBIsDeleted
+	}
+	newFlag, err := mergeFlag(flag, changes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrServiceInvalidScheduledChange, err)
+	}
+	if err := ss.serviceFlag.validateFlag(ctx, newFlag); err != nil {
+		return fmt.Errorf("%w: %v", ErrServiceInvalidScheduledChange, err)
+	}
+	return nil
+}
+
+// mergeFlag накладывает JSON Merge Patch на флаг, имя, удаление, автор, даты
+// и версия изменениями не меняются
+func mergeFlag(flag models.Flag, changes models.JSONmap) (models.Flag, error) {
+	changesDoc, err := json.Marshal(changes)
+	if err != nil {
+		return flag, err
+	}
+	return patchFlag(flag, changesDoc, patch.MergePatch)
+}
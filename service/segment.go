@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/evaluator"
+	"feature-flag-2/models"
+	"feature-flag-2/repository/db"
+	"feature-flag-2/tenant"
+	"fmt"
+	"time"
+)
+
+var ErrServiceInvalidSegment = errors.New("invalid segment")
+
+type ServiceSegment struct {
+	repoSegment *db.RepoSegmentDB
+	repoFlag    *db.RepoFlagDB
+}
+
+func NewServiceSegment(repoSegment *db.RepoSegmentDB, repoFlag *db.RepoFlagDB) *ServiceSegment {
+	return &ServiceSegment{repoSegment: repoSegment, repoFlag: repoFlag}
+}
+
+func (ss *ServiceSegment) CreateNewSegment(
+	ctx context.Context,
+	segmentDecode entity.SegmentDecode,
+) (*entity.SegmentResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	newSegment := segmentDecode.Segment()
+	if err := validateSegment(newSegment); err != nil {
+		return nil, err
+	}
+	newSegment.CreatedBy = audit.FromContext(ctx).Actor
+	newSegment.CreatedAt = time.Now().UTC()
+	newSegment.UpdatedAt = newSegment.CreatedAt
+	segment, err := ss.repoSegment.CreateSegment(ctx, newSegment)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewSegmentResponse(segment), nil
+}
+
+func (ss *ServiceSegment) GetSegmentByName(
+	ctx context.Context,
+	segmentName string,
+) (*entity.SegmentResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	segment, err := ss.repoSegment.GetSegmentByName(ctx, segmentName)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewSegmentResponse(segment), nil
+}
+
+// UpdateSegment обновляет сегмент и сбрасывает кэш флагов, которые на него ссылаются.
+// Автор и дата создания остаются прежними
+func (ss *ServiceSegment) UpdateSegment(
+	ctx context.Context,
+	segmentDecode entity.SegmentDecode,
+) (*entity.SegmentResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	newSegment := segmentDecode.Segment()
+	if err := validateSegment(newSegment); err != nil {
+		return nil, err
+	}
+	newSegment.UpdatedAt = time.Now().UTC()
+	segment, dependentFlags, err := ss.repoSegment.UpdateSegment(ctx, newSegment)
+	if err != nil {
+		return nil, err
+	}
+	ss.repoFlag.EvictFlagsInAllEnvironments(tenant.FromContext(ctx), dependentFlags...)
+	return entity.NewSegmentResponse(segment), nil
+}
+
+func (ss *ServiceSegment) DeleteSegment(
+	ctx context.Context,
+	segmentName string,
+) error {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return err
+	}
+	if err := ss.repoSegment.DeleteSegment(ctx, segmentName); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ss *ServiceSegment) RetrieveListOfAllSegments(ctx context.Context) (*entity.ListOfSegmentResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	listOfSegments, err := ss.repoSegment.ListOfAllSegments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfSegmentResponse(listOfSegments), nil
+}
+
+// validateSegment - проверка сегмента перед записью в БД
+func validateSegment(segment models.Segment) error {
+	if err := evaluator.ValidateSegment(segment); err != nil {
+		return fmt.Errorf("%w: %v", ErrServiceInvalidSegment, err)
+	}
+	return nil
+}
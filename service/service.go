@@ -2,34 +2,72 @@ package service
 
 import (
 	"context"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
 	"feature-flag-2/entity"
+	"feature-flag-2/evaluator"
 	"feature-flag-2/models"
 	"feature-flag-2/repository/db"
 	"feature-flag-2/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
 )
 
+var ErrServiceInvalidFlag = errors.New("invalid flag")
+
 type ServiceFlag struct {
-	repoDB *db.RepoFlagDB
+	repoDB            *db.RepoFlagDB
+	repoSegment       *db.RepoSegmentDB
+	repoProject       *db.RepoProjectDB
+	repoChangeRequest *db.RepoChangeRequestDB
 }
 
-func NewServiceFlag(db *db.RepoFlagDB) *ServiceFlag {
-	return &ServiceFlag{repoDB: db}
+func NewServiceFlag(
+	db *db.RepoFlagDB,
+	repoSegment *db.RepoSegmentDB,
+	repoProject *db.RepoProjectDB,
+	repoChangeRequest *db.RepoChangeRequestDB,
+) *ServiceFlag {
+	return &ServiceFlag{
+		repoDB:            db,
+		repoSegment:       repoSegment,
+		repoProject:       repoProject,
+		repoChangeRequest: repoChangeRequest,
+	}
 }
 
+// CreateNewFlag - создание флага, автор - субъект запроса, даты создания и изменения - время сервера
 func (sf *ServiceFlag) CreateNewFlag(
 	ctx context.Context,
-	newFlag models.Flag,
+	flagDecode entity.FlagDecode,
 ) (*entity.FlagResponse, error) {
-	if err := sf.repoDB.CreateFlag(ctx, newFlag); err != nil {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	newFlag := flagDecode.Flag()
+	newFlag.CreatedBy = audit.FromContext(ctx).Actor
+	newFlag.CreatedAt = time.Now().UTC()
+	newFlag.UpdatedAt = newFlag.CreatedAt
+	if err := sf.validateFlag(ctx, newFlag); err != nil {
 		return nil, err
 	}
-	return entity.NewFlagResponse(newFlag), nil
+	flag, err := sf.repoDB.CreateFlag(ctx, newFlag)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewFlagResponse(flag), nil
 }
 
 func (sf *ServiceFlag) GetFlagByName(
 	ctx context.Context,
 	flagName string,
 ) (*entity.FlagResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
 	flag, err := sf.repoDB.GetFlagByName(ctx, flagName)
 	if err != nil {
 		return nil, err
@@ -37,42 +75,407 @@ func (sf *ServiceFlag) GetFlagByName(
 	return entity.NewFlagResponse(flag), nil
 }
 
+// EvaluateFlag - вычисляет значение флага по имени для контекста пользователя
+func (sf *ServiceFlag) EvaluateFlag(
+	ctx context.Context,
+	flagName string,
+	evalCtx entity.EvaluationContextDecode,
+) (*entity.EvaluationResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsEvaluate); err != nil {
+		return nil, err
+	}
+	flag, err := sf.repoDB.GetFlagByName(ctx, flagName)
+	if err != nil {
+		return nil, err
+	}
+	store, err := sf.evaluationStore(ctx, flag)
+	if err != nil {
+		return nil, err
+	}
+	result := evaluator.Evaluate(
+		flag,
+		evaluator.Context{Key: evalCtx.Key, Attributes: evalCtx.Attributes},
+		store,
+		time.Now(),
+	)
+	return entity.NewEvaluationResponse(result), nil
+}
+
+// EvaluateAllFlags - вычисляет все живые флаги для контекста пользователя за один проход,
+// если заданы имена или теги - только флаги с этими именами и хотя бы одним из тегов
+func (sf *ServiceFlag) EvaluateAllFlags(
+	ctx context.Context,
+	bulkDecode entity.BulkEvaluationDecode,
+) (*entity.BulkEvaluationResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsEvaluate); err != nil {
+		return nil, err
+	}
+	flags, err := sf.repoDB.SnapshotOfAllFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flagNames := make(map[string]struct{}, len(bulkDecode.FlagNames))
+	for _, flagName := range bulkDecode.FlagNames {
+		flagNames[flagName] = struct{}{}
+	}
+	selected := make([]models.Flag, 0, len(flags))
+	segmentNames := []string{}
+	for _, flag := range flags {
+		if flag.IsDeleted {
+			continue
+		}
+		if _, ok := flagNames[flag.FlagName]; len(flagNames) > 0 && !ok {
+			continue
+		}
+		if len(bulkDecode.Tags) > 0 && !hasAnyTag(flag, bulkDecode.Tags) {
+			continue
+		}
+		selected = append(selected, flag)
+		segmentNames = append(segmentNames, flag.Rules.SegmentNames()...)
+	}
+	// в store только пререквизиты: карта по всем флагам снимка - основная цена массового вычисления
+	prerequisiteFlags := snapshotPrerequisites(selected, flags)
+	for _, flag := range prerequisiteFlags {
+		segmentNames = append(segmentNames, flag.Rules.SegmentNames()...)
+	}
+	var segments []models.Segment
+	if len(segmentNames) > 0 {
+		segments, err = sf.repoSegment.ListOfSegmentsByNames(ctx, utils.UniqueWords(segmentNames))
+		if err != nil {
+			return nil, err
+		}
+	}
+	store := evaluator.NewMapStore(prerequisiteFlags, segments)
+	evalCtx := evaluator.Context{Key: bulkDecode.Key, Attributes: bulkDecode.Attributes}
+	now := time.Now()
+	results := make(map[string]evaluator.Result, len(selected))
+	for _, flag := range selected {
+		results[flag.FlagName] = evaluator.Evaluate(flag, evalCtx, store, now)
+	}
+	return entity.NewBulkEvaluationResponse(results), nil
+}
+
+// snapshotPrerequisites - флаги-пререквизиты (транзитивно) выбранных флагов из снимка всех флагов
+func snapshotPrerequisites(selected []models.Flag, flags []models.Flag) []models.Flag {
+	queue := []string{}
+	for _, flag := range selected {
+		queue = append(queue, flag.Prerequisites.FlagNames()...)
+	}
+	if len(queue) == 0 {
+		return nil
+	}
+	flagIndexes := make(map[string]int, len(flags))
+	for i, flag := range flags {
+		flagIndexes[flag.FlagName] = i
+	}
+	prerequisiteFlags := []models.Flag{}
+	visited := make(map[string]struct{}, len(queue))
+	for len(queue) > 0 {
+		flagName := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[flagName]; ok {
+			continue
+		}
+		visited[flagName] = struct{}{}
+		i, ok := flagIndexes[flagName]
+		if !ok {
+			continue
+		}
+		prerequisiteFlags = append(prerequisiteFlags, flags[i])
+		queue = append(queue, flags[i].Prerequisites.FlagNames()...)
+	}
+	return prerequisiteFlags
+}
+
+func hasAnyTag(flag models.Flag, tags []string) bool {
+	for _, tag := range tags {
+		if flag.Tags.Contains(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateFlag - замена флага из тела запроса, автор и дата создания остаются прежними,
+// precondition (If-Match) проверяется под блокировкой строки. Если окружение требует одобрения -
+// открывается запрос на изменение
 func (sf *ServiceFlag) UpdateFlag(
+	ctx context.Context,
+	flagDecode entity.FlagDecode,
+	precondition db.PreconditionFunc,
+) (*entity.FlagChangeResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	newFlag := flagDecode.Flag()
+	newFlag.UpdatedAt = time.Now().UTC()
+	return sf.updateFlag(ctx, newFlag, precondition)
+}
+
+func (sf *ServiceFlag) updateFlag(
 	ctx context.Context,
 	newFlag models.Flag,
-) (*entity.FlagResponse, error) {
-	flag, err := sf.repoDB.UpdateFlag(ctx, newFlag)
+	precondition db.PreconditionFunc,
+) (*entity.FlagChangeResponse, error) {
+	if err := sf.validateFlag(ctx, newFlag); err != nil {
+		return nil, err
+	}
+	requiredApprovals, err := sf.requiredApprovals(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return entity.NewFlagResponse(flag), nil
+	if requiredApprovals > 0 {
+		changeRequest, err := sf.openChangeRequest(
+			ctx,
+			models.ChangeRequestKindUpdate,
+			newFlag,
+			requiredApprovals,
+			precondition,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return entity.NewPendingFlagChangeResponse(changeRequest), nil
+	}
+	flag, err := sf.repoDB.UpdateFlag(ctx, newFlag, precondition)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewFlagChangeResponse(flag), nil
 }
 
+// DeleteFlag удаляет флаг, если окружение требует одобрения - открывает запрос на удаление
+// и возвращает его, иначе возвращает nil. Флаг удаляется во всех окружениях проекта,
+// поэтому одобрений нужно столько, сколько требует самое строгое из них
 func (sf *ServiceFlag) DeleteFlag(
 	ctx context.Context,
 	flagName string,
-) error {
-	if err := sf.repoDB.DeleteFlag(ctx, flagName); err != nil {
-		return err
+	precondition db.PreconditionFunc,
+) (*entity.ChangeRequestResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
 	}
-	return nil
+	requiredApprovals, err := sf.projectRequiredApprovals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if requiredApprovals > 0 {
+		changeRequest, err := sf.openChangeRequest(
+			ctx,
+			models.ChangeRequestKindDelete,
+			models.Flag{FlagName: flagName},
+			requiredApprovals,
+			precondition,
+		)
+		if err != nil {
+			return nil, err
+		}
+		respChangeRequest, err := sf.changeRequestResponse(ctx, changeRequest)
+		if err != nil {
+			return nil, err
+		}
+		respChangeRequest.Status = http.StatusAccepted
+		return respChangeRequest, nil
+	}
+	if err := sf.repoDB.DeleteFlag(ctx, flagName, precondition); err != nil {
+		return nil, err
+	}
+	return nil, nil
 }
 
-func (sf *ServiceFlag) RetrieveListOfAllFlags(ctx context.Context) (*entity.ListOfFlagResponse, error) {
+// RetrieveListOfAllFlags - список всех флагов, если state не пустой - только флаги в этом состоянии
+func (sf *ServiceFlag) RetrieveListOfAllFlags(
+	ctx context.Context,
+	state models.FlagState,
+) (*entity.ListOfFlagResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
 	listOfFlags, err := sf.repoDB.ListOfAllFlags(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return entity.NewListOfFlagResponse(listOfFlags), nil
+	return entity.NewListOfFlagResponse(filterByState(listOfFlags, state, time.Now())), nil
 }
 
+// RetrieveListOfFlagsByNames - флаги по именам, если state не пустой - только флаги в этом состоянии
 func (sf *ServiceFlag) RetrieveListOfFlagsByNames(
 	ctx context.Context,
 	flagNames []string,
+	state models.FlagState,
 ) (*entity.ListOfFlagResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
 	listOfFlags, err := sf.repoDB.ListOfFlagByNames(ctx, utils.UniqueWords(flagNames))
 	if err != nil {
 		return nil, err
 	}
-	return entity.NewListOfFlagResponse(listOfFlags), nil
+	return entity.NewListOfFlagResponse(filterByState(listOfFlags, state, time.Now())), nil
+}
+
+// RetrieveLifecycleOfFlag - история переходов флага по расписанию
+func (sf *ServiceFlag) RetrieveLifecycleOfFlag(
+	ctx context.Context,
+	flagName string,
+) (*entity.ListOfLifecycleEventResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	if _, err := sf.repoDB.GetFlagByName(ctx, flagName); err != nil {
+		return nil, err
+	}
+	events, err := sf.repoDB.ListOfLifecycleEvents(ctx, flagName)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfLifecycleEventResponse(events), nil
+}
+
+// RetrieveHistoryOfFlag - страница истории версий флага, от новых к старым
+func (sf *ServiceFlag) RetrieveHistoryOfFlag(
+	ctx context.Context,
+	flagName string,
+	limit int,
+	offset int,
+) (*entity.ListOfFlagVersionResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	if _, err := sf.repoDB.GetFlagByName(ctx, flagName); err != nil {
+		return nil, err
+	}
+	versions, total, err := sf.repoDB.ListOfFlagVersions(ctx, flagName, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfFlagVersionResponse(versions, total, limit, offset), nil
+}
+
+// RetrieveVersionOfFlag - состояние флага в версии version, автор и комментарий изменения
+func (sf *ServiceFlag) RetrieveVersionOfFlag(
+	ctx context.Context,
+	flagName string,
+	version int64,
+) (*entity.FlagVersionResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsRead); err != nil {
+		return nil, err
+	}
+	flagVersion, err := sf.repoDB.GetFlagVersion(ctx, flagName, version)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewFlagVersionResponse(flagVersion), nil
+}
+
+// RollbackFlag - восстанавливает флаг из версии истории новой версией,
+// без комментария в истории остается "rollback to version N"
+func (sf *ServiceFlag) RollbackFlag(
+	ctx context.Context,
+	flagName string,
+	rollback entity.RollbackDecode,
+) (*entity.FlagResponse, error) {
+	if err := auth.Check(ctx, auth.ScopeFlagsWrite); err != nil {
+		return nil, err
+	}
+	if err := sf.checkWithoutApproval(ctx); err != nil {
+		return nil, err
+	}
+	change := audit.FromContext(ctx)
+	change.Comment = rollback.Comment
+	if change.Comment == "" {
+		change.Comment = fmt.Sprintf("rollback to version %d", rollback.Version)
+	}
+	ctx = audit.WithChange(ctx, change)
+	flag, err := sf.repoDB.RollbackFlag(ctx, flagName, rollback.Version, sf.validateFlag)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewFlagResponse(flag), nil
+}
+
+// RunLifecycleRecorder - раз в interval записывает наступившие переходы флагов в active/expired,
+// работает до отмены ctx
+func (sf *ServiceFlag) RunLifecycleRecorder(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// после перезапуска продолжаем с последнего записанного перехода каждого арендатора,
+	// переходы за время остановки иначе потерялись бы навсегда
+	since := time.Time{}
+	now := time.Now()
+	for {
+		events, err := sf.repoDB.RecordLifecycleTransitions(ctx, since, now)
+		if err != nil {
+			log.Printf("service: RecordLifecycleTransitions error - {%v}", err)
+		} else {
+			for _, event := range events {
+				log.Printf("service: flag {%s} became {%s} at {%s}", event.FlagName, event.State, event.OccurredAt)
+			}
+			// запас в interval, чтобы не потерять переходы, записанные другой репликой с опозданием
+			since = now.Add(-interval)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+	}
+}
+
+func filterByState(flags []models.Flag, state models.FlagState, now time.Time) []models.Flag {
+	if state == "" {
+		return flags
+	}
+	filtered := make([]models.Flag, 0, len(flags))
+	for _, flag := range flags {
+		if flag.StateAt(now) == state {
+			filtered = append(filtered, flag)
+		}
+	}
+	return filtered
+}
+
+// validateFlag - проверка флага перед записью в БД: сегменты из правил должны существовать,
+// пререквизиты - существовать, иметь нужную вариацию и не образовывать цикл
+func (sf *ServiceFlag) validateFlag(ctx context.Context, flag models.Flag) error {
+	if err := evaluator.ValidateFlag(flag); err != nil {
+		return fmt.Errorf("%w: %v", ErrServiceInvalidFlag, err)
+	}
+	segments, err := sf.repoSegment.ListOfSegmentsByNames(ctx, flag.Rules.SegmentNames())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrServiceInvalidFlag, err)
+	}
+	for _, segment := range segments {
+		if segment.IsDeleted {
+			return fmt.Errorf("%w: segment {%s} is deleted", ErrServiceInvalidFlag, segment.SegmentName)
+		}
+	}
+	if err := sf.validatePrerequisites(ctx, flag); err != nil {
+		return fmt.Errorf("%w: %v", ErrServiceInvalidFlag, err)
+	}
+	return nil
+}
+
+// evaluationStore загружает флаги-пререквизиты (транзитивно) и сегменты,
+// на которые ссылаются правила флагов
+func (sf *ServiceFlag) evaluationStore(
+	ctx context.Context,
+	flags ...models.Flag,
+) (evaluator.Store, error) {
+	prerequisiteFlags, err := sf.prerequisiteFlags(ctx, flags...)
+	if err != nil {
+		return nil, err
+	}
+	segmentNames := []string{}
+	for _, flag := range append(flags, prerequisiteFlags...) {
+		segmentNames = append(segmentNames, flag.Rules.SegmentNames()...)
+	}
+	if len(segmentNames) == 0 {
+		return evaluator.NewMapStore(prerequisiteFlags, nil), nil
+	}
+	segments, err := sf.repoSegment.ListOfSegmentsByNames(ctx, utils.UniqueWords(segmentNames))
+	if err != nil {
+		return nil, err
+	}
+	return evaluator.NewMapStore(prerequisiteFlags, segments), nil
 }
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/repository/db"
+	"feature-flag-2/tenant"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tenantAdminAPIKeyName - имя ключа admin, который выпускается вместе с арендатором
+const tenantAdminAPIKeyName = "tenant-admin"
+
+type ServiceTenant struct {
+	repoTenant *db.RepoTenantDB
+}
+
+func NewServiceTenant(repoTenant *db.RepoTenantDB) *ServiceTenant {
+	return &ServiceTenant{repoTenant: repoTenant}
+}
+
+// CreateTenant создает арендатора с окружением default проекта default и ключом admin:
+// остальные ключи, пользователей и проекты арендатор заводит этим ключом сам
+func (st *ServiceTenant) CreateTenant(
+	ctx context.Context,
+	tenantDecode entity.TenantDecode,
+) (*entity.TenantResponse, error) {
+	if err := checkOperator(ctx); err != nil {
+		return nil, err
+	}
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	actor := audit.FromContext(ctx).Actor
+	now := time.Now().UTC()
+	apiKey := newAPIKey(tenantAdminAPIKeyName, key, []string{auth.ScopeAdmin}, actor)
+	apiKey.TenantID = tenantDecode.ID
+	newTenant, err := st.repoTenant.CreateTenant(
+		ctx,
+		models.Tenant{
+			ID:        tenantDecode.ID,
+			Name:      tenantDecode.Name,
+			CreatedBy: actor,
+			CreatedAt: now,
+		},
+		models.Project{
+			Name:      project.DefaultProject,
+			CreatedBy: actor,
+			CreatedAt: now,
+		},
+		[]models.Environment{{
+			ID:        uuid.New(),
+			Project:   project.DefaultProject,
+			Name:      project.DefaultEnvironment,
+			CreatedBy: actor,
+			CreatedAt: now,
+		}},
+		apiKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewTenantResponse(newTenant, &apiKey, key), nil
+}
+
+func (st *ServiceTenant) RetrieveListOfTenants(ctx context.Context) (*entity.ListOfTenantResponse, error) {
+	if err := checkOperator(ctx); err != nil {
+		return nil, err
+	}
+	tenants, err := st.repoTenant.ListOfTenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfTenantResponse(tenants), nil
+}
+
+// checkOperator - арендаторы видят только свои данные, поэтому арендаторами управляет
+// admin арендатора default
+func checkOperator(ctx context.Context) error {
+	if err := auth.Check(ctx, auth.ScopeAdmin); err != nil {
+		return err
+	}
+	if tenant.FromContext(ctx) != tenant.Default {
+		return auth.NewForbiddenError(auth.ReasonTenantNotAllowed, "", auth.Resource{})
+	}
+	return nil
+}
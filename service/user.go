@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"feature-flag-2/audit"
+	"feature-flag-2/auth"
+	"feature-flag-2/entity"
+	"feature-flag-2/models"
+	"feature-flag-2/repository/db"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrServiceInvalidRoleBinding = errors.New("invalid role binding")
+
+// userSubjectPrefix - автор изменений, сделанных пользователем: "user:<sub>"
+const userSubjectPrefix = "user:"
+
+type ServiceUser struct {
+	repoUser *db.RepoUserDB
+	verifier *auth.JWTVerifier
+}
+
+func NewServiceUser(repoUser *db.RepoUserDB, verifier *auth.JWTVerifier) *ServiceUser {
+	return &ServiceUser{repoUser: repoUser, verifier: verifier}
+}
+
+func (su *ServiceUser) CreateUser(ctx context.Context, userDecode entity.UserDecode) (*entity.UserResponse, error) {
+	user, err := su.repoUser.CreateUser(ctx, models.User{
+		ID:        uuid.New(),
+		Subject:   userDecode.Subject,
+		Email:     userDecode.Email,
+		Name:      userDecode.Name,
+		CreatedBy: audit.FromContext(ctx).Actor,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewUserResponse(models.UserAccess{User: user, RoleBindings: []models.RoleBinding{}}), nil
+}
+
+func (su *ServiceUser) GetUser(ctx context.Context, id string) (*entity.UserResponse, error) {
+	access, err := su.repoUser.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewUserResponse(access), nil
+}
+
+func (su *ServiceUser) RetrieveListOfUsers(ctx context.Context) (*entity.ListOfUserResponse, error) {
+	users, err := su.repoUser.ListOfUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewListOfUserResponse(users), nil
+}
+
+// UpdateUser - email, имя и отключение, отключенный пользователь получает 403 user_disabled
+func (su *ServiceUser) UpdateUser(
+	ctx context.Context,
+	id string,
+	userDecode entity.UserUpdateDecode,
+) (*entity.UserResponse, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	access, err := su.repoUser.UpdateUser(ctx, models.User{
+		ID:         userID,
+		Email:      userDecode.Email,
+		Name:       userDecode.Name,
+		IsDisabled: userDecode.IsDisabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewUserResponse(access), nil
+}
+
+func (su *ServiceUser) DeleteUser(ctx context.Context, id string) error {
+	return su.repoUser.DeleteUser(ctx, id)
+}
+
+// CreateRoleBinding выдает пользователю роль глобально, на проект или на окружение
+func (su *ServiceUser) CreateRoleBinding(
+	ctx context.Context,
+	id string,
+	bindingDecode entity.RoleBindingDecode,
+) (*entity.UserResponse, error) {
+	if _, ok := auth.Roles[bindingDecode.Role]; !ok {
+		return nil, fmt.Errorf("%w: unknown role {%s}", ErrServiceInvalidRoleBinding, bindingDecode.Role)
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	access, err := su.repoUser.CreateRoleBinding(ctx, models.RoleBinding{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Role:        bindingDecode.Role,
+		Project:     bindingDecode.Project,
+		Environment: bindingDecode.Environment,
+		CreatedBy:   audit.FromContext(ctx).Actor,
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewUserResponse(access), nil
+}
+
+func (su *ServiceUser) DeleteRoleBinding(
+	ctx context.Context,
+	id string,
+	bindingID string,
+) (*entity.UserResponse, error) {
+	access, err := su.repoUser.DeleteRoleBinding(ctx, id, bindingID)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewUserResponse(access), nil
+}
+
+// Authenticate - auth.AuthenticateFunc для JWT: подпись и claims проверяет verifier,
+// права - роли пользователя с subject = sub. Незаведенный или отключенный пользователь - 403
+func (su *ServiceUser) Authenticate(ctx context.Context, token string) (auth.Principal, error) {
+	claims, err := su.verifier.Verify(token)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	access, err := su.repoUser.GetUserAccessBySubject(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.Principal{}, auth.NewForbiddenError(auth.ReasonUserNotFound, "", auth.Resource{})
+		}
+		return auth.Principal{}, err
+	}
+	if access.User.IsDisabled {
+		return auth.Principal{}, auth.NewForbiddenError(auth.ReasonUserDisabled, "", auth.Resource{})
+	}
+	bindings := make([]auth.Binding, 0, len(access.RoleBindings))
+	for _, roleBinding := range access.RoleBindings {
+		bindings = append(bindings, auth.Binding{
+			Scopes:      auth.Roles[roleBinding.Role],
+			Project:     roleBinding.Project,
+			Environment: roleBinding.Environment,
+		})
+	}
+	return auth.Principal{
+		Subject:  userSubjectPrefix + claims.Subject,
+		Tenant:   access.User.TenantID,
+		Bindings: bindings,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"feature-flag-2/project"
+	"feature-flag-2/tenant"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// SnapshotFunc возвращает полный снимок флагов в JSON для события put
+type SnapshotFunc func(ctx context.Context) ([]byte, error)
+
+// EnvironmentFunc - окружение, флаги которого читает запрос
+type EnvironmentFunc func(c fiber.Ctx) project.Environment
+
+// Handler - Server-Sent Events окружения: при подключении снимок (или пропущенные события по Last-Event-ID),
+// затем события hub, раз в heartbeat - комментарий, чтобы прокси не закрывали простаивающее соединение.
+// snapshot получает окружение в ctx, арендатор - из ctx запроса
+func Handler(hub *Hub, environmentOf EnvironmentFunc, snapshot SnapshotFunc, heartbeat time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		environment := environmentOf(c)
+		subscription := hub.Subscribe(Topic(tenant.FromContext(c), environment), c.Get("Last-Event-ID"))
+		var snapshotData []byte
+		if !subscription.Resumed {
+			data, err := snapshot(project.WithEnvironment(c, environment))
+			if err != nil {
+				subscription.Cancel()
+				log.Printf("stream: snapshot error - {%v}", err)
+				return fiber.NewError(fiber.StatusServiceUnavailable, "snapshot of flags is unavailable")
+			}
+			snapshotData = data
+		}
+		c.Set("Content-Type", "text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		// nginx не должен буферизовать поток
+		c.Set("X-Accel-Buffering", "no")
+		return c.SendStreamWriter(func(w *bufio.Writer) {
+			defer subscription.Cancel()
+			if !subscription.Resumed {
+				if err := writeEvent(w, Event{ID: subscription.LastID, Type: EventPut, Data: snapshotData}); err != nil {
+					return
+				}
+			}
+			for _, event := range subscription.Missed {
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+			}
+			ticker := time.NewTicker(heartbeat)
+			defer ticker.Stop()
+			for {
+				select {
+				case event, ok := <-subscription.Events:
+					if !ok {
+						return
+					}
+					if err := writeEvent(w, event); err != nil {
+						return
+					}
+				case <-ticker.C:
+					if _, err := w.WriteString(": heartbeat\n\n"); err != nil {
+						return
+					}
+					if err := w.Flush(); err != nil {
+						return
+					}
+				}
+			}
+		})
+	}
+}
+
+// writeEvent пишет событие в формате SSE, data - JSON в одну строку
+func writeEvent(w *bufio.Writer, event Event) error {
+	if _, err := w.WriteString("id: " + event.ID + "\nevent: " + event.Type + "\ndata: "); err != nil {
+		return err
+	}
+	if _, err := w.Write(event.Data); err != nil {
+		return err
+	}
+	if _, err := w.WriteString("\n\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,207 @@
+package stream
+
+import (
+	"encoding/json"
+	"feature-flag-2/models"
+	"feature-flag-2/project"
+	"feature-flag-2/tenant"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// типы событий потока
+const (
+	// EventPut - полный снимок флагов, отправляется при подключении и если продолжить с Last-Event-ID нельзя
+	EventPut = "put"
+
+	// EventPatch - флаг создан или изменен
+	EventPatch = "patch"
+
+	// EventDelete - флаг удален
+	EventDelete = "delete"
+)
+
+// subscriberBuffer - сколько событий ждет медленный подписчик, прежде чем его отключат
+const subscriberBuffer = 64
+
+// Event - событие потока, ID вида "<instance>-<seq>", instance меняется при рестарте процесса.
+// Topic - окружение арендатора "<tenant>/<project>/<environment>", подписчик получает события
+// только своего окружения
+type Event struct {
+	ID    string
+	Topic string
+	Type  string
+	Data  []byte
+}
+
+// Hub - рассылка событий подписчикам и ограниченный журнал последних событий для возобновления
+type Hub struct {
+	mu          sync.Mutex
+	instance    string
+	seq         uint64
+	log         []Event
+	logSize     int
+	subscribers map[chan Event]string
+	closed      bool
+}
+
+func NewHub(logSize int) *Hub {
+	return &Hub{
+		instance:    uuid.NewString()[:8],
+		log:         make([]Event, 0, logSize),
+		logSize:     logSize,
+		subscribers: make(map[chan Event]string),
+	}
+}
+
+// Publish записывает событие в журнал и рассылает подписчикам topic,
+// подписчик с переполненным буфером отключается и переподключится с Last-Event-ID
+func (h *Hub) Publish(topic string, eventType string, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.seq++
+	event := Event{
+		ID:    fmt.Sprintf("%s-%d", h.instance, h.seq),
+		Topic: topic,
+		Type:  eventType,
+		Data:  data,
+	}
+	if len(h.log) == h.logSize {
+		copy(h.log, h.log[1:])
+		h.log = h.log[:len(h.log)-1]
+	}
+	h.log = append(h.log, event)
+	for ch, subscriberTopic := range h.subscribers {
+		if subscriberTopic != topic {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Topic - поток окружения арендатора
+func Topic(tenantID string, environment project.Environment) string {
+	return tenant.Key(tenantID, environment.String())
+}
+
+// flagDeleted - данные события delete
+type flagDeleted struct {
+	FlagName string `json:"flag_name"`
+	Version  int64  `json:"version"`
+}
+
+// PublishFlag публикует изменение флага в поток его окружения:
+// patch с флагом целиком либо delete с именем и версией
+func (h *Hub) PublishFlag(flag models.Flag) {
+	eventType := EventPatch
+	var payload any = struct {
+		Flag models.Flag `json:"flag"`
+	}{Flag: flag}
+	if flag.IsDeleted {
+		eventType = EventDelete
+		payload = flagDeleted{FlagName: flag.FlagName, Version: flag.Version}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("stream: json.Marshal error - {%v}", err)
+		return
+	}
+	h.Publish(Topic(flag.TenantID, project.Environment{Project: flag.Project, Name: flag.Environment}), eventType, data)
+}
+
+// Subscription - подписка на события hub
+type Subscription struct {
+	// Missed - события после Last-Event-ID, если Resumed
+	Missed []Event
+	// Resumed - Last-Event-ID найден в журнале, иначе клиенту нужен полный снимок
+	Resumed bool
+	// LastID - ID последнего события на момент подписки, им помечается снимок
+	LastID string
+	// Events закрывается при отписке, переполнении буфера и закрытии hub
+	Events <-chan Event
+
+	cancel func()
+}
+
+// Cancel отписывает от событий, вызывать обязательно
+func (s *Subscription) Cancel() {
+	s.cancel()
+}
+
+// Subscribe подписывает на новые события topic и ищет в журнале события topic после lastEventID
+func (h *Hub) Subscribe(topic string, lastEventID string) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subscription := &Subscription{
+		LastID: fmt.Sprintf("%s-%d", h.instance, h.seq),
+		Events: ch,
+		cancel: func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := h.subscribers[ch]; ok {
+				delete(h.subscribers, ch)
+				close(ch)
+			}
+		},
+	}
+	if h.closed {
+		close(ch)
+		subscription.cancel = func() {}
+		return subscription
+	}
+	h.subscribers[ch] = topic
+	subscription.Missed, subscription.Resumed = h.since(topic, lastEventID)
+	return subscription
+}
+
+// Close отключает всех подписчиков, вызывать до остановки сервера, иначе открытые потоки его задержат
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// since - события topic из журнала после lastEventID, вызывать под h.mu
+func (h *Hub) since(topic string, lastEventID string) ([]Event, bool) {
+	instance, seqText, ok := strings.Cut(lastEventID, "-")
+	if !ok || instance != h.instance {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(seqText, 10, 64)
+	if err != nil || seq > h.seq {
+		return nil, false
+	}
+	if seq == h.seq {
+		return nil, true
+	}
+	// журнал хранит события подряд, первое событие журнала имеет seq = h.seq-len(h.log)+1
+	first := h.seq - uint64(len(h.log)) + 1
+	if seq+1 < first {
+		return nil, false
+	}
+	missed := []Event{}
+	for _, event := range h.log[seq+1-first:] {
+		if event.Topic == topic {
+			missed = append(missed, event)
+		}
+	}
+	return missed, true
+}
@@ -0,0 +1,39 @@
+// Package tenant передает через context арендатора запроса: бизнес-юнит, данные которого
+// изолированы от остальных политиками RLS в Postgres
+package tenant
+
+import "context"
+
+// Default - арендатор данных, созданных до разделения на арендаторов, и запросов без проверки прав
+const Default = "default"
+
+type tenantKey struct{}
+
+// WithTenant сохраняет арендатора запроса в ctx
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext возвращает арендатора запроса, без арендатора - Default
+func FromContext(ctx context.Context) string {
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	if !ok || tenantID == "" {
+		return Default
+	}
+	return tenantID
+}
+
+// Key - ключ кэша арендатора, имена арендаторов без "/"
+func Key(tenantID string, key string) string {
+	return tenantID + "/" + key
+}
+
+// localsSetter - fiber.Ctx: значения Locals видны в нем как значения context
+type localsSetter interface {
+	Locals(key any, value ...any) any
+}
+
+// SetLocals сохраняет арендатора в fiber.Ctx для маршрутов fiber вне huma
+func SetLocals(c localsSetter, tenantID string) {
+	c.Locals(tenantKey{}, tenantID)
+}